@@ -0,0 +1,157 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+)
+
+// DefaultURL is the URL of the fatd API with the default -apiaddress.
+const DefaultURL = "http://localhost:8078"
+
+// Client makes requests to the fatd JSON RPC 2.0 API. The zero value makes
+// requests to DefaultURL without retries.
+type Client struct {
+	// URL of the fatd API, e.g. "http://localhost:8078".
+	URL string
+
+	// User and Password are sent using HTTP Basic Authentication if User
+	// is not empty.
+	User     string
+	Password string
+
+	// Key is sent as an "Authorization: Bearer" API key if not empty and
+	// User is empty.
+	Key string
+
+	// Retries is the number of times a request is retried after a
+	// network error or an HTTP 5xx or 429 response. JSON RPC errors are
+	// never retried.
+	Retries int
+	// RetryDelay is the delay before the first retry. It doubles after
+	// each subsequent retry.
+	RetryDelay time.Duration
+
+	// HTTPClient is used to make requests. If nil, http.DefaultClient is
+	// used.
+	HTTPClient *http.Client
+}
+
+// New returns a Client for the fatd API at url with the default retry
+// policy.
+func New(url string) *Client {
+	return &Client{URL: url, Retries: 3, RetryDelay: 500 * time.Millisecond}
+}
+
+// httpError is returned for unexpected HTTP response statuses.
+type httpError struct {
+	StatusCode int
+	Status     string
+}
+
+func (err httpError) Error() string {
+	return fmt.Sprintf("http: %v", err.Status)
+}
+
+// retry returns true if a request that failed with err may succeed if
+// retried.
+func retry(err error) bool {
+	switch err := err.(type) {
+	case jrpc.Error:
+		return false
+	case httpError:
+		return err.StatusCode >= 500 ||
+			err.StatusCode == http.StatusTooManyRequests
+	}
+	// Any other error occurred while sending the request or reading the
+	// response.
+	return true
+}
+
+// Request makes a JSON RPC request for method with params and unmarshals the
+// result into result, which should be a pointer. Any JSON RPC error in the
+// response is returned as a jrpc.Error.
+//
+// Requests that fail due to network errors or HTTP 5xx or 429 responses are
+// retried up to c.Retries times. Request returns ctx.Err() if ctx is done
+// before a request succeeds.
+func (c *Client) Request(ctx context.Context, method string,
+	params, result interface{}) error {
+	delay := c.RetryDelay
+	for i := 0; ; i++ {
+		err := c.request(ctx, method, params, result)
+		if err == nil || i >= c.Retries || !retry(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) request(ctx context.Context, method string,
+	params, result interface{}) error {
+	// Generate a random ID for this request.
+	id := rand.Uint32()%200 + 500
+	reqBytes, err := json.Marshal(jrpc.NewRequest(method, id, params))
+	if err != nil {
+		return err
+	}
+
+	url := c.URL
+	if len(url) == 0 {
+		url = DefaultURL
+	}
+	req, err := http.NewRequest(http.MethodPost, url,
+		bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Content-Type", "application/json")
+	if len(c.User) > 0 {
+		req.SetBasicAuth(c.User, c.Password)
+	} else if len(c.Key) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.Key)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK &&
+		res.StatusCode != http.StatusBadRequest {
+		return httpError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	resBytes, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("ioutil.ReadAll(http.Response.Body): %v", err)
+	}
+	resJrpc := jrpc.NewResponse(result)
+	if err := json.Unmarshal(resBytes, &resJrpc); err != nil {
+		return fmt.Errorf("json.Unmarshal(%v): %v", string(resBytes), err)
+	}
+	if resJrpc.Error != nil {
+		return *resJrpc.Error
+	}
+	return nil
+}
@@ -0,0 +1,532 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/flag"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer returns a server for the srv handler with no tokens tracked.
+// If wrap is not nil it is used to wrap the srv handler.
+func newTestServer(wrap func(http.Handler) http.Handler) *httptest.Server {
+	h := srv.Handler()
+	if wrap != nil {
+		h = wrap(h)
+	}
+	return httptest.NewServer(h)
+}
+
+func requireErrorCode(t *testing.T, code jrpc.ErrorCode, err error) {
+	require.IsType(t, jrpc.Error{}, err)
+	assert.Equal(t, code, err.(jrpc.Error).Code)
+}
+
+func TestMethods(t *testing.T) {
+	ts := newTestServer(nil)
+	defer ts.Close()
+	c := &Client{URL: ts.URL}
+	ctx := context.Background()
+	chainID := factom.NewBytes32([]byte{0x01})
+	adr := factom.NewAddress(&factom.RCDHash{})
+
+	props, err := c.GetDaemonProperties(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v0", props.APIVersion)
+
+	tkns, err := c.GetDaemonTokens(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tkns)
+
+	status, err := c.GetSyncStatus(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, status.Failing)
+
+	_, err = c.GetIssuance(ctx, srv.ParamsToken{})
+	requireErrorCode(t, srv.ParamsErrorToken.Code, err)
+
+	token := srv.ParamsToken{ChainID: chainID}
+	_, err = c.GetIssuance(ctx, token)
+	requireErrorCode(t, srv.ErrorTokenNotFound.Code, err)
+
+	_, err = c.GetStats(ctx, token)
+	requireErrorCode(t, srv.ErrorTokenNotFound.Code, err)
+
+	_, err = c.GetBalance(ctx, srv.ParamsGetBalance{
+		ParamsToken: token, Address: &adr})
+	requireErrorCode(t, srv.ErrorTokenNotFound.Code, err)
+
+	_, err = c.GetNFBalance(ctx, srv.ParamsGetNFBalance{
+		ParamsToken: token, Address: &adr})
+	requireErrorCode(t, srv.ErrorTokenNotFound.Code, err)
+
+	_, err = c.GetTransactionsPage(ctx, srv.ParamsGetTransactions{
+		ParamsToken: token})
+	requireErrorCode(t, srv.ErrorTokenNotFound.Code, err)
+
+	_, err = c.SendTransaction(ctx, srv.ParamsSendTransaction{
+		ParamsToken: token, Content: factom.Bytes("{}")})
+	requireErrorCode(t, srv.ErrorNoEC.Code, err)
+}
+
+func TestRetries(t *testing.T) {
+	var requests, failures int32
+	ts := newTestServer(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if atomic.AddInt32(&failures, -1) >= 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	defer ts.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		Description string
+		Retries     int
+		Failures    int32
+		Requests    int32
+		Error       bool
+	}{{
+		Description: "no failures",
+		Retries:     2,
+		Requests:    1,
+	}, {
+		Description: "retried",
+		Retries:     2,
+		Failures:    2,
+		Requests:    3,
+	}, {
+		Description: "too many failures",
+		Retries:     1,
+		Failures:    2,
+		Requests:    2,
+		Error:       true,
+	}}
+	for _, test := range tests {
+		t.Run(test.Description, func(t *testing.T) {
+			requests, failures = 0, test.Failures
+			c := &Client{URL: ts.URL, Retries: test.Retries,
+				RetryDelay: time.Millisecond}
+			_, err := c.GetDaemonProperties(ctx)
+			if test.Error {
+				assert.EqualError(t, err, "http: 503 Service Unavailable")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.Requests, requests)
+		})
+	}
+
+	t.Run("JSON RPC errors", func(t *testing.T) {
+		requests, failures = 0, 0
+		c := &Client{URL: ts.URL, Retries: 2, RetryDelay: time.Millisecond}
+		_, err := c.GetIssuance(ctx, srv.ParamsToken{})
+		requireErrorCode(t, srv.ParamsErrorToken.Code, err)
+		assert.Equal(t, int32(1), requests)
+	})
+}
+
+func TestAuth(t *testing.T) {
+	ts := newTestServer(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	defer ts.Close()
+	ctx := context.Background()
+
+	c := &Client{URL: ts.URL}
+	_, err := c.GetDaemonProperties(ctx)
+	assert.EqualError(t, err, "http: 401 Unauthorized")
+
+	c.User, c.Password = "user", "pass"
+	_, err = c.GetDaemonProperties(ctx)
+	assert.NoError(t, err)
+}
+
+func TestContext(t *testing.T) {
+	// Never respond until the test is done.
+	done := make(chan struct{})
+	ts := newTestServer(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		})
+	})
+	defer ts.Close()
+	defer close(done)
+
+	c := &Client{URL: ts.URL, Retries: 5, RetryDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	_, err := c.GetDaemonProperties(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestTransactionsIterator(t *testing.T) {
+	// Serve 5 transactions, 2 per page, using the entry hash as the
+	// cursor.
+	const numTxs = 5
+	methods := jrpc.MethodMap{
+		"get-transactions": func(data json.RawMessage) interface{} {
+			var params srv.ParamsGetTransactions
+			if err := json.Unmarshal(data, &params); err != nil {
+				return jrpc.NewInvalidParamsError(err.Error())
+			}
+			var page TransactionsPage
+			start := params.Cursor
+			if start == 0 {
+				start = 1
+			}
+			for i := start; i <= numTxs; i++ {
+				if len(page.Transactions) == 2 {
+					page.NextCursor = i
+					break
+				}
+				hash := factom.Bytes32{31: byte(i)}
+				page.Transactions = append(page.Transactions,
+					Transaction{Hash: &hash,
+						Tx: json.RawMessage(
+							fmt.Sprintf(`{"n":%v}`, i))})
+			}
+			if len(page.Transactions) == 0 {
+				return srv.ErrorTransactionNotFound
+			}
+			return page
+		},
+	}
+	ts := httptest.NewServer(jrpc.HTTPRequestHandler(methods))
+	defer ts.Close()
+	c := &Client{URL: ts.URL}
+	ctx := context.Background()
+
+	it := c.GetTransactions(srv.ParamsGetTransactions{})
+	var pages, txs int
+	for it.Next(ctx) {
+		pages++
+		for _, tx := range it.Page().Transactions {
+			txs++
+			assert.Equal(t, byte(txs), tx.Hash[31])
+		}
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, numTxs, txs)
+
+	it = c.GetTransactions(srv.ParamsGetTransactions{Cursor: numTxs + 1})
+	assert.False(t, it.Next(ctx))
+	assert.NoError(t, it.Err())
+}
+
+func TestAdmin(t *testing.T) {
+	flag.AdminUser, flag.AdminPassword = "admin", "pass"
+	defer func() { flag.AdminPassword = "" }()
+	require.NoError(t, srv.Configure())
+	ts := httptest.NewServer(srv.AdminHandler())
+	defer ts.Close()
+	ctx := context.Background()
+	token := srv.ParamsToken{ChainID: factom.NewBytes32([]byte{0x01})}
+
+	c := &Client{URL: ts.URL}
+	_, err := c.AdminTrackChain(ctx, token)
+	assert.EqualError(t, err, "http: 401 Unauthorized")
+
+	c.User, c.Password = "admin", "wrong"
+	_, err = c.AdminTrackChain(ctx, token)
+	assert.EqualError(t, err, "http: 401 Unauthorized")
+
+	c.Password = "pass"
+	_, err = c.AdminTrackChain(ctx, srv.ParamsToken{})
+	requireErrorCode(t, srv.ParamsErrorToken.Code, err)
+
+	// The engine is not running.
+	_, err = c.AdminRescanChain(ctx, token)
+	requireErrorCode(t, jrpc.InternalErrorCode, err)
+}
+
+func TestAPIAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fatd-auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fpath := filepath.Join(dir, "auth.json")
+	require.NoError(t, ioutil.WriteFile(fpath, []byte(`[
+	{"user": "reader", "password": "pass", "permissions": ["read"]},
+	{"name": "wallet", "key": "sendkey", "permissions": ["read", "send"]},
+	{"name": "ops", "key": "adminkey", "permissions": ["admin"]},
+	{"name": "limited", "key": "limitedkey", "permissions": ["read"],
+		"ratelimit": 1}
+]`), 0600))
+
+	flag.APIAuth, flag.ECPub = fpath, "EC2DKSYyRcNWf7RS963VFYgMExoHRYLHVeCfQ9PGPmNzwrcmgm2r"
+	defer func() {
+		flag.APIAuth, flag.ECPub, flag.APIRequireAuth = "", "", false
+		require.NoError(t, srv.Configure())
+	}()
+	require.NoError(t, srv.Configure())
+	ts := newTestServer(nil)
+	defer ts.Close()
+	ctx := context.Background()
+	tx := srv.ParamsSendTransaction{
+		ParamsToken: srv.ParamsToken{ChainID: factom.NewBytes32([]byte{0x01})},
+		ExtIDs:      []factom.Bytes{factom.Bytes("sig")},
+		Content:     factom.Bytes("{}")}
+
+	t.Run("anonymous", func(t *testing.T) {
+		c := &Client{URL: ts.URL}
+		_, err := c.GetDaemonProperties(ctx)
+		assert.NoError(t, err)
+		_, err = c.SendTransaction(ctx, tx)
+		assert.EqualError(t, err, "http: 401 Unauthorized")
+	})
+	t.Run("invalid", func(t *testing.T) {
+		c := &Client{URL: ts.URL, User: "reader", Password: "wrong"}
+		_, err := c.GetDaemonProperties(ctx)
+		assert.EqualError(t, err, "http: 401 Unauthorized")
+		c = &Client{URL: ts.URL, Key: "wrong"}
+		_, err = c.GetDaemonProperties(ctx)
+		assert.EqualError(t, err, "http: 401 Unauthorized")
+	})
+	t.Run("permissions", func(t *testing.T) {
+		c := &Client{URL: ts.URL, User: "reader", Password: "pass"}
+		_, err := c.GetDaemonProperties(ctx)
+		assert.NoError(t, err)
+		_, err = c.SendTransaction(ctx, tx)
+		assert.EqualError(t, err, "http: 403 Forbidden")
+
+		c = &Client{URL: ts.URL, Key: "sendkey"}
+		_, err = c.SendTransaction(ctx, tx)
+		requireErrorCode(t, srv.ErrorTokenNotFound.Code, err)
+
+		c = &Client{URL: ts.URL, Key: "adminkey"}
+		_, err = c.GetDaemonProperties(ctx)
+		assert.EqualError(t, err, "http: 403 Forbidden")
+	})
+	t.Run("admin", func(t *testing.T) {
+		ts := httptest.NewServer(srv.AdminHandler())
+		defer ts.Close()
+		c := &Client{URL: ts.URL, Key: "sendkey"}
+		_, err := c.AdminResume(ctx)
+		assert.EqualError(t, err, "http: 401 Unauthorized")
+		c.Key = "adminkey"
+		_, err = c.AdminResume(ctx)
+		assert.NoError(t, err)
+	})
+	t.Run("rate limit", func(t *testing.T) {
+		c := &Client{URL: ts.URL, Key: "limitedkey"}
+		_, err := c.GetDaemonProperties(ctx)
+		assert.NoError(t, err)
+		_, err = c.GetDaemonProperties(ctx)
+		assert.EqualError(t, err, "http: 429 Too Many Requests")
+	})
+	t.Run("require auth", func(t *testing.T) {
+		flag.APIRequireAuth = true
+		require.NoError(t, srv.Configure())
+		ts := newTestServer(nil)
+		defer ts.Close()
+		c := &Client{URL: ts.URL}
+		_, err := c.GetDaemonProperties(ctx)
+		assert.EqualError(t, err, "http: 401 Unauthorized")
+		c.User, c.Password = "reader", "pass"
+		_, err = c.GetDaemonProperties(ctx)
+		assert.NoError(t, err)
+	})
+}
+
+func TestAPIIPRateLimit(t *testing.T) {
+	flag.APIIPRateLimit = 2
+	defer func() {
+		flag.APIIPRateLimit = 0
+		require.NoError(t, srv.Configure())
+	}()
+	require.NoError(t, srv.Configure())
+	ts := newTestServer(nil)
+	defer ts.Close()
+	ctx := context.Background()
+
+	c := &Client{URL: ts.URL}
+	for i := 0; i < 2; i++ {
+		_, err := c.GetDaemonProperties(ctx)
+		assert.NoError(t, err)
+	}
+	_, err := c.GetDaemonProperties(ctx)
+	assert.EqualError(t, err, "http: 429 Too Many Requests")
+}
+
+// writeCert writes a new self signed certificate and its key to dir/name.pem
+// and dir/name.key, and returns the certificate.
+func writeCert(t *testing.T, dir, name string,
+	usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{usage},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl,
+		&key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY",
+		Bytes: keyDER})
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(dir, name+".pem"), certPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(dir, name+".key"), keyPEM, 0600))
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	cert.Leaf, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fatd-listeners")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	serverCert := writeCert(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	clientCert := writeCert(t, dir, "client", x509.ExtKeyUsageClientAuth)
+	socket := filepath.Join(dir, "fatd.sock")
+
+	flag.APIAddress = addr
+	flag.APITLSCert = filepath.Join(dir, "server.pem")
+	flag.APITLSKey = filepath.Join(dir, "server.key")
+	flag.APITLSClientCA = filepath.Join(dir, "client.pem")
+	flag.APISocket, flag.SocketMethods = socket, "admin"
+	defer func() {
+		flag.APIAddress = ":8078"
+		flag.APITLSCert, flag.APITLSKey, flag.APITLSClientCA = "", "", ""
+		flag.APISocket, flag.SocketMethods = "", "read,send,admin"
+		require.NoError(t, srv.Configure())
+	}()
+	require.NoError(t, srv.Start())
+	defer srv.Stop()
+	ctx := context.Background()
+
+	newTLSClient := func(root *x509.Certificate,
+		certs ...tls.Certificate) *Client {
+		roots := x509.NewCertPool()
+		roots.AddCert(root)
+		return &Client{URL: "https://" + addr, HTTPClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs: roots, Certificates: certs}}}}
+	}
+	closeIdle := func(c *Client) {
+		c.HTTPClient.Transport.(*http.Transport).CloseIdleConnections()
+	}
+
+	t.Run("TLS", func(t *testing.T) {
+		c := newTLSClient(serverCert.Leaf, clientCert)
+		defer closeIdle(c)
+		_, err := c.GetDaemonProperties(ctx)
+		assert.NoError(t, err)
+
+		c = &Client{URL: "http://" + addr}
+		_, err = c.GetDaemonProperties(ctx)
+		assert.Error(t, err)
+	})
+	t.Run("client certificate required", func(t *testing.T) {
+		c := newTLSClient(serverCert.Leaf)
+		defer closeIdle(c)
+		_, err := c.GetDaemonProperties(ctx)
+		assert.Error(t, err)
+	})
+	t.Run("reload", func(t *testing.T) {
+		newCert := writeCert(t, dir, "server", x509.ExtKeyUsageServerAuth)
+		c := newTLSClient(newCert.Leaf, clientCert)
+		defer closeIdle(c)
+		_, err := c.GetDaemonProperties(ctx)
+		assert.Error(t, err)
+
+		require.NoError(t, srv.ReloadTLS())
+		_, err = c.GetDaemonProperties(ctx)
+		assert.NoError(t, err)
+	})
+	t.Run("socket", func(t *testing.T) {
+		transport := &http.Transport{DialContext: func(ctx context.Context,
+			_, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}}
+		defer transport.CloseIdleConnections()
+		c := &Client{URL: "http://fatd",
+			HTTPClient: &http.Client{Transport: transport}}
+
+		// Only the admin methods are served, without authentication.
+		_, err := c.AdminResume(ctx)
+		assert.NoError(t, err)
+		_, err = c.GetDaemonProperties(ctx)
+		requireErrorCode(t, jrpc.MethodNotFoundCode, err)
+	})
+}
+
+func TestAPIMethods(t *testing.T) {
+	defer func() {
+		flag.APIMethods = "read,send"
+		require.NoError(t, srv.Configure())
+	}()
+	flag.APIMethods = "bogus"
+	assert.EqualError(t, srv.Configure(),
+		`-apimethods: unknown method or permission: "bogus"`)
+
+	flag.APIMethods = "read,admin-pause"
+	require.NoError(t, srv.Configure())
+	ts := newTestServer(nil)
+	defer ts.Close()
+	ctx := context.Background()
+
+	c := &Client{URL: ts.URL}
+	_, err := c.GetDaemonProperties(ctx)
+	assert.NoError(t, err)
+	_, err = c.SendTransaction(ctx, srv.ParamsSendTransaction{})
+	requireErrorCode(t, jrpc.MethodNotFoundCode, err)
+	// admin-pause is served but requires the admin permission.
+	_, err = c.AdminPause(ctx)
+	assert.EqualError(t, err, "http: 401 Unauthorized")
+}
@@ -0,0 +1,8 @@
+// Package client provides a typed Go client for the fatd JSON RPC 2.0 API.
+//
+// Each RPC has a corresponding method on Client which takes the params and
+// returns the results types defined in the fatd/srv package. JSON RPC errors
+// returned by fatd are returned as a jrpc.Error and may be compared by Code
+// with the errors defined in the fatd/srv package, such as
+// srv.ErrorTokenNotFound.
+package client
@@ -0,0 +1,274 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+// Transaction is a transaction returned by GetTransaction or
+// GetTransactions.
+type Transaction struct {
+	Hash      *factom.Bytes32 `json:"entryhash"`
+	Timestamp *factom.Time    `json:"timestamp"`
+	// Tx is the JSON of the FAT-0 or FAT-1 transaction. Use FAT0 or FAT1
+	// to decode it.
+	Tx json.RawMessage `json:"data"`
+}
+
+// FAT0 decodes tx as a FAT-0 transaction.
+func (tx Transaction) FAT0() (fat0.Transaction, error) {
+	var t fat0.Transaction
+	if err := json.Unmarshal(tx.Tx, &t); err != nil {
+		return t, err
+	}
+	t.Hash, t.Timestamp = tx.Hash, tx.Timestamp
+	return t, nil
+}
+
+// FAT1 decodes tx as a FAT-1 transaction.
+func (tx Transaction) FAT1() (fat1.Transaction, error) {
+	var t fat1.Transaction
+	if err := json.Unmarshal(tx.Tx, &t); err != nil {
+		return t, err
+	}
+	t.Hash, t.Timestamp = tx.Hash, tx.Timestamp
+	return t, nil
+}
+
+// TransactionsPage is a single page of results from get-transactions.
+type TransactionsPage struct {
+	Transactions []Transaction `json:"transactions"`
+	// NextCursor may be used as the Cursor of the params to get the next
+	// page. It is zero if this is the last page.
+	NextCursor uint64 `json:"nextcursor,omitempty"`
+}
+
+func (c *Client) GetIssuance(ctx context.Context,
+	params srv.ParamsToken) (srv.ResultsGetIssuance, error) {
+	var result srv.ResultsGetIssuance
+	err := c.Request(ctx, "get-issuance", params, &result)
+	return result, err
+}
+
+func (c *Client) GetTransaction(ctx context.Context,
+	params srv.ParamsGetTransaction) (Transaction, error) {
+	var result Transaction
+	err := c.Request(ctx, "get-transaction", params, &result)
+	return result, err
+}
+
+// GetTransactionsPage returns the single page of transactions selected by
+// params. If no transactions are found, an empty page is returned rather than
+// srv.ErrorTransactionNotFound.
+func (c *Client) GetTransactionsPage(ctx context.Context,
+	params srv.ParamsGetTransactions) (TransactionsPage, error) {
+	var result TransactionsPage
+	err := c.Request(ctx, "get-transactions", params, &result)
+	if err, ok := err.(jrpc.Error); ok &&
+		err.Code == srv.ErrorTransactionNotFound.Code {
+		return TransactionsPage{Transactions: []Transaction{}}, nil
+	}
+	return result, err
+}
+
+// GetTransactions returns a TransactionsIterator over every page of the
+// transactions selected by params, starting with the page selected by
+// params.
+func (c *Client) GetTransactions(
+	params srv.ParamsGetTransactions) *TransactionsIterator {
+	return &TransactionsIterator{c: c, params: params}
+}
+
+// TransactionsIterator iterates over the pages of transactions returned by
+// get-transactions.
+//
+//	it := c.GetTransactions(params)
+//	for it.Next(ctx) {
+//		for _, tx := range it.Page().Transactions {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type TransactionsIterator struct {
+	c      *Client
+	params srv.ParamsGetTransactions
+	page   TransactionsPage
+	done   bool
+	err    error
+}
+
+// Next gets the next page and returns true if it has any transactions. Next
+// returns false once all pages have been returned or an error occurs.
+func (it *TransactionsIterator) Next(ctx context.Context) bool {
+	if it.done {
+		return false
+	}
+	it.page, it.err = it.c.GetTransactionsPage(ctx, it.params)
+	if it.err != nil || it.page.NextCursor == 0 {
+		it.done = true
+	}
+	// The cursor is exclusive of any Hash or Start used to select the
+	// first page.
+	it.params.Hash, it.params.Start = nil, nil
+	it.params.Cursor = it.page.NextCursor
+	return it.err == nil && len(it.page.Transactions) > 0
+}
+
+// Page returns the page of transactions returned by the last call to Next.
+func (it *TransactionsIterator) Page() TransactionsPage {
+	return it.page
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *TransactionsIterator) Err() error {
+	return it.err
+}
+
+func (c *Client) GetBalance(ctx context.Context,
+	params srv.ParamsGetBalance) (uint64, error) {
+	var balance uint64
+	err := c.Request(ctx, "get-balance", params, &balance)
+	return balance, err
+}
+
+func (c *Client) GetHoldersSnapshot(ctx context.Context,
+	params srv.ParamsGetHoldersSnapshot) (srv.ResultsGetHoldersSnapshot, error) {
+	var result srv.ResultsGetHoldersSnapshot
+	err := c.Request(ctx, "get-holders-snapshot", params, &result)
+	return result, err
+}
+
+// BlockChainEntries are the entries of a single token chain that were
+// processed in a block. It is the same as srv.ResultsGetBlockChainEntries
+// except that the Transactions are not decoded.
+type BlockChainEntries struct {
+	srv.ParamsToken
+	Issuance     *srv.ResultsGetIssuance    `json:"issuance,omitempty"`
+	Transactions []Transaction              `json:"transactions,omitempty"`
+	Rejected     []srv.ResultsRejectedEntry `json:"rejected,omitempty"`
+}
+
+// BlockTransactions is the result of GetBlockTransactions.
+type BlockTransactions struct {
+	Height uint64              `json:"height"`
+	Chains []BlockChainEntries `json:"chains"`
+}
+
+func (c *Client) GetBlockTransactions(ctx context.Context,
+	params srv.ParamsGetBlockTransactions) (BlockTransactions, error) {
+	var result BlockTransactions
+	err := c.Request(ctx, "get-block-transactions", params, &result)
+	return result, err
+}
+
+func (c *Client) GetAddressBalances(ctx context.Context,
+	params srv.ParamsGetAddressBalances) ([]srv.ResultsGetAddressBalances, error) {
+	var result []srv.ResultsGetAddressBalances
+	err := c.Request(ctx, "get-address-balances", params, &result)
+	return result, err
+}
+
+func (c *Client) GetStats(ctx context.Context,
+	params srv.ParamsToken) (srv.ResultsGetStats, error) {
+	var result srv.ResultsGetStats
+	err := c.Request(ctx, "get-stats", params, &result)
+	return result, err
+}
+
+func (c *Client) GetNFToken(ctx context.Context,
+	params srv.ParamsGetNFToken) (srv.ResultsGetNFToken, error) {
+	var result srv.ResultsGetNFToken
+	err := c.Request(ctx, "get-nf-token", params, &result)
+	return result, err
+}
+
+// GetNFBalance returns the NFTokens held by params.Address, which is empty if
+// the address does not hold any.
+func (c *Client) GetNFBalance(ctx context.Context,
+	params srv.ParamsGetNFBalance) (fat1.NFTokens, error) {
+	var data json.RawMessage
+	if err := c.Request(ctx, "get-nf-balance", params, &data); err != nil {
+		return nil, err
+	}
+	tkns := make(fat1.NFTokens)
+	if string(data) == "[]" {
+		return tkns, nil
+	}
+	if err := tkns.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return tkns, nil
+}
+
+func (c *Client) SendTransaction(ctx context.Context,
+	params srv.ParamsSendTransaction) (srv.ResultsSendTransaction, error) {
+	var result srv.ResultsSendTransaction
+	err := c.Request(ctx, "send-transaction", params, &result)
+	return result, err
+}
+
+func (c *Client) GetDaemonTokens(ctx context.Context) ([]srv.ParamsToken, error) {
+	var result []srv.ParamsToken
+	err := c.Request(ctx, "get-daemon-tokens", nil, &result)
+	return result, err
+}
+
+func (c *Client) GetDaemonProperties(
+	ctx context.Context) (srv.ResultsGetDaemonProperties, error) {
+	var result srv.ResultsGetDaemonProperties
+	err := c.Request(ctx, "get-daemon-properties", nil, &result)
+	return result, err
+}
+
+func (c *Client) GetSyncStatus(
+	ctx context.Context) (srv.ResultsGetSyncStatus, error) {
+	var result srv.ResultsGetSyncStatus
+	err := c.Request(ctx, "get-sync-status", nil, &result)
+	return result, err
+}
+
+// The following methods must be made to the admin API at -adminaddress with
+// the -adminuser and -adminpassword as the User and Password.
+
+func (c *Client) AdminTrackChain(ctx context.Context,
+	params srv.ParamsToken) (srv.ResultsAdminChain, error) {
+	var result srv.ResultsAdminChain
+	err := c.Request(ctx, "admin-track-chain", params, &result)
+	return result, err
+}
+
+func (c *Client) AdminRescanChain(ctx context.Context,
+	params srv.ParamsToken) (srv.ResultsAdminChain, error) {
+	var result srv.ResultsAdminChain
+	err := c.Request(ctx, "admin-rescan-chain", params, &result)
+	return result, err
+}
+
+func (c *Client) AdminIgnoreChain(ctx context.Context,
+	params srv.ParamsToken) (srv.ResultsAdminChain, error) {
+	var result srv.ResultsAdminChain
+	err := c.Request(ctx, "admin-ignore-chain", params, &result)
+	return result, err
+}
+
+func (c *Client) AdminPause(
+	ctx context.Context) (srv.ResultsAdminEngine, error) {
+	var result srv.ResultsAdminEngine
+	err := c.Request(ctx, "admin-pause", nil, &result)
+	return result, err
+}
+
+func (c *Client) AdminResume(
+	ctx context.Context) (srv.ResultsAdminEngine, error) {
+	var result srv.ResultsAdminEngine
+	err := c.Request(ctx, "admin-resume", nil, &result)
+	return result, err
+}
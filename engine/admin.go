@@ -0,0 +1,302 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/state"
+)
+
+var (
+	ErrNotRunning    = fmt.Errorf("engine is not running")
+	ErrTracked       = fmt.Errorf("chain is already tracked")
+	ErrNotTracked    = fmt.Errorf("chain is not tracked")
+	ErrBackfilling   = fmt.Errorf("chain is already being backfilled")
+	errNotTokenChain = fmt.Errorf("not a valid token chain")
+)
+
+// pause is closed and replaced by Resume. While paused is true the engine
+// waits on it before processing the next DBlock.
+var pause = struct {
+	sync.Mutex
+	paused bool
+	resume chan struct{}
+}{}
+
+// Pause stops the engine from processing any more DBlocks until Resume is
+// called. Pause returns once the DBlock being processed, if any, is done.
+// Backfills are not paused.
+func Pause() {
+	pause.Lock()
+	if !pause.paused {
+		pause.paused = true
+		pause.resume = make(chan struct{})
+		log.Infof("Paused.")
+	}
+	pause.Unlock()
+	blockMu.Lock()
+	blockMu.Unlock()
+}
+
+// Resume the engine after a call to Pause.
+func Resume() {
+	pause.Lock()
+	defer pause.Unlock()
+	if pause.paused {
+		pause.paused = false
+		close(pause.resume)
+		log.Infof("Resumed.")
+	}
+}
+
+// IsPaused returns true if the engine has been paused by Pause.
+func IsPaused() bool {
+	pause.Lock()
+	defer pause.Unlock()
+	return pause.paused
+}
+
+// waitWhilePaused blocks until the engine is resumed or ctx is done.
+func waitWhilePaused(ctx context.Context) {
+	pause.Lock()
+	if !pause.paused {
+		pause.Unlock()
+		return
+	}
+	resume := pause.resume
+	pause.Unlock()
+	select {
+	case <-resume:
+	case <-ctx.Done():
+	}
+}
+
+// backfills is used by Stop to wait for all backfills to finish.
+var backfills sync.WaitGroup
+
+// TrackChain begins backfilling the chain with chainID, which must not already
+// be tracked. This can be used to track a chain that was created before the
+// height at which fatd started scanning, and so was ignored.
+func TrackChain(chainID *factom.Bytes32) error {
+	return startBackfill(chainID, func(chain state.Chain) error {
+		if chain.IsTracked() {
+			return ErrTracked
+		}
+		return nil
+	})
+}
+
+// RescanChain discards the database of the tracked or quarantined chain with
+// chainID and begins backfilling it from scratch. The previous database is
+// kept with a ".bak" extension.
+func RescanChain(chainID *factom.Bytes32) error {
+	return startBackfill(chainID, func(chain state.Chain) error {
+		if !chain.IsTracked() && !chain.IsQuarantined() {
+			return ErrNotTracked
+		}
+		return nil
+	})
+}
+
+// IgnoreChain stops syncing the chain with chainID. If it is tracked, its
+// database is moved aside with a ".bak" extension.
+func IgnoreChain(chainID *factom.Bytes32) error {
+	if cancel == nil {
+		return ErrNotRunning
+	}
+	blockMu.Lock()
+	defer blockMu.Unlock()
+	if state.Chains.Get(chainID).Backfilling {
+		return ErrBackfilling
+	}
+	if err := state.IgnoreChain(chainID); err != nil {
+		return err
+	}
+	log.Infof("Chain %v ignored.", chainID)
+	return nil
+}
+
+// startBackfill resets the chain with chainID and starts backfilling it if
+// check returns nil for the current state of the chain. The engine is between
+// DBlocks while the chain is reset.
+func startBackfill(chainID *factom.Bytes32, check func(state.Chain) error) error {
+	if cancel == nil {
+		return ErrNotRunning
+	}
+	blockMu.Lock()
+	defer blockMu.Unlock()
+	chain := state.Chains.Get(chainID)
+	if chain.Backfilling {
+		return ErrBackfilling
+	}
+	if err := check(chain); err != nil {
+		return err
+	}
+	if err := state.ResetChain(chainID); err != nil {
+		return err
+	}
+	state.Chains.SetBackfilling(chainID, true)
+	backfills.Add(1)
+	go backfill(engineCtx, chainID)
+	return nil
+}
+
+// backfill syncs the chain with chainID by walking all of its EBlocks and
+// processing them in order, while the engine skips the chain.
+//
+// The EBlocks up to the engine's height at the start are processed without
+// holding up the engine. Then, between DBlocks, any remaining EBlocks up to the
+// engine's current height are processed and the chain is handed back to the
+// engine.
+//
+// If the chain cannot be backfilled it is quarantined.
+func backfill(ctx context.Context, chainID *factom.Bytes32) {
+	defer backfills.Done()
+	log.Infof("Backfilling chain %v...", chainID)
+	err := func() error {
+		blockMu.Lock()
+		height := state.SavedHeight
+		blockMu.Unlock()
+
+		var ebs []factom.EBlock
+		if err := retry(ctx, func() error {
+			var err error
+			ebs, err = getAllEBlocks(ctx, chainID)
+			return err
+		}); err != nil {
+			return err
+		}
+		if len(ebs) == 0 {
+			return fmt.Errorf("chain not found")
+		}
+		if err := retry(ctx, func() error {
+			return processEBlocks(ctx, chainID, ebs, height)
+		}); err != nil {
+			return err
+		}
+
+		return retry(ctx, func() error {
+			blockMu.Lock()
+			defer blockMu.Unlock()
+			if err := catchUp(ctx, chainID); err != nil {
+				return err
+			}
+			state.Chains.SetBackfilling(chainID, false)
+			return nil
+		})
+	}()
+	switch {
+	case err == nil:
+		log.Infof("Backfilled chain %v.", chainID)
+		return
+	case ctx.Err() != nil:
+		// fatd is stopping. The chain will be synced from its saved
+		// height after a restart.
+		return
+	case err == errNotTokenChain:
+		log.Errorf("Backfill chain %v: %v", chainID, err)
+	default:
+		log.Errorf("Backfill chain %v: %v", chainID, err)
+		state.Chains.Quarantine(chainID, err)
+	}
+	state.Chains.SetBackfilling(chainID, false)
+}
+
+// getAllEBlocks returns all EBlocks in the chain with chainID in order, or
+// nil if the chain does not exist.
+func getAllEBlocks(ctx context.Context, chainID *factom.Bytes32) (
+	[]factom.EBlock, error) {
+	eb := factom.EBlock{ChainID: chainID}
+	if err := eb.Get(ctx); err != nil {
+		return nil, err
+	}
+	if !eb.IsPopulated() {
+		return nil, nil
+	}
+	return eb.GetAllPrev(ctx)
+}
+
+// catchUp processes the EBlocks in the chain with chainID that were added after
+// those already processed, up to the engine's height. It must be called with
+// blockMu held.
+func catchUp(ctx context.Context, chainID *factom.Bytes32) error {
+	after := state.Chains.Get(chainID).Metadata.Height
+	eb := factom.EBlock{ChainID: chainID}
+	if err := eb.Get(ctx); err != nil {
+		return err
+	}
+	if !eb.IsPopulated() {
+		return fmt.Errorf("chain not found")
+	}
+	ebs := []factom.EBlock{eb}
+	for !ebs[0].IsFirst() && ebs[0].Height > after {
+		prev := ebs[0].Prev()
+		if err := prev.Get(ctx); err != nil {
+			return err
+		}
+		if !prev.IsPopulated() {
+			return fmt.Errorf("EBlock %v not found", prev.KeyMR)
+		}
+		ebs = append([]factom.EBlock{prev}, ebs...)
+	}
+	return processEBlocks(ctx, chainID, ebs, state.SavedHeight)
+}
+
+// processEBlocks processes each of ebs, which must be in order, for the chain
+// with chainID up to and including maxHeight. EBlocks that have already been
+// processed are skipped.
+func processEBlocks(ctx context.Context, chainID *factom.Bytes32,
+	ebs []factom.EBlock, maxHeight uint64) error {
+	for _, eb := range ebs {
+		if eb.Height > maxHeight {
+			break
+		}
+		chain := state.Chains.Get(chainID)
+		if chain.IsTracked() && eb.Height <= chain.Metadata.Height {
+			continue
+		}
+		if err := chain.Process(ctx, eb); err != nil {
+			return err
+		}
+		if chain.IsIgnored() {
+			return errNotTokenChain
+		}
+	}
+	return nil
+}
+
+// retry calls f until it succeeds or returns an error that is not due to a
+// network failure, using the same backoff as the engine. If ctx is done first,
+// its error is returned.
+func retry(ctx context.Context, f func() error) error {
+	var retryDelay time.Duration
+	for {
+		err := f()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		cause := err
+		if fetchErr, ok := err.(state.FetchError); ok {
+			cause = fetchErr.Err
+		}
+		if err == nil || !factom.IsNetworkError(cause) {
+			return err
+		}
+		retryDelay *= 2
+		if retryDelay < minRetryDelay {
+			retryDelay = minRetryDelay
+		}
+		if retryDelay > maxRetryDelay {
+			retryDelay = maxRetryDelay
+		}
+		log.Warnf("%v, retrying in %v...", err, retryDelay)
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -1,65 +1,117 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/flag"
 	_log "github.com/Factom-Asset-Tokens/fatd/log"
 	"github.com/Factom-Asset-Tokens/fatd/state"
 )
 
 var (
 	returnError chan error
-	stop        chan error
+	engineCtx   context.Context
+	cancel      context.CancelFunc
+	done        chan struct{}
 	log         _log.Log
-	scanTicker  *time.Ticker
+
+	// blockMu is held while each DBlock is processed and its height is
+	// saved.
+	blockMu sync.Mutex
 )
 
 const (
 	scanInterval = 30 * time.Second
+
+	// minRetryDelay and maxRetryDelay bound the exponential backoff used
+	// to retry scanning after a failure.
+	minRetryDelay = 1 * time.Second
+	maxRetryDelay = 5 * time.Minute
 )
 
+// fatalError is returned by scanNewBlocks for failures to save state. All
+// other errors are retried.
+type fatalError struct {
+	Err error
+}
+
+func (err fatalError) Error() string {
+	return err.Err.Error()
+}
+
+// Start loads the state and starts scanning for new blocks. Only failures to
+// save state are sent on the returned channel, after which the engine stops.
+// Failures to reach factomd are retried with exponential backoff.
 func Start() (chan error, error) {
-	if err := state.Load(); err != nil {
+	log = _log.New("engine")
+
+	if flag.Cache {
+		if err := setupCache(); err != nil {
+			return nil, err
+		}
+	}
+
+	engineCtx, cancel = context.WithCancel(context.Background())
+	if err := state.Load(engineCtx); err != nil {
+		cancel()
 		return nil, err
 	}
 
-	log = _log.New("engine")
 	returnError = make(chan error, 1)
-	stop = make(chan error)
-
-	scanTicker = time.NewTicker(scanInterval)
-	go engine()
+	done = make(chan struct{})
+	go engine(engineCtx)
 
 	return returnError, nil
 }
 
+// Stop cancels any requests in progress, waits for the engine and any
+// backfills to stop and then closes the state.
 func Stop() error {
-	if stop == nil {
+	if cancel == nil {
 		return fmt.Errorf("Already not running")
 	}
-	close(stop)
+	cancel()
+	<-done
+	backfills.Wait()
+	cancel = nil
 	state.Close()
 	return nil
 }
 
-func errorStop(err error) {
-	returnError <- err
-	scanTicker.Stop()
-}
-
-func engine() {
+func engine(ctx context.Context) {
+	defer close(done)
+	var retryDelay time.Duration
 	for {
-		if err := scanNewBlocks(); err != nil {
-			errorStop(fmt.Errorf("scanNewBlocks(): %v", err))
+		wait := scanInterval
+		err := scanNewBlocks(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		switch err.(type) {
+		case nil:
+			retryDelay = 0
+		case fatalError:
+			returnError <- fmt.Errorf("scanNewBlocks(): %v", err)
+			return
+		default:
+			retryDelay *= 2
+			if retryDelay < minRetryDelay {
+				retryDelay = minRetryDelay
+			}
+			if retryDelay > maxRetryDelay {
+				retryDelay = maxRetryDelay
+			}
+			log.Errorf("scanNewBlocks(): %v", err)
+			log.Infof("Retrying in %v...", retryDelay)
+			wait = retryDelay
 		}
 		select {
-		case <-scanTicker.C:
-			continue
-		case <-stop:
-			scanTicker.Stop()
+		case <-time.After(wait):
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -67,9 +119,9 @@ func engine() {
 
 var synced bool
 
-func scanNewBlocks() error {
+func scanNewBlocks(ctx context.Context) error {
 	// Get the current leader's block height
-	heights, err := factom.GetHeights()
+	heights, err := factom.GetHeights(ctx)
 	if err != nil {
 		return fmt.Errorf("factom.GetHeights(): %v", err)
 	}
@@ -81,52 +133,12 @@ func scanNewBlocks() error {
 	// Scan blocks from the last saved block height up to but not including
 	// the leader height
 	for height := state.SavedHeight + 1; height <= currentHeight; height++ {
-		log.Debugf("Scanning block %v for FAT entries.", height)
-		dblock := factom.DBlock{Height: height}
-		if err := dblock.Get(); err != nil {
-			return fmt.Errorf("%#v.Get(): %v", dblock, err)
-		}
-
-		wg := &sync.WaitGroup{}
-		chainIDs := make(map[factom.Bytes32]struct{}, len(dblock.EBlocks))
-		for _, eb := range dblock.EBlocks {
-			// Because chains are processed concurrently, there
-			// must never be a duplicate ChainID. Since the DBlock
-			// is external data we must validate it. Factomd should
-			// never return a DBlock with duplicate Chain IDs in
-			// its EBlocks. If this happens it indicates a serious
-			// issue with the factomd API endpoint we are talking
-			// to.
-			_, ok := chainIDs[*eb.ChainID]
-			if ok {
-				return fmt.Errorf("duplicate ChainID in DBlock.EBlocks")
-			}
-			chainIDs[*eb.ChainID] = struct{}{}
-
-			// Skip ignored chains or EBlocks for heights earlier
-			// than this chain's state.
-			chain := state.Chains.Get(eb.ChainID)
-			if chain.IsIgnored() || dblock.Height <= chain.Metadata.Height {
-				continue
-			}
-
-			eb := eb
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				if err := chain.Process(eb); err != nil {
-					go errorStop(err)
-				}
-			}()
+		waitWhilePaused(ctx)
+		if err := scanBlock(ctx, height); err != nil {
+			return err
 		}
-		wg.Wait()
-		select {
-		case <-stop:
+		if ctx.Err() != nil {
 			return nil
-		default:
-		}
-		if err := state.SaveHeight(height); err != nil {
-			return err
 		}
 	}
 	if !synced {
@@ -136,3 +148,89 @@ func scanNewBlocks() error {
 
 	return nil
 }
+
+// scanBlock processes the DBlock at height and saves the height.
+func scanBlock(ctx context.Context, height uint64) error {
+	blockMu.Lock()
+	defer blockMu.Unlock()
+	log.Debugf("Scanning block %v for FAT entries.", height)
+	dblock := factom.DBlock{Height: height}
+	if err := dblock.Get(ctx); err != nil {
+		return fmt.Errorf("%#v.Get(): %v", dblock, err)
+	}
+	if !dblock.IsPopulated() {
+		return fmt.Errorf("DBlock %v not found", height)
+	}
+	if err := processDBlock(ctx, dblock, nil); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := state.SaveHeight(height); err != nil {
+		return fatalError{err}
+	}
+	return nil
+}
+
+// processDBlock processes the EBlocks in dblock for each chain concurrently.
+// If only is not nil, all other chains are skipped. If any chain fails with a
+// state.FetchError, the error is returned once all chains are done so that the
+// DBlock is retried. Chains that succeeded are skipped on the retry since their
+// height has been saved. Chains that become quarantined are skipped. Any other
+// error is a fatalError.
+func processDBlock(ctx context.Context, dblock factom.DBlock,
+	only map[factom.Bytes32]bool) error {
+	wg := &sync.WaitGroup{}
+	chainIDs := make(map[factom.Bytes32]struct{}, len(dblock.EBlocks))
+	errs := make([]error, len(dblock.EBlocks))
+	chains := make([]state.Chain, len(dblock.EBlocks))
+	for i, eb := range dblock.EBlocks {
+		// Because chains are processed concurrently, there must never
+		// be a duplicate ChainID. Since the DBlock is external data we
+		// must validate it. Factomd should never return a DBlock with
+		// duplicate Chain IDs in its EBlocks. If this happens it
+		// indicates a serious issue with the factomd API endpoint we
+		// are talking to.
+		_, ok := chainIDs[*eb.ChainID]
+		if ok {
+			return fmt.Errorf("duplicate ChainID in DBlock.EBlocks")
+		}
+		chainIDs[*eb.ChainID] = struct{}{}
+		if only != nil && !only[*eb.ChainID] {
+			continue
+		}
+
+		// Skip ignored, quarantined or backfilling chains or EBlocks
+		// for heights earlier than this chain's state.
+		chain := state.Chains.Get(eb.ChainID)
+		if chain.IsIgnored() || chain.IsQuarantined() ||
+			chain.Backfilling || dblock.Height <= chain.Metadata.Height {
+			continue
+		}
+
+		i, eb := i, eb
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = chain.Process(ctx, eb)
+			chains[i] = chain
+		}()
+	}
+	wg.Wait()
+
+	var retry error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if _, ok := err.(state.FetchError); !ok {
+			return fatalError{err}
+		}
+		if chains[i].IsQuarantined() {
+			continue
+		}
+		retry = err
+	}
+	return retry
+}
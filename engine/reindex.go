@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/flag"
+	_log "github.com/Factom-Asset-Tokens/fatd/log"
+	"github.com/Factom-Asset-Tokens/fatd/state"
+)
+
+// setupCache enables the factom.BlockCache in the cache directory under
+// -dbpath.
+func setupCache() error {
+	cache, err := factom.NewCache(flag.DBPath + "/cache")
+	if err != nil {
+		return fmt.Errorf("factom.NewCache(): %v", err)
+	}
+	factom.BlockCache = cache
+	return nil
+}
+
+// Reindex rebuilds the databases of the chains with the given chainIDs, or of
+// all chains if chainIDs is empty, using only the blocks and entries in the
+// cache, without making any requests to factomd. The previous databases are
+// kept with a ".bak" extension.
+//
+// The chains are synced from -startscanheight, or the usual starting height,
+// up to the highest cached DBlock. Every DBlock in that range must be cached.
+func Reindex(chainIDs []*factom.Bytes32) error {
+	log = _log.New("engine")
+
+	if err := setupCache(); err != nil {
+		return err
+	}
+	factom.BlockCache.Offline = true
+	heights, err := factom.BlockCache.DBlockHeights()
+	if err != nil {
+		return err
+	}
+	if len(heights) == 0 {
+		return fmt.Errorf("no DBlocks cached in %v",
+			factom.BlockCache.Dir)
+	}
+
+	var only map[factom.Bytes32]bool
+	if len(chainIDs) > 0 {
+		only = make(map[factom.Bytes32]bool, len(chainIDs))
+		for _, chainID := range chainIDs {
+			only[*chainID] = true
+		}
+	}
+
+	start := state.SavedHeight + 1
+	if flag.StartScanHeight > -1 {
+		start = uint64(flag.StartScanHeight)
+	}
+	end := heights[len(heights)-1]
+
+	if err := state.Reset(chainIDs); err != nil {
+		return fmt.Errorf("state.Reset(): %v", err)
+	}
+	ctx := context.Background()
+	if err := state.Load(ctx); err != nil {
+		return fmt.Errorf("state.Load(): %v", err)
+	}
+	defer state.Close()
+
+	log.Infof("Reindexing from block %v to %v...", start, end)
+	for height := start; height <= end; height++ {
+		dblock := factom.DBlock{Height: height}
+		if err := dblock.Get(ctx); err != nil {
+			return fmt.Errorf("DBlock %v: %v", height, err)
+		}
+		if err := processDBlock(ctx, dblock, only); err != nil {
+			return fmt.Errorf("DBlock %v: %v", height, err)
+		}
+		if only == nil {
+			err = state.SaveHeight(height)
+		} else {
+			for _, chainID := range chainIDs {
+				err = state.SaveChainHeight(chainID, height)
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, chainID := range chainIDs {
+		if !state.Chains.Get(chainID).IsTracked() {
+			return fmt.Errorf("chain %v was not tracked, "+
+				"its previous database is in %v/%v.sqlite3.bak",
+				chainID, flag.DBPath, chainID)
+		}
+	}
+	log.Infof("Reindexed.")
+	return nil
+}
@@ -1,6 +1,7 @@
 package factom
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 
@@ -35,14 +36,14 @@ func NewAddressFromString(adrStr string) (Address, error) {
 	return adr, err
 }
 
-func (a *Address) Get() error {
+func (a *Address) Get(ctx context.Context) error {
 	params := struct {
 		A *Address `json:"address"`
 	}{A: a}
 	result := struct {
 		A *Address `json:"secret"`
 	}{A: a}
-	if err := WalletRequest("address", params, &result); err != nil {
+	if err := WalletRequest(ctx, "address", params, &result); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,341 @@
+package factom
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// BlockCache, if not nil, is used by DBlock.Get, EBlock.Get,
+// EBlock.GetChainHead and Entry.Get to store the raw data of everything they
+// fetch from factomd, and to avoid fetching it again.
+var BlockCache *Cache
+
+// ErrNotCached is returned by the Get methods if the BlockCache is Offline and
+// does not hold the requested data.
+var ErrNotCached = fmt.Errorf("not cached")
+
+// Directories within Cache.Dir.
+const (
+	dblocksDir    = "dblocks"
+	heightsDir    = "heights"
+	eblocksDir    = "eblocks"
+	entriesDir    = "entries"
+	chainHeadsDir = "chainheads"
+)
+
+// Cache is an on-disk, content-addressed store of raw DBlocks and EBlocks by
+// KeyMR, and raw Entries by hash. DBlocks are also indexed by height, and the
+// chain head of each chain is recorded.
+//
+// All data is verified when it is read by recomputing its KeyMR or
+// EntryHash. Data that fails verification is removed and fetched again,
+// unless the Cache is Offline.
+type Cache struct {
+	Dir string
+
+	// Offline prevents the Get methods from making any requests to
+	// factomd. Anything not in the cache results in ErrNotCached.
+	Offline bool
+}
+
+// NewCache returns a Cache in dir, creating it if it does not exist.
+func NewCache(dir string) (*Cache, error) {
+	for _, sub := range []string{dblocksDir, heightsDir, eblocksDir,
+		entriesDir, chainHeadsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+// DBlockHeights returns the heights of all cached DBlocks in ascending order.
+func (c *Cache) DBlockHeights() ([]uint64, error) {
+	files, err := ioutil.ReadDir(filepath.Join(c.Dir, heightsDir))
+	if err != nil {
+		return nil, err
+	}
+	heights := make([]uint64, 0, len(files))
+	for _, f := range files {
+		height, err := strconv.ParseUint(f.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] < heights[j]
+	})
+	return heights, nil
+}
+
+// path returns the path to the data for hash, which is sharded by the first
+// byte of the hash to keep directories small.
+func (c *Cache) path(dir string, hash *Bytes32) string {
+	name := hash.String()
+	return filepath.Join(c.Dir, dir, name[:2], name)
+}
+
+func (c *Cache) heightPath(height uint64) string {
+	return filepath.Join(c.Dir, heightsDir, strconv.FormatUint(height, 10))
+}
+
+func (c *Cache) chainHeadPath(chainID *Bytes32) string {
+	return filepath.Join(c.Dir, chainHeadsDir, chainID.String())
+}
+
+// read returns the contents of fpath, or ErrNotCached if it does not exist.
+func (c *Cache) read(fpath string) ([]byte, error) {
+	data, err := ioutil.ReadFile(fpath)
+	if os.IsNotExist(err) {
+		return nil, ErrNotCached
+	}
+	return data, err
+}
+
+// write data to fpath atomically so that a partially written file is never
+// read.
+func (c *Cache) write(fpath string, data []byte) error {
+	dir := filepath.Dir(fpath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fpath)
+}
+
+// corrupted removes fpath, which failed verification with err. An error is
+// returned if c is Offline, otherwise ErrNotCached is returned so that the
+// data is fetched again.
+func (c *Cache) corrupted(fpath string, err error) error {
+	if rmErr := os.Remove(fpath); rmErr != nil {
+		return rmErr
+	}
+	if c.Offline {
+		return fmt.Errorf("corrupted cache file %v: %v", fpath, err)
+	}
+	return ErrNotCached
+}
+
+// getDBlock populates db from the cached DBlock at db.Height.
+func (c *Cache) getDBlock(db *DBlock) error {
+	hpath := c.heightPath(db.Height)
+	data, err := c.read(hpath)
+	if err != nil {
+		return err
+	}
+	if len(data) != len(Bytes32{}) {
+		return c.corrupted(hpath, fmt.Errorf("invalid length"))
+	}
+	keyMR := NewBytes32(data)
+
+	fpath := c.path(dblocksDir, keyMR)
+	if data, err = c.read(fpath); err != nil {
+		return err
+	}
+	var cached DBlock
+	if err := cached.UnmarshalBinary(data); err != nil {
+		return c.corrupted(fpath, err)
+	}
+	if *cached.KeyMR != *keyMR {
+		return c.corrupted(fpath, fmt.Errorf("invalid KeyMR"))
+	}
+	if cached.Height != db.Height {
+		return c.corrupted(hpath, fmt.Errorf("invalid height"))
+	}
+	*db = cached
+	return nil
+}
+
+// putDBlock fetches the raw data for db, which must have been populated by
+// factomd, checks that it matches db and then caches it.
+func (c *Cache) putDBlock(ctx context.Context, db *DBlock) error {
+	if db.KeyMR == nil {
+		return fmt.Errorf("DBlock %v: KeyMR is nil", db.Height)
+	}
+	data, err := getRawData(ctx, db.KeyMR)
+	if err != nil {
+		return err
+	}
+	var raw DBlock
+	if err := raw.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("DBlock %v: raw data: %v", db.Height, err)
+	}
+	if *raw.KeyMR != *db.KeyMR || raw.Height != db.Height ||
+		len(raw.EBlocks) != len(db.EBlocks) {
+		return fmt.Errorf("DBlock %v: raw data does not match", db.Height)
+	}
+	for i, eb := range raw.EBlocks {
+		if *eb.ChainID != *db.EBlocks[i].ChainID ||
+			*eb.KeyMR != *db.EBlocks[i].KeyMR {
+			return fmt.Errorf("DBlock %v: raw data does not match",
+				db.Height)
+		}
+	}
+	db.timestamp = raw.timestamp
+
+	if err := c.write(c.path(dblocksDir, db.KeyMR), data); err != nil {
+		return err
+	}
+	return c.write(c.heightPath(db.Height), db.KeyMR[:])
+}
+
+// getEBlock populates eb from the cached EBlock with eb.KeyMR. The Entry
+// Timestamps are computed from the cached DBlock that the EBlock belongs to.
+func (c *Cache) getEBlock(eb *EBlock) error {
+	fpath := c.path(eblocksDir, eb.KeyMR)
+	data, err := c.read(fpath)
+	if err != nil {
+		return err
+	}
+	var cached EBlock
+	if err := cached.UnmarshalBinary(data); err != nil {
+		return c.corrupted(fpath, err)
+	}
+	if *cached.KeyMR != *eb.KeyMR ||
+		(eb.ChainID != nil && *cached.ChainID != *eb.ChainID) {
+		return c.corrupted(fpath, fmt.Errorf("invalid KeyMR"))
+	}
+
+	db := DBlock{Height: cached.Height}
+	if err := c.getDBlock(&db); err != nil {
+		return err
+	}
+	if !db.contains(cached) {
+		return c.corrupted(fpath,
+			fmt.Errorf("not in DBlock %v", cached.Height))
+	}
+	if err := cached.unmarshalBinary(data, db.timestamp); err != nil {
+		return err
+	}
+	*eb = cached
+	return nil
+}
+
+// putEBlock fetches the raw data for eb, which must have been populated by
+// factomd, checks that it matches eb and then caches it. The DBlock that eb
+// belongs to is also cached since its timestamp is needed to compute the Entry
+// Timestamps.
+func (c *Cache) putEBlock(ctx context.Context, eb *EBlock) error {
+	db := DBlock{Height: eb.Height}
+	if err := db.Get(ctx); err != nil {
+		return err
+	}
+	if !db.IsPopulated() {
+		return fmt.Errorf("EBlock %v: DBlock %v not found",
+			eb.KeyMR, eb.Height)
+	}
+	data, err := getRawData(ctx, eb.KeyMR)
+	if err != nil {
+		return err
+	}
+	var raw EBlock
+	if err := raw.unmarshalBinary(data, db.timestamp); err != nil {
+		return fmt.Errorf("EBlock %v: raw data: %v", eb.KeyMR, err)
+	}
+	if !raw.matches(*eb) || !db.contains(raw) {
+		return fmt.Errorf("EBlock %v: raw data does not match", eb.KeyMR)
+	}
+	return c.write(c.path(eblocksDir, eb.KeyMR), data)
+}
+
+// contains returns true if eb is one of the EBlocks in db.
+func (db DBlock) contains(eb EBlock) bool {
+	for _, dbeb := range db.EBlocks {
+		if *dbeb.ChainID == *eb.ChainID && *dbeb.KeyMR == *eb.KeyMR {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if raw, unmarshaled from binary, agrees with eb, as
+// returned by factomd.
+func (raw EBlock) matches(eb EBlock) bool {
+	if *raw.KeyMR != *eb.KeyMR ||
+		(eb.ChainID != nil && *raw.ChainID != *eb.ChainID) ||
+		*raw.PrevKeyMR != *eb.PrevKeyMR || raw.Height != eb.Height ||
+		len(raw.Entries) != len(eb.Entries) {
+		return false
+	}
+	for i, e := range raw.Entries {
+		ebe := eb.Entries[i]
+		if *e.Hash != *ebe.Hash {
+			return false
+		}
+		if ebe.Timestamp != nil &&
+			e.Timestamp.Unix() != ebe.Timestamp.Unix() {
+			return false
+		}
+	}
+	return true
+}
+
+// getEntry populates e from the cached Entry with e.Hash.
+func (c *Cache) getEntry(e *Entry) error {
+	fpath := c.path(entriesDir, e.Hash)
+	data, err := c.read(fpath)
+	if err != nil {
+		return err
+	}
+	if EntryHash(data) != *e.Hash {
+		return c.corrupted(fpath, fmt.Errorf("invalid EntryHash"))
+	}
+	if err := e.UnmarshalBinary(data); err != nil {
+		return c.corrupted(fpath, err)
+	}
+	return nil
+}
+
+// putEntry caches the raw data of e after checking its hash.
+func (c *Cache) putEntry(e *Entry, data []byte) error {
+	if EntryHash(data) != *e.Hash {
+		return fmt.Errorf("Entry %v: raw data does not match", e.Hash)
+	}
+	return c.write(c.path(entriesDir, e.Hash), data)
+}
+
+// getChainHead populates eb.KeyMR with the cached chain head of eb.ChainID.
+// The KeyMR is left nil if the chain did not exist when it was cached.
+func (c *Cache) getChainHead(eb *EBlock) error {
+	fpath := c.chainHeadPath(eb.ChainID)
+	data, err := c.read(fpath)
+	if err != nil {
+		return err
+	}
+	switch len(data) {
+	case 0:
+		eb.KeyMR = nil
+	case len(Bytes32{}):
+		eb.KeyMR = NewBytes32(data)
+	default:
+		return c.corrupted(fpath, fmt.Errorf("invalid length"))
+	}
+	return nil
+}
+
+// putChainHead records eb.KeyMR as the chain head of eb.ChainID. A nil KeyMR
+// records that the chain does not exist.
+func (c *Cache) putChainHead(eb *EBlock) error {
+	var data []byte
+	if eb.KeyMR != nil {
+		data = eb.KeyMR[:]
+	}
+	return c.write(c.chainHeadPath(eb.ChainID), data)
+}
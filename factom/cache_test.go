@@ -0,0 +1,314 @@
+package factom
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func marshalDBlock(height, minutes uint32, ebs []EBlock) []byte {
+	header := make([]byte, dblockHeaderLen)
+	body := make([]byte, 0, len(ebs)*dblockEntryLen)
+	leaves := make([]Bytes32, len(ebs))
+	for i, eb := range ebs {
+		dbe := append(eb.ChainID[:], eb.KeyMR[:]...)
+		leaves[i] = sha256.Sum256(dbe)
+		body = append(body, dbe...)
+	}
+	bodyMR := merkleRoot(leaves)
+	copy(header[5:37], bodyMR[:])
+	binary.BigEndian.PutUint32(header[101:105], minutes)
+	binary.BigEndian.PutUint32(header[105:109], height)
+	binary.BigEndian.PutUint32(header[109:113], uint32(len(ebs)))
+	return append(header, body...)
+}
+
+func marshalEBlock(chainID, prevKeyMR Bytes32, height uint32,
+	items []Bytes32) []byte {
+	header := make([]byte, eblockHeaderLen)
+	bodyMR := merkleRoot(items)
+	copy(header[:32], chainID[:])
+	copy(header[32:64], bodyMR[:])
+	copy(header[64:96], prevKeyMR[:])
+	binary.BigEndian.PutUint32(header[132:136], height)
+	binary.BigEndian.PutUint32(header[136:140], uint32(len(items)))
+	for _, item := range items {
+		header = append(header, item[:]...)
+	}
+	return header
+}
+
+func minute(n byte) Bytes32 { return Bytes32{31: n} }
+
+// testChain is a chain with a single EBlock with three entries in DBlock 10.
+type testChain struct {
+	ChainID   Bytes32
+	Minutes   uint32
+	Entries   []Entry
+	EntryData [][]byte
+	EBlock    []byte
+	EBKeyMR   Bytes32
+	DBlock    []byte
+	DBKeyMR   Bytes32
+}
+
+func newTestChain() testChain {
+	var c testChain
+	c.ChainID = Bytes32{0xcc}
+	c.Minutes = 25000000
+	var items []Bytes32
+	for i := 0; i < 3; i++ {
+		e := Entry{ChainID: &c.ChainID, ExtIDs: []Bytes{{byte(i)}},
+			Content: Bytes("content")}
+		data := e.MarshalBinary()
+		hash := EntryHash(data)
+		e.Hash = &hash
+		c.Entries = append(c.Entries, e)
+		c.EntryData = append(c.EntryData, data)
+		items = append(items, hash)
+		if i != 1 {
+			items = append(items, minute(byte(i+1)))
+		}
+	}
+	c.EBlock = marshalEBlock(c.ChainID, Bytes32{}, 10, items)
+	c.EBKeyMR = computeKeyMR(c.EBlock[:eblockHeaderLen],
+		*NewBytes32(c.EBlock[32:64]))
+	c.DBlock = marshalDBlock(10, c.Minutes, []EBlock{
+		{ChainID: &Bytes32{0x0a}, KeyMR: &Bytes32{0x01}},
+		{ChainID: &c.ChainID, KeyMR: &c.EBKeyMR}})
+	c.DBKeyMR = computeKeyMR(c.DBlock[:dblockHeaderLen],
+		*NewBytes32(c.DBlock[5:37]))
+	return c
+}
+
+// Timestamps returns the expected Entry timestamps in seconds.
+func (c testChain) Timestamps() []int64 {
+	ts := int64(c.Minutes) * 60
+	return []int64{ts + 60, ts + 3*60, ts + 3*60}
+}
+
+func TestUnmarshalBinary(t *testing.T) {
+	c := newTestChain()
+	assert := assert.New(t)
+
+	var db DBlock
+	require.NoError(t, db.UnmarshalBinary(c.DBlock))
+	assert.Equal(uint64(10), db.Height)
+	assert.Equal(c.DBKeyMR, *db.KeyMR)
+	require.Len(t, db.EBlocks, 2)
+	assert.Equal(c.ChainID, *db.EBlocks[1].ChainID)
+	assert.Equal(c.EBKeyMR, *db.EBlocks[1].KeyMR)
+
+	var eb EBlock
+	require.NoError(t, eb.unmarshalBinary(c.EBlock, db.timestamp))
+	assert.Equal(c.EBKeyMR, *eb.KeyMR)
+	assert.Equal(c.ChainID, *eb.ChainID)
+	assert.True(eb.IsFirst())
+	assert.Equal(uint64(10), eb.Height)
+	require.Len(t, eb.Entries, 3)
+	for i, e := range eb.Entries {
+		assert.Equal(*c.Entries[i].Hash, *e.Hash)
+		assert.Equal(c.Timestamps()[i], e.Timestamp.Unix())
+		assert.Equal(uint64(10), e.Height)
+	}
+
+	// Tampering with the body must invalidate the BodyMR.
+	data := append([]byte{}, c.DBlock...)
+	data[len(data)-1]++
+	assert.EqualError(db.UnmarshalBinary(data), "invalid BodyMR")
+	data = append([]byte{}, c.EBlock...)
+	data[len(data)-33]++
+	assert.EqualError(eb.UnmarshalBinary(data), "invalid BodyMR")
+
+	// Tampering with the header must change the KeyMR.
+	data = append([]byte{}, c.DBlock...)
+	data[0]++
+	require.NoError(t, db.UnmarshalBinary(data))
+	assert.NotEqual(c.DBKeyMR, *db.KeyMR)
+
+	assert.EqualError(db.UnmarshalBinary(c.DBlock[:dblockHeaderLen+1]),
+		"invalid block count")
+	assert.EqualError(eb.UnmarshalBinary(c.EBlock[:eblockHeaderLen-1]),
+		"insufficient length")
+	// Drop the final minute marker.
+	data = append([]byte{}, c.EBlock[:len(c.EBlock)-32]...)
+	binary.BigEndian.PutUint32(data[136:140], 4)
+	assert.EqualError(eb.UnmarshalBinary(data),
+		"missing final minute marker")
+}
+
+func TestMerkleRoot(t *testing.T) {
+	assert := assert.New(t)
+	a, b, c := Bytes32{1}, Bytes32{2}, Bytes32{3}
+	pair := func(l, r Bytes32) Bytes32 {
+		return sha256.Sum256(append(l[:], r[:]...))
+	}
+	assert.Equal(Bytes32{}, merkleRoot(nil))
+	assert.Equal(a, merkleRoot([]Bytes32{a}))
+	assert.Equal(pair(a, b), merkleRoot([]Bytes32{a, b}))
+	assert.Equal(pair(pair(a, b), pair(c, c)),
+		merkleRoot([]Bytes32{a, b, c}))
+}
+
+// newCacheFactomd returns a fake factomd serving c.
+func newCacheFactomd(c testChain) *testFactomd {
+	raw := map[Bytes32][]byte{c.DBKeyMR: c.DBlock, c.EBKeyMR: c.EBlock}
+	for i, e := range c.Entries {
+		raw[*e.Hash] = c.EntryData[i]
+	}
+	hashParam := func(params json.RawMessage, name string) Bytes32 {
+		var p map[string]Bytes32
+		json.Unmarshal(params, &p)
+		return p[name]
+	}
+	f := &testFactomd{}
+	methods := jrpc.MethodMap{
+		"raw-data": func(params json.RawMessage) interface{} {
+			data, ok := raw[hashParam(params, "hash")]
+			if !ok {
+				return jrpc.NewInvalidParamsError("not found")
+			}
+			return map[string]Bytes{"data": data}
+		},
+		"dblock-by-height": func(_ json.RawMessage) interface{} {
+			var db DBlock
+			db.UnmarshalBinary(c.DBlock)
+			return map[string]interface{}{"dblock": db}
+		},
+		"entry-block": func(_ json.RawMessage) interface{} {
+			var entries []map[string]interface{}
+			for i, e := range c.Entries {
+				entries = append(entries, map[string]interface{}{
+					"entryhash": e.Hash,
+					"timestamp": c.Timestamps()[i],
+				})
+			}
+			return map[string]interface{}{
+				"header": map[string]interface{}{
+					"prevkeymr": Bytes32{},
+					"dbheight":  10,
+				},
+				"entrylist": entries,
+			}
+		},
+		"chain-head": func(_ json.RawMessage) interface{} {
+			return map[string]Bytes32{"chainhead": c.EBKeyMR}
+		},
+	}
+	f.Server = httptest.NewServer(jrpc.HTTPRequestHandler(methods))
+	return f
+}
+
+func setBlockCache(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "fatd-cache-test")
+	require.NoError(t, err)
+	BlockCache, err = NewCache(dir)
+	require.NoError(t, err)
+	return func() {
+		BlockCache = nil
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := newTestChain()
+	f := newCacheFactomd(c)
+	defer f.Close()
+	defer setFactomdServers(f)()
+	defer setBlockCache(t)()
+	assert := assert.New(t)
+
+	// Populate the cache.
+	eb := EBlock{ChainID: &c.ChainID}
+	require.NoError(t, eb.Get(ctx))
+	for i := range eb.Entries {
+		require.NoError(t, eb.Entries[i].Get(ctx))
+	}
+
+	// Nothing may be fetched from factomd while Offline.
+	f.Close()
+	BlockCache.Offline = true
+
+	db := DBlock{Height: 10}
+	require.NoError(t, db.Get(ctx))
+	assert.Equal(c.DBKeyMR, *db.KeyMR)
+
+	eb = EBlock{ChainID: &c.ChainID}
+	require.NoError(t, eb.Get(ctx))
+	assert.Equal(c.EBKeyMR, *eb.KeyMR)
+	require.Len(t, eb.Entries, 3)
+	for i := range eb.Entries {
+		e := &eb.Entries[i]
+		assert.Equal(c.Timestamps()[i], e.Timestamp.Unix())
+		require.NoError(t, e.Get(ctx))
+		assert.Equal(c.Entries[i].Content, e.Content)
+		assert.Equal(c.Entries[i].ExtIDs, e.ExtIDs)
+	}
+
+	heights, err := BlockCache.DBlockHeights()
+	assert.NoError(err)
+	assert.Equal([]uint64{10}, heights)
+
+	assert.Equal(ErrNotCached, (&DBlock{Height: 11}).Get(ctx))
+	assert.Equal(ErrNotCached, (&Entry{Hash: &Bytes32{1}}).Get(ctx))
+
+	// Corrupted data is removed and is an error while Offline.
+	fpath := BlockCache.path(entriesDir, c.Entries[0].Hash)
+	require.NoError(t, ioutil.WriteFile(fpath, []byte("corrupt"), 0644))
+	e := Entry{Hash: c.Entries[0].Hash}
+	err = e.Get(ctx)
+	if assert.Error(err) {
+		assert.True(strings.HasPrefix(err.Error(),
+			"corrupted cache file "+fpath))
+	}
+	assert.Equal(ErrNotCached, e.Get(ctx))
+
+	fpath = BlockCache.path(eblocksDir, &c.EBKeyMR)
+	data, err := ioutil.ReadFile(fpath)
+	require.NoError(t, err)
+	data[eblockHeaderLen]++
+	require.NoError(t, ioutil.WriteFile(fpath, data, 0644))
+	err = (&EBlock{KeyMR: &c.EBKeyMR}).Get(ctx)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "invalid BodyMR")
+	}
+	_, err = os.Stat(fpath)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestCacheRefetchCorrupted(t *testing.T) {
+	c := newTestChain()
+	f := newCacheFactomd(c)
+	defer f.Close()
+	defer setFactomdServers(f)()
+	defer setBlockCache(t)()
+
+	db := DBlock{Height: 10}
+	require.NoError(t, db.Get(ctx))
+
+	// Point the height at the wrong DBlock.
+	hpath := BlockCache.heightPath(10)
+	require.NoError(t, ioutil.WriteFile(hpath, c.EBKeyMR[:], 0644))
+
+	// Online, the DBlock is fetched again and the cache is repaired.
+	db = DBlock{Height: 10}
+	require.NoError(t, db.Get(ctx))
+	assert.Equal(t, c.DBKeyMR, *db.KeyMR)
+	data, err := ioutil.ReadFile(filepath.Join(BlockCache.Dir, heightsDir,
+		"10"))
+	require.NoError(t, err)
+	assert.Equal(t, c.DBKeyMR[:], data)
+	assert.False(t, db.timestamp.IsZero())
+	assert.Equal(t, time.Unix(int64(c.Minutes)*60, 0), db.timestamp)
+}
@@ -0,0 +1,109 @@
+package factom
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/FactomProject/ed25519"
+)
+
+// CreateWithKey commits and reveals the entry directly with factomd, paying
+// with the Entry Credit address of the given private key. Unlike Create,
+// factom-walletd is not used. If e.ChainID is nil, then a new chain is
+// created using e.ExtIDs as the NameIDs and e.ChainID is set.
+func (e *Entry) CreateWithKey(ctx context.Context,
+	es *PrivateKey) (*Bytes32, error) {
+	newChain := e.ChainID == nil
+	if newChain {
+		chainID := ChainID(e.ExtIDs)
+		e.ChainID = &chainID
+	}
+	commit, err := e.composeCommit(es, newChain, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	method := "entry"
+	if newChain {
+		method = "chain"
+	}
+
+	var result commitResult
+	if err := FactomdRequest(ctx, "commit-"+method, struct {
+		Message string `json:"message"`
+	}{Message: hex.EncodeToString(commit)}, &result); err != nil {
+		return nil, err
+	}
+	if err := FactomdRequest(ctx, "reveal-"+method, struct {
+		Entry string `json:"entry"`
+	}{Entry: hex.EncodeToString(e.MarshalBinary())}, e); err != nil {
+		return nil, err
+	}
+	return result.TxID, nil
+}
+
+// ECCost returns the number of Entry Credits required to commit the entry,
+// not including the additional 10 EC required to create a new chain.
+func (e Entry) ECCost() (int, error) {
+	size := len(e.MarshalBinary()) - headerLen
+	if size > 10240 {
+		return 0, fmt.Errorf("entry size exceeds 10240 bytes")
+	}
+	cost := (size + 1023) / 1024
+	if cost == 0 {
+		cost = 1
+	}
+	return cost, nil
+}
+
+// composeCommit returns the binary commit message for the entry signed by es.
+// Entry commits are encoded as follows, and chain commits additionally include
+// the [ChainID Hash] and [Commit Weld] after the timestamp:
+//
+// [Version byte (0x00)] +
+// [Timestamp in milliseconds (6 bytes)] +
+// [ChainID Hash (Bytes32)] + [Commit Weld (Bytes32)] +
+// [Entry Hash (Bytes32)] +
+// [EC Cost (1 byte)] +
+// [EC Public Key (32 bytes)] +
+// [Signature of all previous bytes (64 bytes)]
+//
+// https://github.com/FactomProject/FactomDocs/blob/master/factomDataStructureDetails.md#entry-commit
+func (e Entry) composeCommit(es *PrivateKey, newChain bool,
+	ts time.Time) ([]byte, error) {
+	cost, err := e.ECCost()
+	if err != nil {
+		return nil, err
+	}
+	commitLen := 1 + 6 + 32 + 1 + 32 + 64
+	if newChain {
+		cost += 10
+		commitLen += 32 + 32
+	}
+	data := e.MarshalBinary()
+	entryHash := EntryHash(data)
+
+	commit := make([]byte, commitLen)
+	i := 1 // Version byte 0x00
+	ms := ts.UnixNano() / 1e6
+	for j := 5; j >= 0; j-- {
+		commit[i+j] = byte(ms)
+		ms >>= 8
+	}
+	i += 6
+	if newChain {
+		chainIDHash := sha256d(e.ChainID[:])
+		i += copy(commit[i:], chainIDHash[:])
+		weld := sha256d(append(entryHash[:], e.ChainID[:]...))
+		i += copy(commit[i:], weld[:])
+	}
+	i += copy(commit[i:], entryHash[:])
+	commit[i] = byte(cost)
+	i++
+	signedLen := i
+	i += copy(commit[i:], es.PublicKey()[:])
+	sig := ed25519.Sign(es.Bytes(), commit[:signedLen])
+	copy(commit[i:], sig[:])
+	return commit, nil
+}
@@ -0,0 +1,67 @@
+package factom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FactomProject/ed25519"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeCommit(t *testing.T) {
+	var es PrivateKey
+	es[0] = 1
+	ts := time.Unix(1546300800, 123e6)
+	ms := []byte{0x01, 0x68, 0x06, 0xb5, 0xbc, 0x7b}
+
+	for _, test := range []struct {
+		Name     string
+		NewChain bool
+		Len      int
+		Cost     byte
+	}{{
+		Name: "entry", Len: 136, Cost: 1,
+	}, {
+		Name: "chain", NewChain: true, Len: 200, Cost: 11,
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+			e := Entry{ChainID: NewBytes32(nil),
+				ExtIDs: []Bytes{Bytes("test")}, Content: Bytes("test")}
+			commit, err := e.composeCommit(&es, test.NewChain, ts)
+			assert.NoError(err)
+			if !assert.Len(commit, test.Len) {
+				return
+			}
+			assert.Equal(byte(0x00), commit[0])
+			assert.Equal(ms, commit[1:7])
+			entryHash := e.ComputeHash()
+			signedLen := test.Len - 96
+			assert.Equal(entryHash[:], commit[signedLen-33:signedLen-1])
+			assert.Equal(test.Cost, commit[signedLen-1])
+
+			var pubKey [ed25519.PublicKeySize]byte
+			var sig [ed25519.SignatureSize]byte
+			copy(pubKey[:], commit[signedLen:])
+			copy(sig[:], commit[signedLen+32:])
+			assert.Equal(es.PublicKey()[:], pubKey[:])
+			assert.True(ed25519.Verify(&pubKey, commit[:signedLen], &sig))
+		})
+	}
+}
+
+func TestECCost(t *testing.T) {
+	for _, test := range []struct {
+		Size int
+		Cost int
+	}{{0, 1}, {1024, 1}, {1025, 2}, {10240, 10}, {10241, 0}} {
+		e := Entry{ChainID: NewBytes32(nil), Content: make(Bytes, test.Size)}
+		cost, err := e.ECCost()
+		if test.Cost == 0 {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, test.Cost, cost, "size: %v", test.Size)
+	}
+}
@@ -1,11 +1,26 @@
 package factom
 
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
 // DBlock represents a Factom Directory Block.
 type DBlock struct {
 	Height uint64 `json:"height"`
 
-	// DBlock.Get populates EBlocks with their ChainID and KeyMR.
+	// DBlock.Get populates the KeyMR and the EBlocks with their ChainID
+	// and KeyMR.
+	KeyMR   *Bytes32 `json:"keymr,omitempty"`
 	EBlocks []EBlock `json:"dbentries,omitempty"`
+
+	// timestamp is only populated by UnmarshalBinary, and is used to
+	// compute the Entry timestamps of the EBlocks in the DBlock.
+	timestamp time.Time
 }
 
 // IsPopulated returns true if db has already been successfully populated by a
@@ -16,21 +31,173 @@ func (db DBlock) IsPopulated() bool {
 
 // Get queries factomd for the Directory Block at db.Height.
 //
+// If FactomdQuorum is greater than 1, the DBlock is queried from every factomd
+// endpoint and Get returns an error unless at least FactomdQuorum of them
+// agree on the KeyMR computed from its raw data.
+//
 // Get returns any networking or marshaling errors, but not JSON RPC errors. To
 // check if the DBlock has been successfully populated, call IsPopulated().
-func (db *DBlock) Get() error {
+func (db *DBlock) Get(ctx context.Context) error {
 	if db.IsPopulated() {
 		return nil
 	}
+	if BlockCache != nil {
+		if err := BlockCache.getDBlock(db); err != ErrNotCached {
+			return err
+		}
+		if BlockCache.Offline {
+			return ErrNotCached
+		}
+	}
+	if err := db.get(ctx); err != nil {
+		return err
+	}
+	if BlockCache != nil && db.IsPopulated() {
+		return BlockCache.putDBlock(ctx, db)
+	}
+	return nil
+}
+
+// get queries factomd for the Directory Block at db.Height, bypassing the
+// BlockCache.
+func (db *DBlock) get(ctx context.Context) error {
+	if FactomdQuorum > 1 {
+		return db.getQuorum(ctx, factomdServers())
+	}
 
 	// We need the following anonymous struct to accomodate the way the
 	// idiosyncratic way that the JSON response is returned.
 	result := struct {
 		*DBlock `json:"dblock"`
 	}{DBlock: db}
-	if err := FactomdRequest("dblock-by-height", db, &result); err != nil {
+	if err := FactomdRequest(ctx, "dblock-by-height", db, &result); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// getQuorum queries each of the servers concurrently for the DBlock at
+// db.Height and populates db with the DBlock whose KeyMR at least
+// FactomdQuorum of them agree on. The KeyMR of each response is computed from
+// its raw data, rather than trusting the KeyMR reported by the server, so that
+// a server cannot vote for a KeyMR while returning a different body.
+func (db *DBlock) getQuorum(ctx context.Context, servers []server) error {
+	dbs := make([]DBlock, len(servers))
+	errs := make([]error, len(servers))
+	wg := &sync.WaitGroup{}
+	for i, s := range servers {
+		i, s := i, s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result struct {
+				RawData Bytes `json:"rawdata"`
+			}
+			params := DBlock{Height: db.Height}
+			if errs[i] = s.request(ctx, s.endpoint(), "dblock-by-height",
+				params, &result); errs[i] != nil ||
+				len(result.RawData) == 0 {
+				return
+			}
+			if err := dbs[i].UnmarshalBinary(result.RawData); err != nil {
+				errs[i] = fmt.Errorf("raw data: %v", err)
+				return
+			}
+			if dbs[i].Height != db.Height {
+				errs[i] = fmt.Errorf("raw data: invalid height")
+			}
+		}()
+	}
+	wg.Wait()
+
+	votes := make(map[Bytes32]int, len(servers))
+	var responses int
+	var err error
+	for i := range dbs {
+		if errs[i] != nil {
+			err = fmt.Errorf("%v: %v", servers[i].Addr, errs[i])
+			continue
+		}
+		if !dbs[i].IsPopulated() {
+			continue
+		}
+		responses++
+		keyMR := *dbs[i].KeyMR
+		votes[keyMR]++
+		if uint64(votes[keyMR]) >= FactomdQuorum {
+			*db = dbs[i]
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("DBlock %v: quorum of %v not reached: %v",
+			db.Height, FactomdQuorum, err)
+	}
+	if responses == 0 {
+		// No endpoint has the DBlock yet.
+		return nil
+	}
+	return fmt.Errorf("DBlock %v: quorum of %v not reached: "+
+		"%v endpoints returned %v distinct KeyMRs",
+		db.Height, FactomdQuorum, responses, len(votes))
+}
+
+const (
+	// Version, NetworkID, BodyMR, PrevKeyMR, PrevFullHash, Timestamp,
+	// DBHeight, BlockCount
+	dblockHeaderLen = 1 + 4 + 32 + 32 + 32 + 4 + 4 + 4
+	// ChainID, KeyMR
+	dblockEntryLen = 32 + 32
+)
+
+// UnmarshalBinary unmarshals raw DBlock data and populates the Height, KeyMR
+// and EBlocks with their ChainID and KeyMR. The KeyMR is computed from data.
+// DBlocks are encoded as follows and use big endian uint32:
+//
+// [Version (byte)] +
+// [NetworkID (uint32)] +
+// [BodyMR (Bytes32)] +
+// [PrevKeyMR (Bytes32)] +
+// [PrevFullHash (Bytes32)] +
+// [Timestamp in minutes (uint32)] +
+// [DBHeight (uint32)] +
+// [Block Count (uint32)] +
+// [ChainID 0 (Bytes32)] + [KeyMR 0 (Bytes32)] +
+// ... +
+// [ChainID X (Bytes32)] + [KeyMR X (Bytes32)]
+//
+// https://github.com/FactomProject/FactomDocs/blob/master/factomDataStructureDetails.md#directory-block
+func (db *DBlock) UnmarshalBinary(data []byte) error {
+	if len(data) < dblockHeaderLen {
+		return fmt.Errorf("insufficient length")
+	}
+	header := data[:dblockHeaderLen]
+	bodyMR := NewBytes32(header[5:37])
+	minutes := binary.BigEndian.Uint32(header[101:105])
+	height := binary.BigEndian.Uint32(header[105:109])
+	count := int(binary.BigEndian.Uint32(header[109:113]))
+	body := data[dblockHeaderLen:]
+	if len(body) != count*dblockEntryLen {
+		return fmt.Errorf("invalid block count")
+	}
+
+	ebs := make([]EBlock, count)
+	leaves := make([]Bytes32, count)
+	for i := range ebs {
+		dbe := body[i*dblockEntryLen : (i+1)*dblockEntryLen]
+		ebs[i].ChainID = NewBytes32(dbe[:32])
+		ebs[i].KeyMR = NewBytes32(dbe[32:])
+		leaves[i] = sha256.Sum256(dbe)
+	}
+	if merkleRoot(leaves) != *bodyMR {
+		return fmt.Errorf("invalid BodyMR")
+	}
+
+	keyMR := computeKeyMR(header, *bodyMR)
+	db.Height = uint64(height)
+	db.KeyMR = &keyMR
+	db.EBlocks = ebs
+	db.timestamp = time.Unix(int64(minutes)*60, 0)
+	return nil
+}
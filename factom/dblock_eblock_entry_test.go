@@ -1,6 +1,7 @@
 package factom_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+var ctx = context.Background()
+
 var courtesyNode = "courtesy-node.factom.com"
 
 func TestDataStructures(t *testing.T) {
@@ -24,13 +27,13 @@ func TestDataStructures(t *testing.T) {
 
 		// A bad URL will cause an error.
 		factom.RpcConfig.FactomdServer = "example.com"
-		assert.Error(db.Get())
+		assert.Error(db.Get(ctx))
 
 		factom.RpcConfig.FactomdServer = courtesyNode
-		require.NoError(db.Get())
+		require.NoError(db.Get(ctx))
 
 		require.True(db.IsPopulated())
-		assert.NoError(db.Get()) // Take the early exit code path.
+		assert.NoError(db.Get(ctx)) // Take the early exit code path.
 
 		// Validate this DBlock.
 		assert.Len(db.EBlocks, 7)
@@ -46,7 +49,7 @@ func TestDataStructures(t *testing.T) {
 
 		// An EBlock without a KeyMR or ChainID should cause an error.
 		blank := factom.EBlock{}
-		assert.EqualError(blank.Get(), "KeyMR and ChainID are both nil")
+		assert.EqualError(blank.Get(ctx), "KeyMR and ChainID are both nil")
 
 		// We'll use the DBlock from the last test, so it must be
 		// populated to proceed.
@@ -62,13 +65,13 @@ func TestDataStructures(t *testing.T) {
 
 		// A bad URL will cause an error.
 		factom.RpcConfig.FactomdServer = "example.com"
-		assert.Error(eb.Get())
+		assert.Error(eb.Get(ctx))
 
 		factom.RpcConfig.FactomdServer = courtesyNode
-		require.NoError(eb.Get())
+		require.NoError(eb.Get(ctx))
 
 		require.True(eb.IsPopulated())
-		assert.NoError(eb.Get()) // Take the early exit code path.
+		assert.NoError(eb.Get(ctx)) // Take the early exit code path.
 
 		// Validate the entries.
 		assert.Len(eb.Entries, 5)
@@ -85,12 +88,12 @@ func TestDataStructures(t *testing.T) {
 
 		// A bad URL will cause an error.
 		factom.RpcConfig.FactomdServer = "example.com"
-		_, err := eb.GetAllPrev()
+		_, err := eb.GetAllPrev(ctx)
 		assert.Error(err)
 
 		factom.RpcConfig.FactomdServer = courtesyNode
 		factom.RpcConfig.FactomdTimeout = 5 * time.Second
-		ebs, err := eb.GetAllPrev()
+		ebs, err := eb.GetAllPrev(ctx)
 		assert.NoError(err)
 		assert.Len(ebs, 6)
 		assert.True(ebs[0].IsFirst())
@@ -103,24 +106,24 @@ func TestDataStructures(t *testing.T) {
 		// First use an invalid ChainID and an invalid URL.
 		eb2 := factom.EBlock{ChainID: factom.NewBytes32(nil)}
 		factom.RpcConfig.FactomdServer = "example.com"
-		assert.Error(eb2.Get())
-		assert.Error(eb2.GetFirst())
+		assert.Error(eb2.Get(ctx))
+		assert.Error(eb2.GetFirst(ctx))
 
 		factom.RpcConfig.FactomdServer = courtesyNode
-		require.Error(eb2.Get())
+		require.Error(eb2.Get(ctx))
 		require.False(eb2.IsPopulated())
-		assert.EqualError(eb2.GetFirst(),
+		assert.EqualError(eb2.GetFirst(ctx),
 			`jsonrpc2.Error{Code:-32009, Message:"Missing Chain Head"}`)
-		ebs, err = eb2.GetAllPrev()
+		ebs, err = eb2.GetAllPrev(ctx)
 		assert.EqualError(err,
 			`jsonrpc2.Error{Code:-32009, Message:"Missing Chain Head"}`)
 		assert.Nil(ebs)
 
 		// A valid ChainID should allow it to be populated.
 		eb2.ChainID = eb.ChainID
-		require.NoError(eb2.Get())
+		require.NoError(eb2.Get(ctx))
 		require.True(eb2.IsPopulated())
-		assert.NoError(eb2.GetFirst())
+		assert.NoError(eb2.GetFirst(ctx))
 		assert.Equal(first.KeyMR, eb2.KeyMR)
 	})
 	t.Run("Entry", func(t *testing.T) {
@@ -129,7 +132,7 @@ func TestDataStructures(t *testing.T) {
 
 		// An EBlock without a KeyMR or ChainID should cause an error.
 		blank := factom.Entry{}
-		assert.EqualError(blank.Get(), "Hash is nil")
+		assert.EqualError(blank.Get(ctx), "Hash is nil")
 
 		// We'll use the DBlock and EBlock from the last test, so they
 		// must be populated to proceed.
@@ -143,13 +146,13 @@ func TestDataStructures(t *testing.T) {
 
 		// A bad URL will cause an error.
 		factom.RpcConfig.FactomdServer = "example.com"
-		assert.Error(e.Get())
+		assert.Error(e.Get(ctx))
 
 		factom.RpcConfig.FactomdServer = courtesyNode
-		require.NoError(e.Get())
+		require.NoError(e.Get(ctx))
 
 		require.True(e.IsPopulated())
-		assert.NoError(e.Get()) // Take the early exit code path.
+		assert.NoError(e.Get(ctx)) // Take the early exit code path.
 
 		// Validate the entry.
 		assert.Len(e.ExtIDs, 6)
@@ -159,7 +162,7 @@ func TestDataStructures(t *testing.T) {
 		assert.Equal(*e.Hash, e.ComputeHash())
 
 		e = eb.Entries[1]
-		require.NoError(e.Get())
+		require.NoError(e.Get(ctx))
 		assert.Equal(*e.Hash, e.ComputeHash())
 	})
 
@@ -1,6 +1,11 @@
 package factom
 
-import "fmt"
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
 
 // EBlock represents an Factom Entry Block.
 type EBlock struct {
@@ -34,7 +39,7 @@ func (eb EBlock) IsPopulated() bool {
 //
 // Get returns any networking or marshaling errors, but not JSON RPC errors. To
 // check if the EBlock has been successfully populated, call IsPopulated().
-func (eb *EBlock) Get() error {
+func (eb *EBlock) Get(ctx context.Context) error {
 	// If the EBlock is already populated then there is nothing to do.
 	if eb.IsPopulated() {
 		return nil
@@ -46,7 +51,7 @@ func (eb *EBlock) Get() error {
 
 	// If we don't have a KeyMR, fetch the chain head's KeyMR.
 	if eb.KeyMR == nil {
-		if err := eb.GetChainHead(); err != nil {
+		if err := eb.GetChainHead(ctx); err != nil {
 			return err
 		}
 		// If we don't get a KeyMR back for the chain head then we just
@@ -57,10 +62,19 @@ func (eb *EBlock) Get() error {
 		}
 	}
 
+	if BlockCache != nil {
+		if err := BlockCache.getEBlock(eb); err != ErrNotCached {
+			return err
+		}
+		if BlockCache.Offline {
+			return ErrNotCached
+		}
+	}
+
 	// Make RPC request for this Entry Block.
 	params := map[string]interface{}{"keymr": eb.KeyMR}
 	method := "entry-block"
-	if err := FactomdRequest(method, params, eb); err != nil {
+	if err := FactomdRequest(ctx, method, params, eb); err != nil {
 		return err
 	}
 
@@ -69,19 +83,34 @@ func (eb *EBlock) Get() error {
 		eb.Entries[i].ChainID = eb.ChainID
 		eb.Entries[i].Height = eb.Height
 	}
+
+	if BlockCache != nil && eb.IsPopulated() {
+		return BlockCache.putEBlock(ctx, eb)
+	}
 	return nil
 }
 
-func (eb *EBlock) GetChainHead() error {
+// GetChainHead queries factomd for the KeyMR of the latest Entry Block in
+// eb.ChainID. If the chain does not exist then eb.KeyMR is set to nil.
+//
+// If the BlockCache is Offline, the chain head recorded by the last online
+// call is used.
+func (eb *EBlock) GetChainHead(ctx context.Context) error {
+	if BlockCache != nil && BlockCache.Offline {
+		return BlockCache.getChainHead(eb)
+	}
 	params := eb
 	method := "chain-head"
 	result := struct {
 		KeyMR *Bytes32 `json:"chainhead"`
 	}{}
-	if err := FactomdRequest(method, params, &result); err != nil {
+	if err := FactomdRequest(ctx, method, params, &result); err != nil {
 		return err
 	}
 	eb.KeyMR = result.KeyMR
+	if BlockCache != nil {
+		return BlockCache.putChainHead(eb)
+	}
 	return nil
 }
 
@@ -116,10 +145,10 @@ func (eb EBlock) Prev() EBlock {
 // JSON RPC errors. However, failing to populate any EBlock in the chain will
 // result in returning a nil slice, thus it is unneccessary to call IsPopulated
 // on any of the EBlocks in the returned slice.
-func (eb EBlock) GetAllPrev() ([]EBlock, error) {
+func (eb EBlock) GetAllPrev(ctx context.Context) ([]EBlock, error) {
 	ebs := []EBlock{eb}
 	for ; !ebs[0].IsFirst(); ebs = append([]EBlock{ebs[0].Prev()}, ebs...) {
-		if err := ebs[0].Get(); err != nil {
+		if err := ebs[0].Get(ctx); err != nil {
 			return nil, err
 		}
 		if !ebs[0].IsPopulated() {
@@ -139,9 +168,9 @@ func (eb EBlock) GetAllPrev() ([]EBlock, error) {
 // Like Get, GetFirst returns any networking or marshaling errors, but not JSON
 // RPC errors. To check if the EBlock has been successfully populated, call
 // IsPopulated().
-func (eb *EBlock) GetFirst() error {
+func (eb *EBlock) GetFirst(ctx context.Context) error {
 	for ; !eb.IsFirst(); *eb = eb.Prev() {
-		if err := eb.Get(); err != nil {
+		if err := eb.Get(ctx); err != nil {
 			return err
 		}
 		if !eb.IsPopulated() {
@@ -150,3 +179,94 @@ func (eb *EBlock) GetFirst() error {
 	}
 	return nil
 }
+
+const (
+	// ChainID, BodyMR, PrevKeyMR, PrevFullHash, EBSequence, DBHeight,
+	// EntryCount
+	eblockHeaderLen = 32 + 32 + 32 + 32 + 4 + 4 + 4
+)
+
+// UnmarshalBinary unmarshals raw EBlock data and populates the ChainID, KeyMR,
+// PrevKeyMR, Height and the Entries with their Hash, ChainID and Height. The
+// KeyMR is computed from data. The Entry Timestamps are not populated because
+// they depend on the timestamp of the DBlock. EBlocks are encoded as follows
+// and use big endian uint32:
+//
+// [ChainID (Bytes32)] +
+// [BodyMR (Bytes32)] +
+// [PrevKeyMR (Bytes32)] +
+// [PrevFullHash (Bytes32)] +
+// [EBSequence (uint32)] +
+// [DBHeight (uint32)] +
+// [Entry Count (uint32)] +
+// [Entry Hash or Minute Marker 0 (Bytes32)] +
+// ... +
+// [Entry Hash or Minute Marker X (Bytes32)]
+//
+// https://github.com/FactomProject/FactomDocs/blob/master/factomDataStructureDetails.md#entry-block
+func (eb *EBlock) UnmarshalBinary(data []byte) error {
+	return eb.unmarshalBinary(data, time.Time{})
+}
+
+// unmarshalBinary is like UnmarshalBinary but also populates the Entry
+// Timestamps if dbTimestamp is not zero. Each Entry is timestamped with the
+// minute of the minute marker that follows it.
+func (eb *EBlock) unmarshalBinary(data []byte, dbTimestamp time.Time) error {
+	if len(data) < eblockHeaderLen {
+		return fmt.Errorf("insufficient length")
+	}
+	header := data[:eblockHeaderLen]
+	bodyMR := NewBytes32(header[32:64])
+	height := binary.BigEndian.Uint32(header[132:136])
+	count := int(binary.BigEndian.Uint32(header[136:140]))
+	body := data[eblockHeaderLen:]
+	if len(body) != count*len(Bytes32{}) {
+		return fmt.Errorf("invalid entry count")
+	}
+
+	chainID := NewBytes32(header[:32])
+	items := make([]Bytes32, count)
+	var es []Entry
+	var minuteStart int
+	for i := range items {
+		copy(items[i][:], body[i*32:(i+1)*32])
+		if minute, ok := minuteMarker(items[i]); ok {
+			if !dbTimestamp.IsZero() {
+				ts := &Time{Time: dbTimestamp.Add(
+					time.Duration(minute) * time.Minute)}
+				for j := range es[minuteStart:] {
+					es[minuteStart+j].Timestamp = ts
+				}
+			}
+			minuteStart = len(es)
+			continue
+		}
+		es = append(es, Entry{Hash: NewBytes32(items[i][:]),
+			ChainID: chainID, Height: uint64(height)})
+	}
+	if minuteStart != len(es) {
+		return fmt.Errorf("missing final minute marker")
+	}
+	if merkleRoot(items) != *bodyMR {
+		return fmt.Errorf("invalid BodyMR")
+	}
+
+	keyMR := computeKeyMR(header, *bodyMR)
+	eb.ChainID = chainID
+	eb.KeyMR = &keyMR
+	eb.PrevKeyMR = NewBytes32(header[64:96])
+	eb.Height = uint64(height)
+	eb.Entries = es
+	return nil
+}
+
+// minuteMarker returns the minute of item and true if item is a minute marker:
+// 31 zero bytes followed by the minute, from 1 to 10.
+func minuteMarker(item Bytes32) (uint8, bool) {
+	minute := item[31]
+	item[31] = 0
+	if item != zeroBytes32 || minute == 0 || minute > 10 {
+		return 0, false
+	}
+	return minute, true
+}
@@ -1,6 +1,7 @@
 package factom
 
 import (
+	"context"
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/json"
@@ -49,7 +50,7 @@ func (e *Entry) SetTimestampToNow() {
 //
 // Get returns any networking or marshaling errors, but not JSON RPC errors. To
 // check if the Entry has been successfully populated, call IsPopulated().
-func (e *Entry) Get() error {
+func (e *Entry) Get(ctx context.Context) error {
 	// If the Hash is nil then we have nothing to query for.
 	if e.Hash == nil {
 		return fmt.Errorf("Hash is nil")
@@ -59,16 +60,40 @@ func (e *Entry) Get() error {
 	if e.IsPopulated() {
 		return nil
 	}
+	if BlockCache != nil {
+		if err := BlockCache.getEntry(e); err != ErrNotCached {
+			return err
+		}
+		if BlockCache.Offline {
+			return ErrNotCached
+		}
+	}
+	data, err := getRawData(ctx, e.Hash)
+	if err != nil {
+		return err
+	}
+	if err := e.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	if BlockCache != nil && e.IsPopulated() {
+		return BlockCache.putEntry(e, data)
+	}
+	return nil
+}
+
+// getRawData queries factomd for the raw binary data of the Entry, EBlock or
+// DBlock with the given hash or KeyMR.
+func getRawData(ctx context.Context, hash *Bytes32) (Bytes, error) {
 	params := struct {
 		Hash *Bytes32 `json:"hash"`
-	}{Hash: e.Hash}
+	}{Hash: hash}
 	var result struct {
 		Data Bytes `json:"data"`
 	}
-	if err := FactomdRequest("raw-data", params, &result); err != nil {
-		return err
+	if err := FactomdRequest(ctx, "raw-data", params, &result); err != nil {
+		return nil, err
 	}
-	return e.UnmarshalBinary(result.Data)
+	return result.Data, nil
 }
 
 type chainFirstEntryParams struct {
@@ -95,7 +120,7 @@ type commitResult struct {
 	TxID *Bytes32
 }
 
-func (e *Entry) Create(ecpub string) (*Bytes32, error) {
+func (e *Entry) Create(ctx context.Context, ecpub string) (*Bytes32, error) {
 	var params interface{}
 	var method string
 	if e.ChainID == nil {
@@ -112,7 +137,7 @@ func (e *Entry) Create(ecpub string) (*Bytes32, error) {
 		}
 	}
 	result := composeResult{}
-	if err := WalletRequest(method, params, &result); err != nil {
+	if err := WalletRequest(ctx, method, params, &result); err != nil {
 		return nil, err
 	}
 	if len(result.Commit.Method) == 0 {
@@ -120,11 +145,11 @@ func (e *Entry) Create(ecpub string) (*Bytes32, error) {
 	}
 
 	var commit commitResult
-	if err := FactomdRequest(result.Commit.Method, result.Commit.Params,
+	if err := FactomdRequest(ctx, result.Commit.Method, result.Commit.Params,
 		&commit); err != nil {
 		return nil, err
 	}
-	if err := FactomdRequest(result.Reveal.Method, result.Reveal.Params,
+	if err := FactomdRequest(ctx, result.Reveal.Method, result.Reveal.Params,
 		e); err != nil {
 		return nil, err
 	}
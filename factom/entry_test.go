@@ -19,7 +19,7 @@ var marshalBinaryTests = []struct {
 		RpcConfig.FactomdServer = courtesyNode
 		e := Entry{Hash: NewBytes32(hexToBytes(
 			"935e8442a554383e50b02938420d16ef9fcc07d0a0ac03d191bd4275ddd98dee"))}
-		if err := e.Get(); err != nil {
+		if err := e.Get(ctx); err != nil {
 			panic(err)
 		}
 		if !e.IsPopulated() {
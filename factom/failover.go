@@ -0,0 +1,257 @@
+package factom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+)
+
+var (
+	// FactomdQuorum is the number of factomd endpoints that must agree on
+	// the KeyMR of a DBlock for DBlock.Get to succeed. Values less than 2
+	// disable the cross-check.
+	FactomdQuorum uint64
+
+	// HealthCheckInterval is how often the health of the factomd
+	// endpoints is rechecked when more than one is configured.
+	HealthCheckInterval = 30 * time.Second
+
+	// RetryDelay is the delay before retrying a request on the next
+	// factomd endpoint. It doubles after each subsequent retry.
+	RetryDelay = 250 * time.Millisecond
+)
+
+// healthCheckTimeout bounds how long a health check waits for the slowest
+// endpoint. An endpoint that does not respond in time is unhealthy.
+const healthCheckTimeout = 5 * time.Second
+
+// syncedTolerance is the number of blocks that an endpoint may be behind the
+// highest endpoint and still be considered synced.
+const syncedTolerance = 1
+
+// httpError is returned for unexpected HTTP response statuses.
+type httpError struct {
+	StatusCode int
+	Status     string
+}
+
+func (err httpError) Error() string {
+	return fmt.Sprintf("http: %v", err.Status)
+}
+
+// readError is returned if the response body could not be read.
+type readError struct {
+	Err error
+}
+
+func (err readError) Error() string {
+	return fmt.Sprintf("ioutil.ReadAll(http.Response.Body): %v", err.Err)
+}
+
+// IsNetworkError returns true if err occurred while sending a request or
+// reading its response, or if the server responded with an HTTP 5xx or 429
+// status. Such errors are not caused by the request or the data returned and
+// so the request may succeed later.
+func IsNetworkError(err error) bool {
+	switch err := err.(type) {
+	case *url.Error, readError:
+		return true
+	case httpError:
+		return err.StatusCode >= 500 ||
+			err.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// transient returns true if a request that failed with err may succeed on
+// another endpoint.
+func transient(err error) bool {
+	switch err.(type) {
+	case nil, jrpc.Error, httpError:
+		return IsNetworkError(err)
+	}
+	// Any other error occurred while sending the request or reading the
+	// response, or the response was malformed.
+	return true
+}
+
+// factomdServers returns a server for each of the comma separated addresses in
+// RpcConfig.FactomdServer. All servers share the same TLS settings,
+// credentials and timeout.
+func factomdServers() []server {
+	addrs := strings.Split(RpcConfig.FactomdServer, ",")
+	servers := make([]server, 0, len(addrs))
+	for _, addr := range addrs {
+		s := factomdServer()
+		s.Addr = strings.TrimSpace(addr)
+		if len(s.Addr) == 0 && len(addrs) > 1 {
+			continue
+		}
+		servers = append(servers, s)
+	}
+	return servers
+}
+
+// health is the result of the last health check of an endpoint.
+type health struct {
+	Height  uint64
+	Latency time.Duration
+	Err     error
+}
+
+// healths holds the health of each endpoint by address. Only one health check
+// runs at a time, while checking is true.
+var healths = struct {
+	sync.Mutex
+	m        map[string]health
+	checked  time.Time
+	checking bool
+}{m: make(map[string]health)}
+
+// checkHealth queries the heights of each of the servers concurrently and
+// records their height and latency. The caller must have set healths.checking.
+func checkHealth(ctx context.Context, servers []server) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	results := make([]health, len(servers))
+	wg := &sync.WaitGroup{}
+	for i, s := range servers {
+		i, s := i, s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var heights Heights
+			start := time.Now()
+			err := s.request(ctx, s.endpoint(), "heights", nil,
+				&heights)
+			results[i] = health{
+				Height:  uint64(heights.DirectoryBlockHeight),
+				Latency: time.Since(start),
+				Err:     err,
+			}
+		}()
+	}
+	wg.Wait()
+
+	healths.Lock()
+	defer healths.Unlock()
+	for i, s := range servers {
+		healths.m[s.Addr] = results[i]
+	}
+	healths.checked = time.Now()
+	healths.checking = false
+}
+
+// markUnhealthy records that a request to addr failed with err so that it is
+// ranked last until the next health check.
+func markUnhealthy(addr string, err error) {
+	healths.Lock()
+	defer healths.Unlock()
+	h := healths.m[addr]
+	h.Err = err
+	healths.m[addr] = h
+}
+
+// rankServers returns servers ordered from best to worst by the results of the
+// last health check. Healthy servers within syncedTolerance blocks of the
+// highest server are ranked first by latency, followed by the remaining
+// healthy servers by height, and then the unhealthy servers.
+//
+// If the results are older than HealthCheckInterval, the health is rechecked
+// in the background so that the request is not delayed. Only the first check
+// is waited for, since until then there are no results to rank by.
+func rankServers(ctx context.Context, servers []server) []server {
+	if len(servers) < 2 {
+		return servers
+	}
+	healths.Lock()
+	first := healths.checked.IsZero()
+	stale := !healths.checking &&
+		time.Since(healths.checked) > HealthCheckInterval
+	if stale {
+		healths.checking = true
+	}
+	healths.Unlock()
+	if stale {
+		if first {
+			checkHealth(ctx, servers)
+		} else {
+			go checkHealth(context.Background(), servers)
+		}
+	}
+
+	healths.Lock()
+	hs := make(map[string]health, len(servers))
+	var maxHeight uint64
+	for _, s := range servers {
+		h, ok := healths.m[s.Addr]
+		if !ok {
+			// A server that has not been checked yet, perhaps
+			// because the config changed, is tried last.
+			h.Err = fmt.Errorf("not checked")
+		}
+		hs[s.Addr] = h
+		if h.Err == nil && h.Height > maxHeight {
+			maxHeight = h.Height
+		}
+	}
+	healths.Unlock()
+
+	synced := func(h health) bool {
+		return h.Err == nil && h.Height+syncedTolerance >= maxHeight
+	}
+	ranked := append([]server{}, servers...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		hi, hj := hs[ranked[i].Addr], hs[ranked[j].Addr]
+		if synced(hi) != synced(hj) {
+			return synced(hi)
+		}
+		if synced(hi) {
+			return hi.Latency < hj.Latency
+		}
+		if (hi.Err == nil) != (hj.Err == nil) {
+			return hi.Err == nil
+		}
+		return hi.Height > hj.Height
+	})
+	return ranked
+}
+
+// failoverRequest makes the request on the best of the servers. If it fails
+// due to a network error or an HTTP 5xx or 429 response, the server is marked
+// unhealthy and the request is retried on the next best server after a delay
+// that begins at RetryDelay and doubles after each retry. The error from the
+// last server tried is returned, or ctx.Err() if ctx is done first.
+func failoverRequest(ctx context.Context, servers []server, method string,
+	params, result interface{}) error {
+	delay := RetryDelay
+	var err error
+	for i, s := range rankServers(ctx, servers) {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		err = s.request(ctx, s.endpoint(), method, params, result)
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !transient(err) {
+			return err
+		}
+		if len(servers) > 1 {
+			markUnhealthy(s.Addr, err)
+		}
+	}
+	return err
+}
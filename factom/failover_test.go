@@ -0,0 +1,212 @@
+package factom
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFactomd is a fake factomd that serves the heights and dblock-by-height
+// methods. The DBlock body has a single EBlock with the given KeyMR, but the
+// DBlock KeyMR reported in the JSON is always Bytes32{1}.
+type testFactomd struct {
+	*httptest.Server
+	Height   int64
+	KeyMR    Bytes32
+	Down     bool
+	Delay    time.Duration
+	Requests int32
+}
+
+func newTestFactomd(height int64, keyMR byte) *testFactomd {
+	f := &testFactomd{Height: height, KeyMR: Bytes32{keyMR}}
+	methods := jrpc.MethodMap{
+		"heights": func(_ json.RawMessage) interface{} {
+			return Heights{DirectoryBlockHeight: f.Height}
+		},
+		"dblock-by-height": func(_ json.RawMessage) interface{} {
+			ebs := []EBlock{{ChainID: &Bytes32{}, KeyMR: &f.KeyMR}}
+			return map[string]interface{}{
+				"dblock": DBlock{Height: uint64(f.Height),
+					KeyMR: &Bytes32{1}, EBlocks: ebs},
+				"rawdata": Bytes(marshalDBlock(
+					uint32(f.Height), 0, ebs)),
+			}
+		},
+	}
+	handler := jrpc.HTTPRequestHandler(methods)
+	f.Server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&f.Requests, 1)
+			time.Sleep(f.Delay)
+			if f.Down {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			handler(w, r)
+		}))
+	return f
+}
+
+func (f *testFactomd) Addr() string {
+	return strings.TrimPrefix(f.URL, "http://")
+}
+
+// setFactomdServers sets RpcConfig.FactomdServer to the addresses of fs and
+// resets the health checks. The returned func restores RpcConfig.
+func setFactomdServers(fs ...*testFactomd) func() {
+	cfg, quorum, delay := *RpcConfig, FactomdQuorum, RetryDelay
+	addrs := make([]string, len(fs))
+	for i, f := range fs {
+		addrs[i] = f.Addr()
+	}
+	RpcConfig.FactomdServer = strings.Join(addrs, ", ")
+	RpcConfig.FactomdTLSEnable = false
+	RetryDelay = time.Millisecond
+	healths.Lock()
+	healths.checked = time.Time{}
+	healths.checking = false
+	healths.Unlock()
+	return func() {
+		*RpcConfig, FactomdQuorum, RetryDelay = cfg, quorum, delay
+	}
+}
+
+func TestRankServers(t *testing.T) {
+	behind, down, fast := newTestFactomd(8, 1), newTestFactomd(10, 1),
+		newTestFactomd(10, 1)
+	defer behind.Close()
+	defer down.Close()
+	defer fast.Close()
+	down.Down = true
+	defer setFactomdServers(down, behind, fast)()
+
+	ranked := rankServers(ctx, factomdServers())
+	require.Len(t, ranked, 3)
+	assert.Equal(t, fast.Addr(), ranked[0].Addr)
+	assert.Equal(t, behind.Addr(), ranked[1].Addr)
+	assert.Equal(t, down.Addr(), ranked[2].Addr)
+}
+
+func TestRankServersStale(t *testing.T) {
+	a, b := newTestFactomd(10, 1), newTestFactomd(10, 1)
+	defer a.Close()
+	defer b.Close()
+	defer setFactomdServers(a, b)()
+	assert := assert.New(t)
+
+	servers := factomdServers()
+	ranked := rankServers(ctx, servers)
+	best, other := a, b
+	if ranked[0].Addr != a.Addr() {
+		best, other = b, a
+	}
+
+	// The best endpoint becomes slow and then fails, but the stale
+	// results are used while the health is rechecked in the background.
+	best.Delay, best.Down = 200*time.Millisecond, true
+	healths.Lock()
+	healths.checked = time.Now().Add(-2 * HealthCheckInterval)
+	healths.Unlock()
+	requests := atomic.LoadInt32(&other.Requests)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		ranked = rankServers(ctx, servers)
+		assert.Equal(best.Addr(), ranked[0].Addr)
+	}
+	assert.True(time.Since(start) < best.Delay)
+
+	for {
+		healths.Lock()
+		checking := healths.checking
+		healths.Unlock()
+		if !checking {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Only one health check ran.
+	assert.Equal(requests+1, atomic.LoadInt32(&other.Requests))
+	ranked = rankServers(ctx, servers)
+	assert.Equal(other.Addr(), ranked[0].Addr)
+}
+
+func TestFailover(t *testing.T) {
+	a, b := newTestFactomd(10, 1), newTestFactomd(10, 1)
+	defer a.Close()
+	defer b.Close()
+	defer setFactomdServers(a, b)()
+	assert := assert.New(t)
+
+	heights, err := GetHeights(ctx)
+	require.NoError(t, err)
+	assert.Equal(int64(10), heights.DirectoryBlockHeight)
+
+	// Take down each endpoint in turn without rechecking health.
+	for _, f := range []*testFactomd{a, b} {
+		a.Down, b.Down = false, false
+		f.Down = true
+		_, err := GetHeights(ctx)
+		assert.NoError(err)
+	}
+
+	a.Down, b.Down = true, true
+	_, err = GetHeights(ctx)
+	assert.EqualError(err, "http: 503 Service Unavailable")
+
+	// JSON RPC errors are not retried on another endpoint.
+	a.Down, b.Down = false, false
+	atomic.StoreInt32(&a.Requests, 0)
+	atomic.StoreInt32(&b.Requests, 0)
+	err = FactomdRequest(ctx, "unknown-method", nil, nil)
+	assert.IsType(jrpc.Error{}, err)
+	assert.Equal(int32(1), atomic.LoadInt32(&a.Requests)+
+		atomic.LoadInt32(&b.Requests))
+}
+
+func TestDBlockQuorum(t *testing.T) {
+	a, b, c := newTestFactomd(10, 1), newTestFactomd(10, 1),
+		newTestFactomd(10, 2)
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+	defer setFactomdServers(a, b, c)()
+	assert := assert.New(t)
+
+	FactomdQuorum = 2
+	db := DBlock{Height: 10}
+	require.NoError(t, db.Get(ctx))
+	assert.True(db.IsPopulated())
+	assert.Equal(Bytes32{1}, *db.EBlocks[0].KeyMR)
+	raw := marshalDBlock(10, 0, db.EBlocks)
+	assert.Equal(computeKeyMR(raw[:dblockHeaderLen],
+		*NewBytes32(raw[5:37])), *db.KeyMR)
+
+	// c reports the same KeyMR as a and b for a different body.
+	FactomdQuorum = 3
+	db = DBlock{Height: 10}
+	assert.EqualError(db.Get(ctx), "DBlock 10: quorum of 3 not reached: "+
+		"3 endpoints returned 2 distinct KeyMRs")
+	assert.False(db.IsPopulated())
+
+	c.KeyMR = Bytes32{1}
+	c.Down = true
+	db = DBlock{Height: 10}
+	err := db.Get(ctx)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "503 Service Unavailable")
+	}
+
+	c.Down = false
+	db = DBlock{Height: 10}
+	assert.NoError(db.Get(ctx))
+	assert.True(db.IsPopulated())
+}
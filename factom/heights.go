@@ -0,0 +1,20 @@
+package factom
+
+import "context"
+
+// Heights are the block heights reported by factomd.
+type Heights struct {
+	DirectoryBlockHeight int64 `json:"directoryblockheight"`
+	LeaderHeight         int64 `json:"leaderheight"`
+	EntryBlockHeight     int64 `json:"entryblockheight"`
+	EntryHeight          int64 `json:"entryheight"`
+}
+
+// GetHeights queries factomd for the current Heights.
+func GetHeights(ctx context.Context) (*Heights, error) {
+	heights := new(Heights)
+	if err := FactomdRequest(ctx, "heights", nil, heights); err != nil {
+		return nil, err
+	}
+	return heights, nil
+}
@@ -4,8 +4,5 @@ import (
 	"github.com/AdamSLevy/factom"
 )
 
-// GetHeights returns a struct of Factom Blockchain Heights.
-var GetHeights = factom.GetHeights
-
 // RpcConfig is a pointer to the RPC settings.
 var RpcConfig = factom.RpcConfig
@@ -0,0 +1,32 @@
+package factom
+
+import "crypto/sha256"
+
+// merkleRoot returns the Merkle root of hashes as computed by factomd. Each
+// level hashes adjacent pairs of nodes, pairing the last node with itself if
+// the level has an odd number of nodes. The root of a single hash is the hash
+// itself. The root of no hashes is all zeroes.
+func merkleRoot(hashes []Bytes32) Bytes32 {
+	if len(hashes) == 0 {
+		return Bytes32{}
+	}
+	for len(hashes) > 1 {
+		next := make([]Bytes32, (len(hashes)+1)/2)
+		for i := range next {
+			left, right := hashes[2*i], hashes[2*i]
+			if 2*i+1 < len(hashes) {
+				right = hashes[2*i+1]
+			}
+			next[i] = sha256.Sum256(append(left[:], right[:]...))
+		}
+		hashes = next
+	}
+	return hashes[0]
+}
+
+// computeKeyMR returns the KeyMR of a DBlock or EBlock with the given binary
+// header and BodyMR: sha256(sha256(header) + BodyMR).
+func computeKeyMR(header []byte, bodyMR Bytes32) Bytes32 {
+	headerHash := sha256.Sum256(header)
+	return sha256.Sum256(append(headerHash[:], bodyMR[:]...))
+}
@@ -2,24 +2,146 @@ package factom
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
 )
 
 var DebugRPC bool
 
-// request makes a JSON RPC request with the given method and params, and then
-// parses the response with the given result type. request only returns
-// networking and unmarshaling errors. JSON RPC Errors are not returned. It is
-// up to the caller to determine whether their result is properly populated
-// after request returns. Since data will need to be marshaled into result, the
+// server holds the connection settings for a factomd or factom-walletd API.
+type server struct {
+	Addr      string
+	TLSEnable bool
+	// TLSCertFile is the PEM encoded certificate of the server, or of the
+	// CA that issued it. If set, it is the only certificate trusted for
+	// the server. Otherwise the system's root CAs are used.
+	TLSCertFile string
+	User        string
+	Password    string
+	Timeout     time.Duration
+}
+
+func factomdServer() server {
+	return server{
+		Addr:        RpcConfig.FactomdServer,
+		TLSEnable:   RpcConfig.FactomdTLSEnable,
+		TLSCertFile: RpcConfig.FactomdTLSCertFile,
+		User:        RpcConfig.FactomdRPCUser,
+		Password:    RpcConfig.FactomdRPCPassword,
+		Timeout:     RpcConfig.FactomdTimeout,
+	}
+}
+
+func walletServer() server {
+	return server{
+		Addr:        RpcConfig.WalletServer,
+		TLSEnable:   RpcConfig.WalletTLSEnable,
+		TLSCertFile: RpcConfig.WalletTLSCertFile,
+		User:        RpcConfig.WalletRPCUser,
+		Password:    RpcConfig.WalletRPCPassword,
+		Timeout:     RpcConfig.WalletTimeout,
+	}
+}
+
+// endpoint returns the URL of the v2 API of s.
+func (s server) endpoint() string {
+	scheme := "http://"
+	if s.TLSEnable {
+		scheme = "https://"
+	}
+	return scheme + s.Addr + "/v2"
+}
+
+// clientKey identifies the settings that an http.Client depends on.
+type clientKey struct {
+	TLSCertFile string
+	Timeout     time.Duration
+}
+
+// clients caches an http.Client for each clientKey so that connections are
+// kept alive and reused across requests.
+var clients = struct {
+	sync.Mutex
+	m map[clientKey]*http.Client
+}{m: make(map[clientKey]*http.Client)}
+
+// httpClient returns the shared http.Client for the TLSCertFile and Timeout
+// of s.
+func (s server) httpClient() (*http.Client, error) {
+	key := clientKey{TLSCertFile: s.TLSCertFile, Timeout: s.Timeout}
+	clients.Lock()
+	defer clients.Unlock()
+	if c, ok := clients.m[key]; ok {
+		return c, nil
+	}
+	tlsConfig, err := newTLSConfig(s.TLSCertFile)
+	if err != nil {
+		return nil, err
+	}
+	c := &http.Client{Timeout: s.Timeout, Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}}
+	clients.m[key] = c
+	return c, nil
+}
+
+// newTLSConfig returns a tls.Config that trusts only the PEM encoded
+// certificates in certFile. If certFile is empty, nil is returned so that the
+// system's root CAs are used.
+func newTLSConfig(certFile string) (*tls.Config, error) {
+	if len(certFile) == 0 {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%v: no PEM encoded certificates found",
+			certFile)
+	}
+	return &tls.Config{RootCAs: roots}, nil
+}
+
+// Request makes a JSON RPC request with the given method and params to
+// endpoint, and then parses the response with the given result type. No
+// credentials are sent and the factomd timeout is used. Use FactomdRequest or
+// WalletRequest to use all of the settings in RpcConfig.
+//
+// Request only returns networking and unmarshaling errors, and any JSON RPC
+// Error as a jrpc.Error. Since data will need to be marshaled into result, the
 // result type should be passed as a pointer.
-func Request(endpoint, method string, params, result interface{}) error {
+func Request(ctx context.Context, endpoint, method string,
+	params, result interface{}) error {
+	return server{Timeout: RpcConfig.FactomdTimeout}.
+		request(ctx, endpoint, method, params, result)
+}
+
+// request makes a JSON RPC request to endpoint using the credentials, TLS
+// settings and timeout of s.
+func (s server) request(ctx context.Context, endpoint, method string,
+	params, result interface{}) error {
 	// Generate a random ID for this request.
 	id := rand.Uint32()%200 + 500
 
@@ -38,20 +160,28 @@ func Request(endpoint, method string, params, result interface{}) error {
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(ctx)
 	req.Header.Add("Content-Type", "application/json")
-	c := http.Client{Timeout: RpcConfig.FactomdTimeout}
+	if len(s.User) > 0 {
+		req.SetBasicAuth(s.User, s.Password)
+	}
+	c, err := s.httpClient()
+	if err != nil {
+		return err
+	}
 	res, err := c.Do(req)
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusBadRequest {
-		return fmt.Errorf("http: %v", res.Status)
+		return httpError{StatusCode: res.StatusCode, Status: res.Status}
 	}
 
 	// Read the HTTP response.
 	resBytes, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return fmt.Errorf("ioutil.ReadAll(http.Response.Body): %v", err)
+		return readError{err}
 	}
 
 	// Unmarshal the HTTP response into a JSON RPC response.
@@ -69,11 +199,19 @@ func Request(endpoint, method string, params, result interface{}) error {
 	return nil
 }
 
-func FactomdRequest(method string, params, result interface{}) error {
-	endpoint := "http://" + RpcConfig.FactomdServer + "/v2"
-	return Request(endpoint, method, params, result)
+// FactomdRequest makes a request to the factomd API using the settings in
+// RpcConfig. If RpcConfig.FactomdServer is a comma separated list of
+// addresses, the request is made on the healthiest endpoint and fails over to
+// the others.
+func FactomdRequest(ctx context.Context, method string,
+	params, result interface{}) error {
+	return failoverRequest(ctx, factomdServers(), method, params, result)
 }
-func WalletRequest(method string, params, result interface{}) error {
-	endpoint := "http://" + RpcConfig.WalletServer + "/v2"
-	return Request(endpoint, method, params, result)
+
+// WalletRequest makes a request to the factom-walletd API using the settings
+// in RpcConfig.
+func WalletRequest(ctx context.Context, method string,
+	params, result interface{}) error {
+	s := walletServer()
+	return s.request(ctx, s.endpoint(), method, params, result)
 }
@@ -1,13 +1,24 @@
 package factom
 
 import (
+	"context"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+var ctx = context.Background()
+
 type badParams int
 
 func (b badParams) MarshalJSON() ([]byte, error) {
@@ -21,22 +32,22 @@ func (b *badParams) UnmarshalJSON(_ []byte) error {
 func TestRequest(t *testing.T) {
 	var b badParams
 	assert := assert.New(t)
-	assert.EqualError(WalletRequest("test", &b, nil),
+	assert.EqualError(WalletRequest(ctx, "test", &b, nil),
 		"json: error calling MarshalJSON for type jsonrpc2.Request: json: error calling MarshalJSON for type *factom.badParams: bad params")
 
-	assert.EqualError(FactomdRequest("test", &b, nil),
+	assert.EqualError(FactomdRequest(ctx, "test", &b, nil),
 		"json: error calling MarshalJSON for type jsonrpc2.Request: json: error calling MarshalJSON for type *factom.badParams: bad params")
 
 	RpcConfig.FactomdServer = "@#$%^"
-	assert.EqualError(FactomdRequest("test", nil, nil),
+	assert.EqualError(FactomdRequest(ctx, "test", nil, nil),
 		`parse http://@#$%^/v2: invalid URL escape "%^/"`)
 
 	RpcConfig.FactomdServer = "localhost"
-	assert.EqualError(FactomdRequest("test", nil, nil),
+	assert.EqualError(FactomdRequest(ctx, "test", nil, nil),
 		"Post http://localhost/v2: dial tcp [::1]:80: connect: connection refused")
 
 	RpcConfig.FactomdServer = "example.com/404please"
-	assert.EqualError(FactomdRequest("test", nil, nil), "http: 404 Not Found")
+	assert.EqualError(FactomdRequest(ctx, "test", nil, nil), "http: 404 Not Found")
 
 	badServeURL := "localhost:10000"
 	go http.ListenAndServe(badServeURL, http.HandlerFunc(
@@ -44,14 +55,14 @@ func TestRequest(t *testing.T) {
 			w.Header().Set("Content-Length", "1")
 		}))
 	RpcConfig.FactomdServer = badServeURL
-	assert.EqualError(FactomdRequest("properties", nil, &b),
+	assert.EqualError(FactomdRequest(ctx, "properties", nil, &b),
 		"ioutil.ReadAll(http.Response.Body): unexpected EOF")
 
 	RpcConfig.FactomdServer = "courtesy-node.factom.com"
-	assert.EqualError(FactomdRequest("properties", nil, &b), "json.Unmarshal({\"jsonrpc\":\"2.0\",\"id\":580,\"result\":{\"factomdversion\":\"6.0.0\",\"factomdapiversion\":\"2.0\"}}): bad params")
+	assert.EqualError(FactomdRequest(ctx, "properties", nil, &b), "json.Unmarshal({\"jsonrpc\":\"2.0\",\"id\":580,\"result\":{\"factomdversion\":\"6.0.0\",\"factomdapiversion\":\"2.0\"}}): bad params")
 
 	var result map[string]string
-	assert.NoError(FactomdRequest("properties", nil, &result))
+	assert.NoError(FactomdRequest(ctx, "properties", nil, &result))
 	version, ok := result["factomdversion"]
 	assert.True(ok)
 	assert.NotEmpty(version, "factomd version")
@@ -59,3 +70,88 @@ func TestRequest(t *testing.T) {
 	assert.True(ok)
 	assert.Equal(version, "2.0", "factomd api version")
 }
+
+func TestRequestTLS(t *testing.T) {
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"ok"}`)
+		}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	// Write the test server's self signed certificate to a file.
+	certFile, err := ioutil.TempFile("", "factomd.cert")
+	require.NoError(t, err)
+	defer os.Remove(certFile.Name())
+	require.NoError(t, pem.Encode(certFile, &pem.Block{
+		Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+	require.NoError(t, certFile.Close())
+
+	badCertFile, err := ioutil.TempFile("", "bad.cert")
+	require.NoError(t, err)
+	defer os.Remove(badCertFile.Name())
+	require.NoError(t, badCertFile.Close())
+
+	cfg := *RpcConfig
+	defer func() { *RpcConfig = cfg }()
+	RpcConfig.FactomdServer = strings.TrimPrefix(ts.URL, "https://")
+	RpcConfig.FactomdTLSEnable = true
+	RpcConfig.FactomdTLSCertFile = certFile.Name()
+	RpcConfig.FactomdRPCUser = "user"
+	RpcConfig.FactomdRPCPassword = "pass"
+
+	assert := assert.New(t)
+	var result string
+	for i := 0; i < 3; i++ {
+		result = ""
+		assert.NoError(FactomdRequest(ctx, "test", nil, &result))
+		assert.Equal("ok", result)
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(&conns),
+		"connections should be reused")
+
+	RpcConfig.FactomdRPCPassword = "wrong"
+	assert.EqualError(FactomdRequest(ctx, "test", nil, &result),
+		"http: 401 Unauthorized")
+	RpcConfig.FactomdRPCPassword = "pass"
+
+	// The wallet settings are independent of the factomd settings.
+	RpcConfig.WalletServer = RpcConfig.FactomdServer
+	RpcConfig.WalletTLSEnable = true
+	RpcConfig.WalletTLSCertFile = certFile.Name()
+	assert.EqualError(WalletRequest(ctx, "test", nil, &result),
+		"http: 401 Unauthorized")
+	RpcConfig.WalletRPCUser = "user"
+	RpcConfig.WalletRPCPassword = "pass"
+	assert.NoError(WalletRequest(ctx, "test", nil, &result))
+
+	// Without the cert the system root CAs do not trust the server.
+	RpcConfig.FactomdTLSCertFile = ""
+	err = FactomdRequest(ctx, "test", nil, &result)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "certificate")
+	}
+
+	RpcConfig.FactomdTLSCertFile = badCertFile.Name()
+	assert.EqualError(FactomdRequest(ctx, "test", nil, &result),
+		badCertFile.Name()+": no PEM encoded certificates found")
+
+	RpcConfig.FactomdTLSCertFile = certFile.Name() + ".missing"
+	assert.Error(FactomdRequest(ctx, "test", nil, &result))
+
+	// Without TLS the server rejects the plain HTTP request.
+	RpcConfig.FactomdTLSCertFile = certFile.Name()
+	RpcConfig.FactomdTLSEnable = false
+	assert.Error(FactomdRequest(ctx, "test", nil, &result))
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+// Status of each transaction in an airdrop state file.
+const (
+	// The transaction was signed and saved but may or may not have been
+	// submitted.
+	airdropSigned = "signed"
+	// The transaction was accepted by factomd or fatd.
+	airdropSubmitted = "submitted"
+	// The transaction has been applied by fatd.
+	airdropConfirmed = "confirmed"
+)
+
+// airdropState is the format of the file used to resume an airdrop. Each
+// signed transaction is saved before it is submitted so that if the airdrop is
+// interrupted, the exact same entry is resubmitted rather than a new one with a
+// different Entry Hash which could result in the tokens being minted twice.
+type airdropState struct {
+	ChainID *factom.Bytes32 `json:"chainid"`
+	// CSVHash is the SHA256 hash of the CSV file so that a state file is
+	// never used with a CSV file that has since changed.
+	CSVHash      factom.Bytes32       `json:"csvhash"`
+	Transactions []airdropTransaction `json:"transactions"`
+}
+
+type airdropTransaction struct {
+	txFile
+	Status string `json:"status"`
+}
+
+// airdrop mints the amounts in the CSV file to each address using as few
+// coinbase transactions as possible. Progress is saved to the state file after
+// each step so that the airdrop can be resumed by running the same command
+// again.
+func airdrop() error {
+	// Only FAT-0 coinbase transactions are built, so refuse any other
+	// token type before a state file is written.
+	issuance, err := fatd.GetIssuance(ctx, srv.ParamsToken{ChainID: chainID})
+	if err != nil {
+		return err
+	}
+	if issuance.Issuance.Type != fat.TypeFAT0 {
+		return fmt.Errorf("airdrop only supports FAT-0 tokens, "+
+			"not %v", issuance.Issuance.Type)
+	}
+
+	data, err := ioutil.ReadFile(airdropCSVPath)
+	if err != nil {
+		return err
+	}
+	outputs, err := parseAirdropCSV(data)
+	if err != nil {
+		return fmt.Errorf("%v: %v", airdropCSVPath, err)
+	}
+	txs := splitOutputs(*coinbaseAddress.RCDHash(), outputs)
+
+	state, err := loadAirdropState(sha256.Sum256(data), txs)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the status of transactions from a previous run.
+	var remaining uint64
+	var pending []*factom.Bytes32
+	for i := range txs {
+		saved := &state.Transactions[i]
+		if saved.Status == airdropConfirmed {
+			continue
+		}
+		if len(saved.ExtIDs) > 0 {
+			tx, err := saved.transaction()
+			if err != nil {
+				return fmt.Errorf("%v: transactions[%v]: %v",
+					airdropStatePath, i, err)
+			}
+			hash := tx.ComputeHash()
+			confirmed, err := isTransactionConfirmed(&hash)
+			if err != nil {
+				return err
+			}
+			if confirmed {
+				saved.Status = airdropConfirmed
+				continue
+			}
+			resign, err := canResign(tx, &hash)
+			if err != nil {
+				return err
+			}
+			if resign {
+				// The saved entry can never be applied, so
+				// it is safe to sign a new one.
+				saved.ExtIDs = nil
+				saved.Status = ""
+			} else if saved.Status == airdropSubmitted {
+				pending = append(pending, &hash)
+			}
+		}
+		remaining += txs[i].Outputs.Sum()
+	}
+	if err := saveAirdropState(state); err != nil {
+		return err
+	}
+	if remaining == 0 {
+		fmt.Fprintln(infoOut, "Airdrop already complete")
+		return nil
+	}
+
+	if err := checkRemainingSupply(remaining); err != nil {
+		return err
+	}
+	if err := verifySK1(); err != nil {
+		return err
+	}
+	fmt.Fprintf(infoOut, "Minting %v tokens to %v addresses in %v transactions, "+
+		"%v remaining\n", outputs.Sum(), len(outputs), len(txs), remaining)
+
+	for i := range txs {
+		saved := &state.Transactions[i]
+		if saved.Status == airdropConfirmed ||
+			saved.Status == airdropSubmitted {
+			continue
+		}
+		tx := &txs[i]
+		if saved.Status == airdropSigned {
+			// This may have been submitted before the previous run
+			// was interrupted so the same entry must be used.
+			resumed, err := saved.transaction()
+			if err != nil {
+				return err
+			}
+			*tx = resumed
+		} else {
+			if err := tx.MarshalEntry(); err != nil {
+				return err
+			}
+			// The timestamp salt is exactly now, rather than
+			// randomized, so that canResign can tell when the
+			// entry can no longer be applied.
+			tx.SetTimestampToNow()
+			tx.InitRCDSigs(1)
+			if err := tx.SignRCDSigID(0, sk1); err != nil {
+				return err
+			}
+			if err := tx.Valid(sk1.RCDHash()); err != nil {
+				return err
+			}
+			saved.ExtIDs = tx.ExtIDs
+			saved.Status = airdropSigned
+			if err := saveAirdropState(state); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(infoOut, "Transaction %v/%v: %v outputs, %v tokens\n",
+			i+1, len(txs), len(tx.Outputs), tx.Outputs.Sum())
+		if dryRun {
+			continue
+		}
+		txID, err := submit(&tx.Entry.Entry)
+		if err != nil {
+			return fmt.Errorf("%v\nThe transaction may already have "+
+				"been submitted. Run the same command again to "+
+				"resume the airdrop.", err)
+		}
+		saved.Status = airdropSubmitted
+		if err := saveAirdropState(state); err != nil {
+			return err
+		}
+		hash := tx.ComputeHash()
+		pending = append(pending, &hash)
+		fmt.Fprintln(infoOut, "\tTransaction Entry Hash: ", tx.Hash)
+		fmt.Fprintln(infoOut, "\tFactom TxID: ", txID)
+		recordSubmitted(chainID, tx.Hash, txID)
+	}
+	if dryRun || len(pending) == 0 {
+		return nil
+	}
+
+	if err := waitForTransactions(pending); err != nil {
+		return err
+	}
+	for i := range state.Transactions {
+		state.Transactions[i].Status = airdropConfirmed
+	}
+	if err := saveAirdropState(state); err != nil {
+		return err
+	}
+	fmt.Fprintln(infoOut, "Airdrop complete")
+	return nil
+}
+
+// parseAirdropCSV returns the outputs for the address,amount rows in data.
+// Lines starting with # are ignored, as is an optional header row.
+func parseAirdropCSV(data []byte) (fat0.AddressAmountMap, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 && strings.EqualFold(records[0][0], "address") {
+		records = records[1:]
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no rows")
+	}
+	outputs := make(fat0.AddressAmountMap, len(records))
+	var total uint64
+	for i, record := range records {
+		var rcdHash factom.RCDHash
+		if err := rcdHash.FromString(record[0]); err != nil {
+			return nil, fmt.Errorf("row %v: %v", i+1, err)
+		}
+		if rcdHash == *coinbaseAddress.RCDHash() {
+			return nil, fmt.Errorf("row %v: coinbase address", i+1)
+		}
+		if _, ok := outputs[rcdHash]; ok {
+			return nil, fmt.Errorf("row %v: duplicate address: %v",
+				i+1, record[0])
+		}
+		amount, err := strconv.ParseUint(strings.TrimSpace(record[1]),
+			10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("row %v: %v", i+1, err)
+		}
+		if amount == 0 {
+			return nil, fmt.Errorf("row %v: amount may not be zero",
+				i+1)
+		}
+		if total+amount < total {
+			return nil, fmt.Errorf("row %v: total overflows uint64",
+				i+1)
+		}
+		total += amount
+		outputs[rcdHash] = amount
+	}
+	return outputs, nil
+}
+
+// loadAirdropState returns the state saved at airdropStatePath, or a new state
+// if the file does not exist. The saved transactions must have the same
+// content as txs.
+func loadAirdropState(csvHash factom.Bytes32,
+	txs []fat0.Transaction) (airdropState, error) {
+	state := airdropState{ChainID: chainID, CSVHash: csvHash,
+		Transactions: make([]airdropTransaction, len(txs))}
+	for i := range txs {
+		if err := txs[i].MarshalEntry(); err != nil {
+			return state, err
+		}
+		state.Transactions[i].ChainID = chainID
+		state.Transactions[i].Content = txs[i].Content
+	}
+
+	data, err := ioutil.ReadFile(airdropStatePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	var saved airdropState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return state, fmt.Errorf("%v: %v", airdropStatePath, err)
+	}
+	if saved.ChainID == nil || *saved.ChainID != *chainID ||
+		saved.CSVHash != csvHash ||
+		len(saved.Transactions) != len(txs) {
+		return state, fmt.Errorf("%v: does not match the CSV file "+
+			"and token chain", airdropStatePath)
+	}
+	for i, tx := range saved.Transactions {
+		if !bytes.Equal(tx.Content, state.Transactions[i].Content) {
+			return state, fmt.Errorf("%v: transactions[%v]: "+
+				"content does not match the CSV file",
+				airdropStatePath, i)
+		}
+	}
+	fmt.Fprintln(infoOut, "Resuming airdrop from ", airdropStatePath)
+	return saved, nil
+}
+
+// saveAirdropState atomically writes state to airdropStatePath. Nothing is
+// saved for a dry run.
+func saveAirdropState(state airdropState) error {
+	if dryRun {
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := airdropStatePath + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, airdropStatePath)
+}
+
+// transaction returns the saved signed Transaction.
+func (saved airdropTransaction) transaction() (fat0.Transaction, error) {
+	tx := fat0.NewTransaction(factom.Entry{ChainID: saved.ChainID,
+		Content: saved.Content, ExtIDs: saved.ExtIDs})
+	if err := tx.UnmarshalEntry(); err != nil {
+		return tx, err
+	}
+	return tx, nil
+}
+
+// airdropResignDelay is how long after its timestamp salt a saved entry that
+// is not in any Entry Block may be replaced. An entry is only valid in a block
+// within 12 hours of its timestamp salt, and factomd drops any submitted entry
+// that is not in a block within an hour.
+const airdropResignDelay = 13 * time.Hour
+
+// canResign returns true if the saved transaction tx with Entry Hash hash,
+// which fatd has not applied, can never be applied, so that a new entry may be
+// signed in its place without the tokens being minted twice. This is the case
+// if the entry is in a block that fatd has processed without applying it, or
+// if factomd does not have the entry in any block and it is too late for a new
+// block to include it.
+func canResign(tx fat0.Transaction, hash *factom.Bytes32) (bool, error) {
+	e := factom.Entry{Hash: hash, ChainID: tx.ChainID}
+	pending, err := getEntryBlockInfo(&e)
+	if err != nil {
+		return false, err
+	}
+	if pending {
+		salt, err := timestampSalt(tx)
+		if err != nil {
+			// fatd always rejects an invalid timestamp salt.
+			return true, nil
+		}
+		return time.Since(salt) > airdropResignDelay, nil
+	}
+	block, err := getBlockEntries(e.Height)
+	if err != nil {
+		return false, err
+	}
+	if block == nil {
+		// fatd has not yet processed the block with the entry.
+		return false, nil
+	}
+	return verdict(*block, hash) != "applied", nil
+}
+
+// timestampSalt returns the time of the timestamp salt of tx.
+func timestampSalt(tx fat0.Transaction) (time.Time, error) {
+	sec, err := strconv.ParseInt(string(tx.ExtIDs[0]), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// checkRemainingSupply returns an error if minting amount would exceed the
+// token's supply.
+func checkRemainingSupply(amount uint64) error {
+	params := srv.ParamsToken{ChainID: chainID}
+	stats, err := fatd.GetStats(ctx, params)
+	if err != nil {
+		return err
+	}
+	if stats.Supply < 0 {
+		// Unlimited supply
+		return nil
+	}
+	issued := stats.CirculatingSupply + stats.Burned
+	available := uint64(stats.Supply) - issued
+	if amount > available {
+		return fmt.Errorf("insufficient remaining supply: "+
+			"have %v, need %v", available, amount)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAirdropCSV(t *testing.T) {
+	a, b := testRCDHashes[0], testRCDHashes[1]
+	coinbase := *coinbaseAddress.RCDHash()
+	for _, test := range []struct {
+		Name    string
+		CSV     string
+		Outputs fat0.AddressAmountMap
+		Error   string
+	}{{
+		Name: "header, comments and whitespace",
+		CSV: fmt.Sprintf("address,amount\n# comment\n%v, 5\n\n%v,10 \n",
+			a, b),
+		Outputs: fat0.AddressAmountMap{a: 5, b: 10},
+	}, {
+		Name:  "empty",
+		CSV:   "",
+		Error: "no rows",
+	}, {
+		Name:  "coinbase address",
+		CSV:   fmt.Sprintf("%v,5\n%v,10\n", a, coinbase),
+		Error: "row 2: coinbase address",
+	}, {
+		Name:  "duplicate address",
+		CSV:   fmt.Sprintf("%v,5\n%v,10\n", a, a),
+		Error: fmt.Sprintf("row 2: duplicate address: %v", a),
+	}, {
+		Name:  "zero amount",
+		CSV:   fmt.Sprintf("%v,0\n", a),
+		Error: "row 1: amount may not be zero",
+	}, {
+		Name: "total overflows",
+		CSV: fmt.Sprintf("%v,%v\n%v,1\n",
+			a, uint64(math.MaxUint64), b),
+		Error: "row 2: total overflows uint64",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			outputs, err := parseAirdropCSV([]byte(test.CSV))
+			if len(test.Error) > 0 {
+				assert.EqualError(t, err, test.Error)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Outputs, outputs)
+		})
+	}
+}
+
+func TestLoadAirdropState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fatd-airdrop")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	airdropStatePath = filepath.Join(dir, "airdrop.json")
+	defer func() { airdropStatePath = "" }()
+
+	csvHash := factom.Bytes32(sha256.Sum256([]byte("csv")))
+	input := *coinbaseAddress.RCDHash()
+	txs := splitOutputs(input, newTestAmounts(t, 300))
+	require.True(t, len(txs) > 1)
+
+	// Without a saved state a new state is returned.
+	state, err := loadAirdropState(csvHash, txs)
+	require.NoError(t, err)
+	assert.Equal(t, chainID, state.ChainID)
+	assert.Equal(t, csvHash, state.CSVHash)
+	require.Len(t, state.Transactions, len(txs))
+	for i := range txs {
+		assert.Equal(t, txs[i].Content, state.Transactions[i].Content)
+		assert.Empty(t, state.Transactions[i].Status)
+	}
+
+	state.Transactions[0].Status = "submitted"
+	require.NoError(t, saveAirdropState(state))
+
+	for _, test := range []struct {
+		Name    string
+		CSVHash factom.Bytes32
+		Txs     []fat0.Transaction
+		Error   string
+	}{{
+		Name:    "resume",
+		CSVHash: csvHash,
+		Txs:     txs,
+	}, {
+		Name:    "csv changed",
+		CSVHash: factom.Bytes32{1},
+		Txs:     txs,
+		Error: airdropStatePath +
+			": does not match the CSV file and token chain",
+	}, {
+		Name:    "content changed",
+		CSVHash: csvHash,
+		Txs: func() []fat0.Transaction {
+			changed := splitOutputs(input, newTestAmounts(t, 300))
+			require.Len(t, changed, len(txs))
+			return changed
+		}(),
+		Error: airdropStatePath +
+			": transactions[0]: content does not match the CSV file",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			loaded, err := loadAirdropState(test.CSVHash, test.Txs)
+			if len(test.Error) > 0 {
+				assert.EqualError(t, err, test.Error)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, state, loaded)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/jsonlen"
+)
+
+// maxEntryDataLen is the maximum combined length of the encoded ExtIDs and the
+// Content of a Factom Entry.
+const maxEntryDataLen = 10240
+
+// extIDsLen returns the encoded length of the ExtIDs of a signed Transaction
+// with numRCDSigPairs RCD/signature pairs.
+func extIDsLen(numRCDSigPairs int) int {
+	timeSaltLen := jsonlen.Int64(time.Now().Unix())
+	return 2 + timeSaltLen +
+		numRCDSigPairs*(2+factom.RCDSize+2+factom.SignatureSize)
+}
+
+// addressAmountJSONLen returns the length of a single `"FA...":amount` member
+// of a compact AddressAmountMap JSON object.
+func addressAmountJSONLen(amount uint64) int {
+	return len(`"FA2MwhbJFxPckPahsmntwF1ogKjXGz8FSqo2cLWtshdU47GQVZDC":`) +
+		jsonlen.Uint64(amount)
+}
+
+// txContentLen returns the length of the compact JSON content of a Transaction
+// whose inputs and outputs have the given JSON member lengths.
+func txContentLen(inputsLen, numInputs, outputsLen, numOutputs int,
+	metadataLen int) int {
+	return len(`{"inputs":{},"outputs":{}}`) +
+		inputsLen + numInputs - 1 +
+		outputsLen + numOutputs - 1 +
+		metadataLen
+}
+
+// splitOutputs divides outputs across as few Transactions from the single
+// input address as possible while keeping each Transaction's Entry within the
+// Factom Entry size limit. Outputs are assigned in the order returned by
+// sortedRCDHashes so the result is deterministic.
+func splitOutputs(input factom.RCDHash,
+	outputs fat0.AddressAmountMap) []fat0.Transaction {
+	maxContentLen := maxEntryDataLen - extIDsLen(1)
+	var txs []fat0.Transaction
+	var tx fat0.Transaction
+	var outputsLen int
+	for _, rcdHash := range sortedRCDHashes(outputs) {
+		amount := outputs[rcdHash]
+		if amount == 0 {
+			continue
+		}
+		memberLen := addressAmountJSONLen(amount)
+		if len(tx.Outputs) > 0 {
+			sum := tx.Outputs.Sum() + amount
+			contentLen := txContentLen(addressAmountJSONLen(sum), 1,
+				outputsLen+memberLen, len(tx.Outputs)+1,
+				tx.MetadataJSONLen())
+			if contentLen > maxContentLen {
+				txs = append(txs, tx)
+				tx = fat0.Transaction{}
+				outputsLen = 0
+			}
+		}
+		if tx.Outputs == nil {
+			tx.Inputs = fat0.AddressAmountMap{input: 0}
+			tx.Outputs = make(fat0.AddressAmountMap)
+			tx.ChainID = chainID
+		}
+		tx.Inputs[input] += amount
+		tx.Outputs[rcdHash] = amount
+		outputsLen += memberLen
+	}
+	if len(tx.Outputs) > 0 {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// sortedRCDHashes returns the RCDHashes of m in ascending byte order.
+func sortedRCDHashes(m fat0.AddressAmountMap) []factom.RCDHash {
+	rcdHashes := make([]factom.RCDHash, 0, len(m))
+	for rcdHash := range m {
+		rcdHashes = append(rcdHashes, rcdHash)
+	}
+	sort.Slice(rcdHashes, func(i, j int) bool {
+		return bytes.Compare(rcdHashes[i][:], rcdHashes[j][:]) < 0
+	})
+	return rcdHashes
+}
+
+// splitInputs divides inputs across as few Transactions to the single output
+// address as possible while keeping each Transaction's Entry within the Factom
+// Entry size limit. Each input requires its own RCD/signature pair in the
+// ExtIDs. Inputs are assigned in the order returned by sortedRCDHashes so the
+// result is deterministic.
+func splitInputs(inputs fat0.AddressAmountMap,
+	output factom.RCDHash) []fat0.Transaction {
+	var txs []fat0.Transaction
+	var tx fat0.Transaction
+	var inputsLen int
+	for _, rcdHash := range sortedRCDHashes(inputs) {
+		amount := inputs[rcdHash]
+		if amount == 0 {
+			continue
+		}
+		memberLen := addressAmountJSONLen(amount)
+		if len(tx.Inputs) > 0 {
+			sum := tx.Inputs.Sum() + amount
+			entryLen := extIDsLen(len(tx.Inputs)+1) +
+				txContentLen(inputsLen+memberLen,
+					len(tx.Inputs)+1,
+					addressAmountJSONLen(sum), 1,
+					tx.MetadataJSONLen())
+			if entryLen > maxEntryDataLen {
+				txs = append(txs, tx)
+				tx = fat0.Transaction{}
+				inputsLen = 0
+			}
+		}
+		if tx.Inputs == nil {
+			tx.Inputs = make(fat0.AddressAmountMap)
+			tx.Outputs = fat0.AddressAmountMap{output: 0}
+			tx.ChainID = chainID
+		}
+		tx.Inputs[rcdHash] = amount
+		tx.Outputs[output] += amount
+		inputsLen += memberLen
+	}
+	if len(tx.Inputs) > 0 {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// signAndSubmit marshals, signs, validates and submits each of txs in order.
+// Each transaction is signed with the keys for its inputs, in the order
+// returned by sortedRCDHashes. The Entry Hash of each submitted transaction is
+// printed as it is submitted and all are returned. If dryRun is true the
+// transactions are signed and validated but not submitted.
+func signAndSubmit(txs []fat0.Transaction, dryRun bool,
+	keys map[factom.RCDHash]factom.Address) ([]*factom.Bytes32, error) {
+	var hashes []*factom.Bytes32
+	for i := range txs {
+		tx := &txs[i]
+		if err := tx.MarshalEntry(); err != nil {
+			return hashes, err
+		}
+		signingSet := make([]factom.Address, 0, len(tx.Inputs))
+		for _, rcdHash := range sortedRCDHashes(tx.Inputs) {
+			adr, ok := keys[rcdHash]
+			if !ok {
+				return hashes, fmt.Errorf("no key for %v", rcdHash)
+			}
+			signingSet = append(signingSet, adr)
+		}
+		tx.Sign(signingSet...)
+		if err := tx.Valid(sk1.RCDHash()); err != nil {
+			return hashes, err
+		}
+		fmt.Fprintf(infoOut, "Transaction %v/%v: %v inputs, %v outputs, %v tokens\n",
+			i+1, len(txs), len(tx.Inputs), len(tx.Outputs),
+			tx.Outputs.Sum())
+		if dryRun {
+			continue
+		}
+		txID, err := submit(&tx.Entry.Entry)
+		if err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, tx.Hash)
+		fmt.Fprintln(infoOut, "\tTransaction Entry Hash: ", tx.Hash)
+		fmt.Fprintln(infoOut, "\tFactom TxID: ", txID)
+		recordSubmitted(chainID, tx.Hash, txID)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSigners returns n Addresses with random private keys.
+func newTestSigners(t *testing.T, n int) []factom.Address {
+	signers := make([]factom.Address, n)
+	for i := range signers {
+		seed := make([]byte, 32)
+		_, err := rand.Read(seed)
+		require.NoError(t, err)
+		*signers[i].PrivateKey() = *factom.NewPrivateKey(seed)
+	}
+	return signers
+}
+
+// newTestAmounts returns an AddressAmountMap with n distinct addresses that
+// each have a large amount so that their JSON is as long as possible.
+func newTestAmounts(t *testing.T, n int) fat0.AddressAmountMap {
+	amounts := make(fat0.AddressAmountMap, n)
+	for i := 0; i < n; i++ {
+		var rcdHash factom.RCDHash
+		_, err := rand.Read(rcdHash[:])
+		require.NoError(t, err)
+		amounts[rcdHash] = 1e15 + uint64(i)
+	}
+	return amounts
+}
+
+// signedLen returns the combined length of the encoded ExtIDs and the Content
+// of tx once it is signed by numSigners addresses.
+func signedLen(t *testing.T, tx *fat0.Transaction, numSigners int) int {
+	tx.ChainID = chainID
+	require.NoError(t, tx.MarshalEntry())
+	tx.Sign(newTestSigners(t, numSigners)...)
+	n := len(tx.Content)
+	for _, extID := range tx.ExtIDs {
+		n += 2 + len(extID)
+	}
+	return n
+}
+
+func TestExtIDsLen(t *testing.T) {
+	for _, n := range []int{1, 10} {
+		inputs := newTestAmounts(t, n)
+		tx := fat0.Transaction{Inputs: inputs, Outputs: fat0.AddressAmountMap{
+			factom.RCDHash{0xff}: inputs.Sum()}}
+		assert.Equal(t, signedLen(t, &tx, n)-len(tx.Content),
+			extIDsLen(n), "%v RCD/signature pairs", n)
+	}
+}
+
+func TestSplitOutputs(t *testing.T) {
+	input := *coinbaseAddress.RCDHash()
+	outputs := newTestAmounts(t, 1000)
+	outputs[testRCDHashes[0]] = 0
+	txs := splitOutputs(input, outputs)
+	// Fewer transactions would exceed the maximum entry size.
+	require.Len(t, txs, 8)
+
+	delete(outputs, testRCDHashes[0])
+	split := make(fat0.AddressAmountMap)
+	for i := range txs {
+		tx := &txs[i]
+		assert.Equal(t, fat0.AddressAmountMap{input: tx.Outputs.Sum()},
+			tx.Inputs)
+		for rcdHash, amount := range tx.Outputs {
+			split[rcdHash] = amount
+		}
+		assert.True(t, signedLen(t, tx, 1) <= maxEntryDataLen)
+	}
+	assert.Equal(t, outputs, split)
+}
+
+func TestSplitInputs(t *testing.T) {
+	output := factom.RCDHash{0xff}
+	inputs := newTestAmounts(t, 200)
+	inputs[testRCDHashes[0]] = 0
+	txs := splitInputs(inputs, output)
+	// Fewer transactions would exceed the maximum entry size.
+	require.Len(t, txs, 4)
+
+	delete(inputs, testRCDHashes[0])
+	split := make(fat0.AddressAmountMap)
+	for i := range txs {
+		tx := &txs[i]
+		assert.Equal(t, fat0.AddressAmountMap{output: tx.Inputs.Sum()},
+			tx.Outputs)
+		for rcdHash, amount := range tx.Inputs {
+			split[rcdHash] = amount
+		}
+		assert.True(t, signedLen(t, tx, len(tx.Inputs)) <=
+			maxEntryDataLen)
+	}
+	assert.Equal(t, inputs, split)
+}
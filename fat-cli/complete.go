@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"os"
 
 	"github.com/posener/complete"
@@ -12,6 +13,7 @@ var (
 			"-debug": complete.PredictNothing,
 
 			"-apiaddress": complete.PredictAnything,
+			"-apitimeout": complete.PredictAnything,
 
 			"-w":              complete.PredictAnything,
 			"-wallettimeout":  complete.PredictAnything,
@@ -31,6 +33,10 @@ var (
 			"-installcompletion":   complete.PredictNothing,
 			"-uninstallcompletion": complete.PredictNothing,
 
+			"-keystore": complete.PredictFiles("*"),
+
+			"-format": complete.PredictSet("table", "json", "csv"),
+
 			"-tokenid":  complete.PredictAnything,
 			"-identity": complete.PredictAnything,
 			"-chainid":  complete.PredictAnything,
@@ -39,15 +45,34 @@ var (
 			"balance": complete.Command{
 				Args: predictAddress(true, 1, "", ""),
 			},
+			"nfbalance": complete.Command{
+				Args: predictAddress(true, 1, "", ""),
+			},
+			"nftoken": complete.Command{
+				Args: complete.PredictAnything,
+			},
+			"explain": complete.Command{
+				Args: complete.PredictAnything,
+			},
+			"portfolio": complete.Command{
+				Args: predictAddress(true, math.MaxInt32, "", ""),
+			},
 			"issue": complete.Command{
 				Flags: complete.Flags{
 					"-ecpub": predictAddress(
 						false, 1, "-ecpub", ""),
-					"-sk1":    complete.PredictAnything,
-					"-type":   complete.PredictSet("FAT-0"),
-					"-supply": complete.PredictAnything,
-					"-symbol": complete.PredictAnything,
-					"-name":   complete.PredictAnything,
+					"-sk1":     complete.PredictAnything,
+					"-type":    complete.PredictSet("FAT-0", "FAT-1"),
+					"-supply":  complete.PredictAnything,
+					"-symbol":  complete.PredictAnything,
+					"-name":    complete.PredictAnything,
+					"-wait":    complete.PredictNothing,
+					"-timeout": complete.PredictAnything,
+					"-csv":     complete.PredictFiles("*.csv"),
+					"-state":   complete.PredictFiles("*"),
+
+					"-metadata":      complete.PredictAnything,
+					"-metadata-file": complete.PredictFiles("*.json"),
 				},
 				Args: complete.PredictAnything,
 			},
@@ -61,9 +86,133 @@ var (
 						true, 1, "-input", ":"),
 					"-output": predictAddress(
 						true, 1, "-output", ":"),
+					"-from-wallet": complete.PredictNothing,
+					"-strategy": complete.PredictSet(
+						"fewest", "oldest", "addresses"),
+					"-from": predictAddress(
+						true, 1, "-from", ""),
+					"-change": predictAddress(
+						true, 1, "-change", ""),
+					"-tokenmetadata": complete.PredictFiles("*.json"),
+					"-metadata":      complete.PredictAnything,
+					"-metadata-file": complete.PredictFiles("*.json"),
 				},
 				Args: complete.PredictAnything,
 			},
+			"build": complete.Command{
+				Flags: complete.Flags{
+					"-coinbase": complete.PredictAnything,
+					"-input": predictAddress(
+						true, 1, "-input", ":"),
+					"-output": predictAddress(
+						true, 1, "-output", ":"),
+					"-txfile":  complete.PredictFiles("*"),
+					"-partial": complete.PredictNothing,
+				},
+			},
+			"sign": complete.Command{
+				Flags: complete.Flags{
+					"-sk1":     complete.PredictAnything,
+					"-keyfile": complete.PredictFiles("*"),
+					"-txfile":  complete.PredictFiles("*"),
+					"-partial": complete.PredictNothing,
+				},
+			},
+			"keystore": complete.Command{
+				Sub: complete.Commands{
+					"import": complete.Command{
+						Flags: complete.Flags{
+							"-label": complete.PredictAnything,
+						},
+					},
+					"generate": complete.Command{
+						Flags: complete.Flags{
+							"-label": complete.PredictAnything,
+							"-type": complete.PredictSet(
+								"Fs", "Es", "sk1"),
+						},
+					},
+					"list": complete.Command{},
+					"label": complete.Command{
+						Flags: complete.Flags{
+							"-label": complete.PredictAnything,
+						},
+						Args: complete.PredictAnything,
+					},
+				},
+			},
+			"combine": complete.Command{
+				Flags: complete.Flags{
+					"-txfile": complete.PredictFiles("*"),
+				},
+				Args: complete.PredictFiles("*"),
+			},
+			"submit": complete.Command{
+				Flags: complete.Flags{
+					"-ecpub": predictAddress(
+						false, 1, "-ecpub", ""),
+					"-txfile": complete.PredictFiles("*"),
+				},
+			},
+			"distribute": complete.Command{
+				Flags: complete.Flags{
+					"-ecpub": predictAddress(
+						false, 1, "-ecpub", ""),
+					"-sk1": complete.PredictAnything,
+					"-source": predictAddress(
+						true, 1, "-source", ""),
+					"-height": complete.PredictAnything,
+					"-total":  complete.PredictAnything,
+					"-dryrun": complete.PredictNothing,
+				},
+			},
+			"sweep": complete.Command{
+				Flags: complete.Flags{
+					"-ecpub": predictAddress(
+						false, 1, "-ecpub", ""),
+					"-from": predictAddress(
+						true, 1, "-from", ""),
+					"-to": predictAddress(
+						true, 1, "-to", ""),
+					"-dryrun": complete.PredictNothing,
+				},
+			},
+			"history": complete.Command{
+				Flags: complete.Flags{
+					"-address": predictAddress(
+						true, 1, "-address", ""),
+					"-tofrom": complete.PredictSet(
+						"to", "from"),
+					"-limit":  complete.PredictAnything,
+					"-start":  complete.PredictAnything,
+					"-cursor": complete.PredictAnything,
+					"-order": complete.PredictSet(
+						"asc", "desc"),
+					"-starttime":   complete.PredictAnything,
+					"-endtime":     complete.PredictAnything,
+					"-startheight": complete.PredictAnything,
+					"-endheight":   complete.PredictAnything,
+				},
+			},
+			"watch": complete.Command{
+				Flags: complete.Flags{
+					"-address": predictAddress(
+						true, 1, "-address", ""),
+					"-tofrom": complete.PredictSet(
+						"to", "from"),
+					"-interval": complete.PredictAnything,
+				},
+			},
+			"airdrop": complete.Command{
+				Flags: complete.Flags{
+					"-ecpub": predictAddress(
+						false, 1, "-ecpub", ""),
+					"-sk1":    complete.PredictAnything,
+					"-csv":    complete.PredictFiles("*.csv"),
+					"-state":  complete.PredictFiles("*"),
+					"-dryrun": complete.PredictNothing,
+				},
+			},
 		},
 	})
 )
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+func distribute() error {
+	// The distribution is paid in FAT-0 transactions of the token's own
+	// chain, which fatd would reject for any other token type.
+	issuance, err := fatd.GetIssuance(ctx, srv.ParamsToken{ChainID: chainID})
+	if err != nil {
+		return err
+	}
+	if issuance.Issuance.Type != fat.TypeFAT0 {
+		return fmt.Errorf("distribute only supports FAT-0 tokens, "+
+			"not %v", issuance.Issuance.Type)
+	}
+
+	params := srv.ParamsGetHoldersSnapshot{
+		ParamsToken: srv.ParamsToken{ChainID: chainID},
+		Height:      &snapshotHeight,
+	}
+	snapshot, err := fatd.GetHoldersSnapshot(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	var signer factom.Address
+	var input factom.RCDHash
+	if flagIsSet["sk1"] {
+		if err := verifySK1(); err != nil {
+			return err
+		}
+		signer = sk1
+		input = *coinbaseAddress.RCDHash()
+	} else {
+		signer = factom.NewAddress(&source)
+		if err := getAddress(&signer); err != nil {
+			return err
+		}
+		input = *signer.RCDHash()
+		// An address may not be both an input and an output.
+		delete(snapshot.Holders, input)
+	}
+	if len(snapshot.Holders) == 0 {
+		return fmt.Errorf("no holders at height %v", snapshot.Height)
+	}
+
+	amounts := proRata(snapshot.Holders, distributeTotal)
+	txs := splitOutputs(input, amounts)
+	fmt.Fprintf(infoOut, "Distributing %v tokens to %v holders at height %v "+
+		"in %v transactions\n", distributeTotal, len(amounts),
+		snapshot.Height, len(txs))
+	_, err = signAndSubmit(txs, dryRun,
+		map[factom.RCDHash]factom.Address{input: signer})
+	return err
+}
+
+// proRata divides total amongst holders in proportion to their balances.
+//
+// Each holder first receives the floor of their exact share. The remaining
+// tokens are then given out one at a time to the holders with the largest
+// fractional remainders. Ties are broken in favor of the holder with the
+// larger balance and then by ascending RCDHash, so the result is always the
+// same for the same snapshot and total. Holders whose share is zero are
+// omitted from the returned map.
+func proRata(holders fat0.AddressAmountMap, total uint64) fat0.AddressAmountMap {
+	sum := new(big.Int)
+	for _, balance := range holders {
+		sum.Add(sum, new(big.Int).SetUint64(balance))
+	}
+	amounts := make(fat0.AddressAmountMap, len(holders))
+	if sum.Sign() == 0 {
+		return amounts
+	}
+
+	type share struct {
+		rcdHash   factom.RCDHash
+		balance   uint64
+		remainder *big.Int
+	}
+	shares := make([]share, 0, len(holders))
+	totalBig := new(big.Int).SetUint64(total)
+	distributed := uint64(0)
+	for _, rcdHash := range sortedRCDHashes(holders) {
+		balance := holders[rcdHash]
+		product := new(big.Int).SetUint64(balance)
+		product.Mul(product, totalBig)
+		quo, rem := product.QuoRem(product, sum, new(big.Int))
+		amount := quo.Uint64()
+		amounts[rcdHash] = amount
+		distributed += amount
+		shares = append(shares, share{rcdHash: rcdHash,
+			balance: balance, remainder: rem})
+	}
+
+	sort.SliceStable(shares, func(i, j int) bool {
+		if cmp := shares[i].remainder.Cmp(shares[j].remainder); cmp != 0 {
+			return cmp > 0
+		}
+		if shares[i].balance != shares[j].balance {
+			return shares[i].balance > shares[j].balance
+		}
+		return bytes.Compare(shares[i].rcdHash[:],
+			shares[j].rcdHash[:]) < 0
+	})
+	for i := uint64(0); i < total-distributed; i++ {
+		amounts[shares[i].rcdHash]++
+	}
+
+	for rcdHash, amount := range amounts {
+		if amount == 0 {
+			delete(amounts, rcdHash)
+		}
+	}
+	return amounts
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRCDHashes = func() []factom.RCDHash {
+	rcdHashes := make([]factom.RCDHash, 3)
+	for i := range rcdHashes {
+		rcdHashes[i][0] = byte(i + 1)
+	}
+	return rcdHashes
+}()
+
+func TestProRata(t *testing.T) {
+	a, b, c := testRCDHashes[0], testRCDHashes[1], testRCDHashes[2]
+	for _, test := range []struct {
+		Name    string
+		Holders fat0.AddressAmountMap
+		Total   uint64
+		Amounts fat0.AddressAmountMap
+	}{{
+		Name:    "zero balances",
+		Holders: fat0.AddressAmountMap{a: 0, b: 0},
+		Total:   10,
+		Amounts: fat0.AddressAmountMap{},
+	}, {
+		Name:    "largest remainder",
+		Holders: fat0.AddressAmountMap{a: 1, b: 2},
+		Total:   4,
+		Amounts: fat0.AddressAmountMap{a: 1, b: 3},
+	}, {
+		Name:    "tie broken by RCDHash",
+		Holders: fat0.AddressAmountMap{c: 1, b: 1, a: 1},
+		Total:   10,
+		Amounts: fat0.AddressAmountMap{a: 4, b: 3, c: 3},
+	}, {
+		Name: "no overflow",
+		Holders: fat0.AddressAmountMap{
+			a: math.MaxUint64, b: math.MaxUint64},
+		Total: math.MaxUint64,
+		Amounts: fat0.AddressAmountMap{
+			a: math.MaxUint64/2 + 1, b: math.MaxUint64 / 2},
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			amounts := proRata(test.Holders, test.Total)
+			assert.Equal(t, test.Amounts, amounts)
+			if len(amounts) > 0 {
+				assert.Equal(t, test.Total, amounts.Sum())
+			}
+		})
+	}
+}
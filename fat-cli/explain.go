@@ -0,0 +1,510 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/client"
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+// Results of an explainCheck.
+const (
+	checkPass = "PASS"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// explainChecks are the checks performed by Valid in the order that they are
+// performed.
+var explainChecks = []fat.Check{
+	fat.CheckJSON,
+	fat.CheckData,
+	fat.CheckExtIDs,
+	fat.CheckTimestamp,
+	fat.CheckSignatures,
+	fat.CheckRCDs,
+}
+
+// explainCheck is the outcome of a single validation check.
+type explainCheck struct {
+	Check  string `json:"check"`
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type explainResult struct {
+	ChainID   *factom.Bytes32 `json:"chainid"`
+	EntryHash *factom.Bytes32 `json:"entryhash"`
+	// Height and Timestamp are omitted if the entry is not yet in a
+	// block, in which case the checks use the current time.
+	Height    uint64         `json:"height,omitempty"`
+	Timestamp *factom.Time   `json:"timestamp,omitempty"`
+	Checks    []explainCheck `json:"checks"`
+	Valid     bool           `json:"valid"`
+	// Fatd is whether fatd applied or rejected the entry.
+	Fatd string `json:"fatd"`
+}
+
+// explain runs every validation check on the entry txHash in order and
+// reports which passed, which failed and why, along with the verdict of fatd.
+// An error is returned if any check fails.
+func explain() error {
+	e := factom.Entry{Hash: txHash}
+	if err := e.Get(ctx); err != nil {
+		return err
+	}
+	if !e.IsPopulated() {
+		return fmt.Errorf("entry not found: %v", txHash)
+	}
+	if *e.ChainID != *chainID {
+		return fmt.Errorf("entry %v is not in token chain %v",
+			txHash, chainID)
+	}
+	result := explainResult{ChainID: chainID, EntryHash: txHash}
+	pending, err := getEntryBlockInfo(&e)
+	if err != nil {
+		return err
+	}
+	if pending {
+		e.SetTimestampToNow()
+	} else {
+		result.Height, result.Timestamp = e.Height, e.Timestamp
+	}
+
+	issuance, err := fatd.GetIssuance(ctx, srv.ParamsToken{ChainID: chainID})
+	if err != nil {
+		return err
+	}
+	identity := fat.Identity{ChainID: issuance.IssuerChainID}
+	if err := identity.Get(ctx); err != nil {
+		return err
+	}
+	if !identity.IsPopulated() {
+		return fmt.Errorf("issuer identity not found: %v",
+			issuance.IssuerChainID)
+	}
+
+	// inputs are the balances required of each input address of a FAT-0
+	// transaction. The NFTokenIDs of a FAT-1 nfTx are checked instead.
+	var inputs fat0.AddressAmountMap
+	var nfTx *fat1.Transaction
+	var balanceSkip string
+	switch {
+	case *issuance.Hash == *e.Hash:
+		i := fat.NewIssuance(e)
+		err = i.Valid(identity.IDKey)
+		balanceSkip = "Issuance"
+	case issuance.Issuance.Type == fat.TypeFAT1:
+		tx := fat1.NewTransaction(e)
+		err = tx.Valid(identity.IDKey)
+		nfTx = &tx
+	default:
+		tx := fat0.NewTransaction(e)
+		err = tx.Valid(identity.IDKey)
+		inputs = tx.Inputs
+		if tx.IsCoinbase() {
+			balanceSkip = "coinbase transaction"
+		}
+	}
+	result.Checks = validationChecks(err)
+	result.Valid = err == nil
+
+	var block *client.BlockChainEntries
+	if !pending {
+		if block, err = getBlockEntries(e.Height); err != nil {
+			return err
+		}
+	}
+
+	balances := explainCheck{Check: "balances", Result: checkSkip,
+		Reason: balanceSkip}
+	if result.Valid && len(balanceSkip) == 0 {
+		if nfTx != nil {
+			balances, err = checkNFTokens(e, *nfTx, pending, block)
+		} else {
+			balances, err = checkBalances(e, inputs, pending, block)
+		}
+		if err != nil {
+			return err
+		}
+		result.Valid = balances.Result != checkFail
+	}
+	result.Checks = append(result.Checks, balances)
+
+	switch {
+	case pending:
+		result.Fatd = "pending: entry is not yet in a block"
+	case block == nil:
+		result.Fatd = fmt.Sprintf("unknown: fatd has not synced height %v",
+			e.Height)
+	default:
+		result.Fatd = verdict(*block, e.Hash)
+	}
+
+	if err := printResult(result, func() {
+		fmt.Printf("Entry Hash: %v\n", result.EntryHash)
+		fmt.Printf("Token Chain ID: %v\n", result.ChainID)
+		if pending {
+			fmt.Printf("Height: pending\n")
+		} else {
+			fmt.Printf("Height: %v\n", result.Height)
+			fmt.Printf("Timestamp: %v\n", result.Timestamp.Time)
+		}
+		fmt.Printf("Checks:\n")
+		for _, c := range result.Checks {
+			fmt.Printf("\t%v  %v", c.Result, c.Check)
+			if len(c.Reason) > 0 {
+				fmt.Printf(": %v", c.Reason)
+			}
+			fmt.Printf("\n")
+		}
+		fmt.Printf("fatd: %v\n", result.Fatd)
+	}, func() [][]string {
+		records := [][]string{{"check", "result", "reason"}}
+		for _, c := range result.Checks {
+			records = append(records,
+				[]string{c.Check, c.Result, c.Reason})
+		}
+		return records
+	}); err != nil {
+		return err
+	}
+	if !result.Valid {
+		return fmt.Errorf("entry %v is invalid", txHash)
+	}
+	return nil
+}
+
+// validationChecks returns the outcome of each of the explainChecks given the
+// error returned by Valid. Every check before the one that failed passed and
+// every check after it was not performed.
+func validationChecks(err error) []explainCheck {
+	failed := fat.Check(0)
+	if err != nil {
+		failed = fat.CheckJSON
+		var vErr fat.ValidationError
+		if errors.As(err, &vErr) {
+			failed = vErr.Check
+		}
+	}
+	checks := make([]explainCheck, len(explainChecks))
+	for i, check := range explainChecks {
+		c := &checks[i]
+		c.Check = check.String()
+		switch {
+		case failed == 0 || check < failed:
+			c.Result = checkPass
+		case check == failed:
+			c.Result, c.Reason = checkFail, err.Error()
+		default:
+			c.Result = checkSkip
+		}
+	}
+	return checks
+}
+
+// getEntryBlockInfo populates the Timestamp and Height of e from its Entry
+// Block. If e is not yet in an Entry Block then pending is true.
+func getEntryBlockInfo(e *factom.Entry) (pending bool, err error) {
+	params := struct {
+		Hash *factom.Bytes32 `json:"hash"`
+	}{Hash: e.Hash}
+	var result struct {
+		Receipt struct {
+			EBlockKeyMR *factom.Bytes32 `json:"entryblockkeymr"`
+		} `json:"receipt"`
+	}
+	err = factom.FactomdRequest(ctx, "receipt", params, &result)
+	if _, ok := err.(jrpc.Error); ok {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if result.Receipt.EBlockKeyMR == nil {
+		return true, nil
+	}
+	eb := factom.EBlock{ChainID: e.ChainID, KeyMR: result.Receipt.EBlockKeyMR}
+	if err := eb.Get(ctx); err != nil {
+		return false, err
+	}
+	for _, ebe := range eb.Entries {
+		if *ebe.Hash == *e.Hash {
+			e.Timestamp, e.Height = ebe.Timestamp, ebe.Height
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("entry %v not found in Entry Block %v",
+		e.Hash, eb.KeyMR)
+}
+
+// getBlockEntries returns the entries of the token chain that fatd processed
+// at height, in the order that they were processed. A nil result is returned
+// if fatd has not synced height.
+func getBlockEntries(height uint64) (*client.BlockChainEntries, error) {
+	result, err := fatd.GetBlockTransactions(ctx,
+		srv.ParamsGetBlockTransactions{Height: &height})
+	if _, ok := err.(jrpc.Error); ok {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, chain := range result.Chains {
+		if *chain.ChainID == *chainID {
+			return &chain, nil
+		}
+	}
+	return &client.BlockChainEntries{}, nil
+}
+
+// verdict returns whether fatd applied or rejected the entry hash in block b.
+func verdict(b client.BlockChainEntries, hash *factom.Bytes32) string {
+	if b.Issuance != nil && *b.Issuance.Hash == *hash {
+		return "applied"
+	}
+	for _, tx := range b.Transactions {
+		if *tx.Hash == *hash {
+			return "applied"
+		}
+	}
+	for _, r := range b.Rejected {
+		if *r.Hash == *hash {
+			return "rejected: " + r.Error
+		}
+	}
+	return "ignored"
+}
+
+// checkBalances checks that each input address held at least its amount in
+// inputs just before e was processed. This is the holders snapshot of the
+// previous block with the transactions that fatd applied before e in the same
+// block. If e is not yet in a block, the current balances are used.
+func checkBalances(e factom.Entry, inputs fat0.AddressAmountMap,
+	pending bool, block *client.BlockChainEntries) (explainCheck, error) {
+	check := explainCheck{Check: "balances", Result: checkSkip}
+	balances := make(fat0.AddressAmountMap, len(inputs))
+	switch {
+	case block == nil && !pending:
+		check.Reason = "fatd has not synced this height"
+		return check, nil
+	case pending:
+		for rcdHash := range inputs {
+			adr := factom.NewAddress(&rcdHash)
+			balance, err := fatd.GetBalance(ctx, srv.ParamsGetBalance{
+				ParamsToken: srv.ParamsToken{ChainID: chainID},
+				Address:     &adr,
+			})
+			if err != nil {
+				return check, err
+			}
+			balances[rcdHash] = balance
+		}
+	default:
+		if e.Height > 0 {
+			height := e.Height - 1
+			snapshot, err := fatd.GetHoldersSnapshot(ctx,
+				srv.ParamsGetHoldersSnapshot{
+					ParamsToken: srv.ParamsToken{ChainID: chainID},
+					Height:      &height,
+				})
+			if _, ok := err.(jrpc.Error); ok {
+				check.Reason = fmt.Sprintf("fatd: %v", err)
+				return check, nil
+			}
+			if err != nil {
+				return check, err
+			}
+			for rcdHash, amount := range snapshot.Holders {
+				balances[rcdHash] = amount
+			}
+		}
+		for _, tx := range block.Transactions {
+			if *tx.Hash == *e.Hash {
+				break
+			}
+			if err := applyBalances(balances, tx); err != nil {
+				return check, fmt.Errorf("%v: %v", tx.Hash, err)
+			}
+		}
+	}
+
+	for _, rcdHash := range sortedRCDHashes(inputs) {
+		if balances[rcdHash] < inputs[rcdHash] {
+			check.Result = checkFail
+			check.Reason = fmt.Sprintf("%v: balance %v < %v",
+				factom.NewAddress(&rcdHash),
+				balances[rcdHash], inputs[rcdHash])
+			return check, nil
+		}
+	}
+	check.Result = checkPass
+	return check, nil
+}
+
+// applyBalances updates balances with the FAT-0 transaction.
+func applyBalances(balances fat0.AddressAmountMap, tx client.Transaction) error {
+	tx0, err := tx.FAT0()
+	if err != nil {
+		return err
+	}
+	coinbase := *coinbaseAddress.RCDHash()
+	for rcdHash, amount := range tx0.Inputs {
+		if rcdHash != coinbase {
+			balances[rcdHash] -= amount
+		}
+	}
+	for rcdHash, amount := range tx0.Outputs {
+		balances[rcdHash] += amount
+	}
+	return nil
+}
+
+// checkNFTokens checks that each input address of the FAT-1 tx held every
+// NFTokenID that it spends just before e was processed, or for a coinbase tx,
+// that none of its NFTokenIDs had already been issued. If e is not yet in a
+// block, the NFTokenIDs currently held are used.
+func checkNFTokens(e factom.Entry, tx fat1.Transaction, pending bool,
+	block *client.BlockChainEntries) (explainCheck, error) {
+	check := explainCheck{Check: "balances", Result: checkSkip}
+	if block == nil && !pending {
+		check.Reason = "fatd has not synced this height"
+		return check, nil
+	}
+	held := make(fat1.AddressNFTokensMap, len(tx.Inputs))
+	for rcdHash := range tx.Inputs {
+		params := srv.ParamsGetTransactions{
+			ParamsToken: srv.ParamsToken{ChainID: chainID}}
+		adr := factom.NewAddress(&rcdHash)
+		var tkns fat1.NFTokens
+		var err error
+		switch {
+		case tx.IsCoinbase():
+			params.Coinbase = true
+			tkns, err = replayNFTokens(params, e, block, rcdHash)
+		case pending:
+			tkns, err = fatd.GetNFBalance(ctx, srv.ParamsGetNFBalance{
+				ParamsToken: params.ParamsToken, Address: &adr})
+		default:
+			params.FactoidAddress = &adr
+			tkns, err = replayNFTokens(params, e, block, rcdHash)
+		}
+		if _, ok := err.(jrpc.Error); ok {
+			check.Reason = fmt.Sprintf("fatd: %v", err)
+			return check, nil
+		}
+		if err != nil {
+			return check, err
+		}
+		held[rcdHash] = tkns
+	}
+	return nfTokensCheck(tx, held), nil
+}
+
+// replayNFTokens returns the NFTokenIDs held by rcdHash just before e was
+// processed by replaying the ID sets of the transactions selected by params
+// up to the previous block, and then those that fatd applied before e in the
+// same block. If e is pending, block is nil and every selected transaction is
+// replayed.
+func replayNFTokens(params srv.ParamsGetTransactions, e factom.Entry,
+	block *client.BlockChainEntries,
+	rcdHash factom.RCDHash) (fat1.NFTokens, error) {
+	tkns := make(fat1.NFTokens)
+	apply := func(tx client.Transaction) error {
+		tx1, err := tx.FAT1()
+		if err != nil {
+			return fmt.Errorf("%v: %v", tx.Hash, err)
+		}
+		applyNFTokens(tkns, rcdHash, tx1)
+		return nil
+	}
+	// An EndHeight of 0 selects every height, so nothing precedes height
+	// 1.
+	if block == nil || e.Height > 1 {
+		if block != nil {
+			params.EndHeight = e.Height - 1
+		}
+		params.Order = "asc"
+		it := fatd.GetTransactions(params)
+		for it.Next(ctx) {
+			for _, tx := range it.Page().Transactions {
+				if err := apply(tx); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if block == nil {
+		return tkns, nil
+	}
+	for _, tx := range block.Transactions {
+		if *tx.Hash == *e.Hash {
+			break
+		}
+		if err := apply(tx); err != nil {
+			return nil, err
+		}
+	}
+	return tkns, nil
+}
+
+// applyNFTokens updates tkns, the NFTokenIDs held by rcdHash, with the ID sets
+// of tx. The coinbase address is treated as holding every NFTokenID that it
+// has issued.
+func applyNFTokens(tkns fat1.NFTokens, rcdHash factom.RCDHash,
+	tx fat1.Transaction) {
+	if rcdHash == *coinbaseAddress.RCDHash() {
+		for id := range tx.Inputs[rcdHash] {
+			tkns[id] = struct{}{}
+		}
+		return
+	}
+	for id := range tx.Inputs[rcdHash] {
+		delete(tkns, id)
+	}
+	for id := range tx.Outputs[rcdHash] {
+		tkns[id] = struct{}{}
+	}
+}
+
+// nfTokensCheck returns the balances check of the FAT-1 tx given the
+// NFTokenIDs held by each of its input addresses. Each input address must
+// hold every NFTokenID that it spends, except the coinbase address, which must
+// not have issued any of them already.
+func nfTokensCheck(tx fat1.Transaction,
+	held fat1.AddressNFTokensMap) explainCheck {
+	check := explainCheck{Check: "balances", Result: checkPass}
+	rcdHashes := make(fat0.AddressAmountMap, len(tx.Inputs))
+	for rcdHash := range tx.Inputs {
+		rcdHashes[rcdHash] = 0
+	}
+	coinbase := *coinbaseAddress.RCDHash()
+	for _, rcdHash := range sortedRCDHashes(rcdHashes) {
+		for _, id := range tx.Inputs[rcdHash].Slice() {
+			_, ok := held[rcdHash][id]
+			switch {
+			case rcdHash == coinbase && ok:
+				check.Reason = fmt.Sprintf(
+					"NFTokenID already issued: %v", id)
+			case rcdHash != coinbase && !ok:
+				check.Reason = fmt.Sprintf(
+					"%v: does not hold NFTokenID %v",
+					factom.NewAddress(&rcdHash), id)
+			default:
+				continue
+			}
+			check.Result = checkFail
+			return check
+		}
+	}
+	return check
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNFTokensCheck(t *testing.T) {
+	a, b := testRCDHashes[0], testRCDHashes[1]
+	coinbase := *coinbaseAddress.RCDHash()
+	tkns := func(ids ...fat1.NFTokenID) fat1.NFTokens {
+		tkns := make(fat1.NFTokens, len(ids))
+		for _, id := range ids {
+			tkns[id] = struct{}{}
+		}
+		return tkns
+	}
+	transfer := func(from, to factom.RCDHash,
+		ids ...fat1.NFTokenID) fat1.Transaction {
+		return fat1.Transaction{
+			Inputs:  fat1.AddressNFTokensMap{from: tkns(ids...)},
+			Outputs: fat1.AddressNFTokensMap{to: tkns(ids...)},
+		}
+	}
+
+	// The coinbase address holds every NFTokenID it has issued, and each
+	// NFTokenID is held by whichever address it was last sent to.
+	held := fat1.AddressNFTokensMap{a: tkns(), b: tkns(), coinbase: tkns()}
+	for _, tx := range []fat1.Transaction{
+		transfer(coinbase, a, 1, 2, 3),
+		transfer(a, b, 1, 2),
+		transfer(b, a, 2),
+	} {
+		for rcdHash, tkns := range held {
+			applyNFTokens(tkns, rcdHash, tx)
+		}
+	}
+	assert.Equal(t, fat1.AddressNFTokensMap{
+		a: tkns(2, 3), b: tkns(1), coinbase: tkns(1, 2, 3)}, held)
+
+	for _, test := range []struct {
+		Name   string
+		Tx     fat1.Transaction
+		Reason string
+	}{{
+		Name: "held",
+		Tx:   transfer(a, b, 2, 3),
+	}, {
+		Name: "same count, different NFTokenIDs",
+		Tx:   transfer(a, b, 1, 3),
+		Reason: factom.NewAddress(&a).String() +
+			": does not hold NFTokenID 1",
+	}, {
+		Name: "coinbase",
+		Tx:   transfer(coinbase, a, 4, 5),
+	}, {
+		Name:   "coinbase already issued",
+		Tx:     transfer(coinbase, a, 3, 4),
+		Reason: "NFTokenID already issued: 3",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			check := nfTokensCheck(test.Tx, held)
+			assert.Equal(t, test.Reason, check.Reason)
+			if len(test.Reason) > 0 {
+				assert.Equal(t, checkFail, check.Result)
+			} else {
+				assert.Equal(t, checkPass, check.Result)
+			}
+		})
+	}
+}
@@ -1,17 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Factom-Asset-Tokens/base58"
+	"github.com/Factom-Asset-Tokens/fatd/client"
 	"github.com/Factom-Asset-Tokens/fatd/factom"
 	"github.com/Factom-Asset-Tokens/fatd/fat"
 	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
 	"github.com/FactomProject/ed25519"
 	"github.com/sirupsen/logrus"
 )
@@ -24,6 +33,7 @@ var (
 		"debug": "DEBUG",
 
 		"apiaddress": "API_ADDRESS",
+		"apitimeout": "API_TIMEOUT",
 
 		"w":              "WALLETD_SERVER",
 		"wallettimeout":  "WALLETD_TIMEOUT",
@@ -40,11 +50,15 @@ var (
 		"factomdtls":      "FACTOMD_TLS_ENABLE",
 
 		"ecpub": "ECPUB",
+
+		"keystore": "KEYSTORE",
 	}
 	defaults = map[string]interface{}{
 		"debug": false,
 
 		"apiaddress": "http://localhost:8078",
+		"apitimeout": time.Duration(0),
+		"format":     outputTable,
 
 		"w":              "localhost:8089",
 		"wallettimeout":  time.Duration(0),
@@ -70,12 +84,50 @@ var (
 		"symbol": "",
 		"name":   "",
 
-		"coinbase": uint64(0),
+		"coinbase":      uint64(0),
+		"tokenmetadata": "",
+
+		"metadata":      "",
+		"metadata-file": "",
+
+		"height": uint64(0),
+		"total":  uint64(0),
+		"dryrun": false,
+
+		"from-wallet": false,
+		"strategy":    strategyFewest,
+
+		"csv":   "",
+		"state": "",
+
+		"wait":    false,
+		"timeout": 30 * time.Minute,
+
+		"tofrom":      "",
+		"limit":       uint64(25),
+		"start":       uint64(0),
+		"cursor":      uint64(0),
+		"order":       "desc",
+		"startheight": uint64(0),
+		"endheight":   uint64(0),
+		"interval":    pollInterval,
+
+		"txfile":  "",
+		"keyfile": "",
+		"partial": false,
+
+		"keystore": func() string {
+			home, _ := os.UserHomeDir()
+			return filepath.Join(home, ".fat-cli", "keystore.json")
+		}(),
+		"label": "",
 	}
 	descriptions = map[string]string{
 		"debug": "Log debug messages",
 
 		"apiaddress": "IPAddr:port# to bind to for serving the JSON RPC 2.0 API",
+		"apitimeout": "Timeout for fatd API requests, 0 means never timeout",
+		"format":     `Output format for command results: "table", "json" or "csv"`,
 
 		"w":              "IPAddr:port# of factom-walletd API to use to access blockchain",
 		"wallettimeout":  "Timeout for factom-walletd API requests, 0 means never timeout",
@@ -105,6 +157,50 @@ var (
 		"coinbase": "Create a coinbase transaction with the given amount. Requires -sk1.",
 		"input":    "Add an -input ADDRESS:AMOUNT to the transaction. Can be specified multiple times.",
 		"output":   "Add an -output ADDRESS:AMOUNT to the transaction. Can be specified multiple times.",
+
+		"nfinput":       "Add an -input ADDRESS:AMOUNT, or ADDRESS:[NFTokenIDs] such as FA...:[1,5-100] for FAT-1, to the transaction. Use coinbase:[NFTokenIDs] with -sk1 to mint FAT-1 tokens. Can be specified multiple times.",
+		"nfoutput":      "Add an -output ADDRESS:AMOUNT, or ADDRESS:[NFTokenIDs] such as FA...:[1,5-100] for FAT-1, to the transaction. Can be specified multiple times.",
+		"tokenmetadata": "Path to a JSON file of FAT-1 token metadata for a coinbase transaction, in the same format as the transaction's \"tokenmetadata\"",
+
+		"metadata":      `Arbitrary JSON to include as the "metadata" of the entry (e.g. '{"memo":"deposit 1234"}')`,
+		"metadata-file": `Path to a file of JSON to include as the "metadata" of the entry`,
+
+		"height": "Block height at which to take the snapshot of token holders",
+		"total":  "Total number of tokens to distribute pro-rata amongst the holders",
+		"source": "Address to distribute tokens from. Use -sk1 instead to distribute newly minted tokens.",
+		"dryrun": "Build and sign the transactions but do not submit them",
+
+		"from-wallet": "Select the inputs from the wallet's addresses to cover the outputs",
+		"strategy":    `Input selection strategy for -from-wallet: "fewest", "oldest" or "addresses"`,
+		"from":        `Add an address to select inputs from with -strategy "addresses", or to sweep from. Can be specified multiple times.`,
+		"change":      "Address to send any surplus of the selected inputs to. Without -change only the required amount is spent.",
+
+		"to": "Address to sweep all balances to",
+
+		"address":     "Only show transactions to or from this address, with amounts as signed deltas for the address",
+		"tofrom":      `With -address, only show transactions "to" or "from" the address`,
+		"limit":       "Maximum number of transactions to show",
+		"start":       "Number of transactions to skip",
+		"cursor":      "Begin at the -cursor printed by a previous history command",
+		"order":       `"desc" to show the newest transactions first, or "asc"`,
+		"starttime":   "Only show transactions on or after this time, as a date, RFC3339 time or Unix timestamp",
+		"endtime":     "Only show transactions on or before this time, as a date, RFC3339 time or Unix timestamp",
+		"startheight": "Only show transactions at or above this block height",
+		"endheight":   "Only show transactions at or below this block height",
+		"interval":    "How often to poll fatd for new transactions",
+
+		"csv":   "Path to a CSV file of address,amount rows to airdrop to a FAT-0 token",
+		"state": "Path to the file used to resume an interrupted airdrop (default: the -csv path with .state.json appended)",
+
+		"wait":    "Wait for the Token Chain to be created, then issue the token and wait until fatd has processed the Issuance. Run again to resume. The signed Issuance is saved to CHAINID.issuance.json so that it is not issued twice.",
+		"timeout": "Maximum time to wait for each entry to be confirmed, 0 means never timeout",
+
+		"txfile":  "Path to the transaction file to write, sign or submit",
+		"keyfile": "Path to a file of Fs and sk1 keys, one per line, to sign with instead of factom-walletd",
+		"partial": "Allow the transaction to be signed by multiple parties. Each party signs only the inputs they hold keys for.",
+
+		"keystore": "Path to the encrypted keystore used instead of factom-walletd when -w is not set",
+		"label":    "Label for the key in the keystore",
 	}
 
 	issuance = func() fat.Issuance {
@@ -127,20 +223,85 @@ var (
 	address        = factom.Address{}
 	ECPub          string
 	metadata       string
+	metadataFile   string
 	tokenID        string
 
+	nfTransaction = fat1.Transaction{
+		Inputs:  fat1.AddressNFTokensMap{},
+		Outputs: fat1.AddressNFTokensMap{},
+	}
+	nfTokenID         *fat1.NFTokenID
+	tokenMetadataPath string
+
 	txHash *factom.Bytes32
 
+	coinbaseAddress = factom.Address{}
+	snapshotHeight  uint64
+	distributeTotal uint64
+	source          factom.RCDHash
+	dryRun          bool
+
+	portfolioAddresses []factom.Address
+
+	txFilePath   string
+	keyFilePath  string
+	partial      bool
+	partialFiles []string
+
+	fromWallet    bool
+	inputStrategy string
+	fromAddresses []factom.RCDHash
+	changeAddress factom.RCDHash
+
+	sweepDestination factom.RCDHash
+
+	historyParams    srv.ParamsGetTransactions
+	historyAddress   factom.RCDHash
+	historyLimit     uint64
+	historyStart     uint64
+	historyStartTime factom.Time
+	historyEndTime   factom.Time
+	watchInterval    time.Duration
+
+	airdropCSVPath   string
+	airdropStatePath string
+
+	waitIssue         bool
+	waitTimeout       time.Duration
+	issuanceStatePath string
+
+	keystorePath    string
+	keystoreSubCmd  string
+	keystoreKeyType string
+	keystoreLabel   string
+	keystoreArgs    []string
+	sk1Label        string
+
 	cmd string
 
 	globalFlagSet = flag.NewFlagSet("fat-cli", flag.ContinueOnError)
 
-	issueFlagSet    = flag.NewFlagSet("issue", flag.ExitOnError)
-	transactFlagSet = flag.NewFlagSet("transact", flag.ExitOnError)
+	issueFlagSet      = flag.NewFlagSet("issue", flag.ExitOnError)
+	transactFlagSet   = flag.NewFlagSet("transact", flag.ExitOnError)
+	distributeFlagSet = flag.NewFlagSet("distribute", flag.ExitOnError)
+	buildFlagSet      = flag.NewFlagSet("build", flag.ExitOnError)
+	signFlagSet       = flag.NewFlagSet("sign", flag.ExitOnError)
+	submitFlagSet     = flag.NewFlagSet("submit", flag.ExitOnError)
+	combineFlagSet    = flag.NewFlagSet("combine", flag.ExitOnError)
+	keystoreFlagSet   = flag.NewFlagSet("keystore", flag.ExitOnError)
+	sweepFlagSet      = flag.NewFlagSet("sweep", flag.ExitOnError)
+	airdropFlagSet    = flag.NewFlagSet("airdrop", flag.ExitOnError)
+	historyFlagSet    = flag.NewFlagSet("history", flag.ExitOnError)
+	watchFlagSet      = flag.NewFlagSet("watch", flag.ExitOnError)
 
 	LogDebug bool
 
 	APIAddress string
+	APITimeout time.Duration
+	// fatd is the client for the fatd API at APIAddress. All requests
+	// use ctx.
+	fatd *client.Client
+	ctx  = context.Background()
 
 	rpc = factom.RpcConfig
 
@@ -152,6 +313,8 @@ func init() {
 	flagVar(globalFlagSet, &LogDebug, "debug")
 
 	flagVar(globalFlagSet, &APIAddress, "apiaddress")
+	flagVar(globalFlagSet, &APITimeout, "apitimeout")
+	flagVar(globalFlagSet, &outputFormat, "format")
 
 	flagVar(globalFlagSet, &rpc.WalletServer, "w")
 	flagVar(globalFlagSet, &rpc.WalletTimeout, "wallettimeout")
@@ -167,22 +330,94 @@ func init() {
 	flagVar(globalFlagSet, &rpc.FactomdTLSCertFile, "factomdcert")
 	flagVar(globalFlagSet, &rpc.FactomdTLSEnable, "factomdtls")
 
+	flagVar(globalFlagSet, &keystorePath, "keystore")
+
 	flagVar(globalFlagSet, &tokenID, "tokenid")
 	flagVar(globalFlagSet, (*flagBytes32)(identity.ChainID), "identity")
 	flagVar(globalFlagSet, (*flagBytes32)(chainID), "chainid")
 
 	flagVar(issueFlagSet, (*ecpub)(&ECPub), "ecpub")
-	flagVar(issueFlagSet, (*SecretKey)(sk1.PrivateKey()), "sk1")
+	flagVar(issueFlagSet, sk1OrLabel{(*SecretKey)(sk1.PrivateKey())}, "sk1")
 	flagVar(issueFlagSet, &issuance.Type, "type")
 	flagVar(issueFlagSet, &issuance.Supply, "supply")
 	flagVar(issueFlagSet, &issuance.Symbol, "symbol")
 	flagVar(issueFlagSet, &issuance.Name, "name")
+	flagVar(issueFlagSet, &waitIssue, "wait")
+	flagVar(issueFlagSet, &waitTimeout, "timeout")
+	flagVar(issueFlagSet, &airdropCSVPath, "csv")
+	flagVar(issueFlagSet, &airdropStatePath, "state")
+	flagVar(issueFlagSet, &metadata, "metadata")
+	flagVar(issueFlagSet, &metadataFile, "metadata-file")
 
 	flagVar(transactFlagSet, (*ecpub)(&ECPub), "ecpub")
-	flagVar(transactFlagSet, (*SecretKey)(sk1.PrivateKey()), "sk1")
+	flagVar(transactFlagSet, sk1OrLabel{(*SecretKey)(sk1.PrivateKey())}, "sk1")
 	flagVar(transactFlagSet, &coinbaseAmount, "coinbase")
-	flagVar(transactFlagSet, (addressAmountMap)(transaction.Inputs), "input")
-	flagVar(transactFlagSet, (addressAmountMap)(transaction.Outputs), "output")
+	transactFlagSet.Var(addressAmountOrNFTokensMap{
+		transaction.Inputs, nfTransaction.Inputs},
+		"input", descriptions["nfinput"])
+	transactFlagSet.Var(addressAmountOrNFTokensMap{
+		transaction.Outputs, nfTransaction.Outputs},
+		"output", descriptions["nfoutput"])
+	flagVar(transactFlagSet, &tokenMetadataPath, "tokenmetadata")
+	flagVar(transactFlagSet, &metadata, "metadata")
+	flagVar(transactFlagSet, &metadataFile, "metadata-file")
+	flagVar(transactFlagSet, &fromWallet, "from-wallet")
+	flagVar(transactFlagSet, &inputStrategy, "strategy")
+	flagVar(transactFlagSet, (*flagFAAddresses)(&fromAddresses), "from")
+	flagVar(transactFlagSet, (*flagFAAddress)(&changeAddress), "change")
+
+	flagVar(distributeFlagSet, (*ecpub)(&ECPub), "ecpub")
+	flagVar(distributeFlagSet, sk1OrLabel{(*SecretKey)(sk1.PrivateKey())}, "sk1")
+	flagVar(distributeFlagSet, (*flagFAAddress)(&source), "source")
+	flagVar(distributeFlagSet, &snapshotHeight, "height")
+	flagVar(distributeFlagSet, &distributeTotal, "total")
+	flagVar(distributeFlagSet, &dryRun, "dryrun")
+
+	flagVar(sweepFlagSet, (*ecpub)(&ECPub), "ecpub")
+	flagVar(sweepFlagSet, (*flagFAAddresses)(&fromAddresses), "from")
+	flagVar(sweepFlagSet, (*flagFAAddress)(&sweepDestination), "to")
+	flagVar(sweepFlagSet, &dryRun, "dryrun")
+
+	flagVar(airdropFlagSet, (*ecpub)(&ECPub), "ecpub")
+	flagVar(airdropFlagSet, sk1OrLabel{(*SecretKey)(sk1.PrivateKey())}, "sk1")
+	flagVar(airdropFlagSet, &airdropCSVPath, "csv")
+	flagVar(airdropFlagSet, &airdropStatePath, "state")
+	flagVar(airdropFlagSet, &dryRun, "dryrun")
+
+	flagVar(historyFlagSet, (*flagFAAddress)(&historyAddress), "address")
+	flagVar(historyFlagSet, &historyParams.ToFrom, "tofrom")
+	flagVar(historyFlagSet, &historyLimit, "limit")
+	flagVar(historyFlagSet, &historyStart, "start")
+	flagVar(historyFlagSet, &historyParams.Cursor, "cursor")
+	flagVar(historyFlagSet, &historyParams.Order, "order")
+	flagVar(historyFlagSet, (*flagTime)(&historyStartTime), "starttime")
+	flagVar(historyFlagSet, (*flagTime)(&historyEndTime), "endtime")
+	flagVar(historyFlagSet, &historyParams.StartHeight, "startheight")
+	flagVar(historyFlagSet, &historyParams.EndHeight, "endheight")
+
+	flagVar(watchFlagSet, (*flagFAAddress)(&historyAddress), "address")
+	flagVar(watchFlagSet, &historyParams.ToFrom, "tofrom")
+	flagVar(watchFlagSet, &watchInterval, "interval")
+
+	flagVar(buildFlagSet, &coinbaseAmount, "coinbase")
+	flagVar(buildFlagSet, (addressAmountMap)(transaction.Inputs), "input")
+	flagVar(buildFlagSet, (addressAmountMap)(transaction.Outputs), "output")
+	flagVar(buildFlagSet, &txFilePath, "txfile")
+	flagVar(buildFlagSet, &partial, "partial")
+
+	flagVar(signFlagSet, sk1OrLabel{(*SecretKey)(sk1.PrivateKey())}, "sk1")
+	flagVar(signFlagSet, &keyFilePath, "keyfile")
+	flagVar(signFlagSet, &txFilePath, "txfile")
+	flagVar(signFlagSet, &partial, "partial")
+
+	flagVar(combineFlagSet, &txFilePath, "txfile")
+
+	keystoreFlagSet.StringVar(&keystoreKeyType, "type", "Fs",
+		`Type of key to generate: "Fs", "Es" or "sk1"`)
+	flagVar(keystoreFlagSet, &keystoreLabel, "label")
+
+	flagVar(submitFlagSet, (*ecpub)(&ECPub), "ecpub")
+	flagVar(submitFlagSet, &txFilePath, "txfile")
 
 	// Add flags for self installing the CLI completion tool
 	Completion.CLI.InstallName = "installcompletion"
@@ -211,6 +446,30 @@ func Parse() {
 		flagSet = issueFlagSet
 	case "transact":
 		flagSet = transactFlagSet
+	case "distribute":
+		flagSet = distributeFlagSet
+	case "sweep":
+		flagSet = sweepFlagSet
+	case "airdrop":
+		flagSet = airdropFlagSet
+	case "history":
+		flagSet = historyFlagSet
+	case "watch":
+		flagSet = watchFlagSet
+	case "build":
+		flagSet = buildFlagSet
+	case "sign":
+		flagSet = signFlagSet
+	case "submit":
+		flagSet = submitFlagSet
+	case "combine":
+		flagSet = combineFlagSet
+	case "keystore":
+		if len(args) > 0 {
+			keystoreSubCmd = args[0]
+			args = args[1:]
+		}
+		flagSet = keystoreFlagSet
 	case "balance":
 		if len(args) == 1 {
 			if err := address.UnmarshalJSON(
@@ -218,7 +477,32 @@ func Parse() {
 				return
 			}
 		}
-	case "gettransaction":
+	case "portfolio":
+		for _, arg := range args {
+			var adr factom.Address
+			if err := adr.UnmarshalJSON(
+				[]byte(fmt.Sprintf("%#v", arg))); err != nil {
+				portfolioAddresses = nil
+				return
+			}
+			portfolioAddresses = append(portfolioAddresses, adr)
+		}
+	case "nfbalance":
+		if len(args) == 1 {
+			if err := address.UnmarshalJSON(
+				[]byte(fmt.Sprintf("%#v", args[0]))); err != nil {
+				return
+			}
+		}
+	case "nftoken":
+		if len(args) == 1 {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return
+			}
+			nfTokenID = (*fat1.NFTokenID)(&id)
+		}
+	case "gettransaction", "explain":
 		if len(args) == 1 {
 			txHash = factom.NewBytes32(nil)
 			if err := txHash.UnmarshalJSON(
@@ -232,6 +516,12 @@ func Parse() {
 	if flagSet != nil {
 		flagSet.Parse(args)
 		flagSet.Visit(setFlagIsSet)
+		switch cmd {
+		case "combine":
+			partialFiles = flagSet.Args()
+		case "keystore":
+			keystoreArgs = flagSet.Args()
+		}
 	}
 
 	// Load options from environment variables if they haven't been
@@ -239,13 +529,16 @@ func Parse() {
 	loadFromEnv(&LogDebug, "debug")
 
 	loadFromEnv(&APIAddress, "apiaddress")
+	loadFromEnv(&APITimeout, "apitimeout")
+
+	loadFromEnv(&keystorePath, "keystore")
 
 	loadFromEnv(&rpc.WalletServer, "w")
-	loadFromEnv(&rpc.WalletTimeout, "walletdtimeout")
-	loadFromEnv(&rpc.WalletRPCUser, "factomduser")
-	loadFromEnv(&rpc.WalletRPCPassword, "factomdpassword")
-	loadFromEnv(&rpc.WalletTLSCertFile, "factomdcert")
-	loadFromEnv(&rpc.WalletTLSEnable, "factomdtls")
+	loadFromEnv(&rpc.WalletTimeout, "wallettimeout")
+	loadFromEnv(&rpc.WalletRPCUser, "walletuser")
+	loadFromEnv(&rpc.WalletRPCPassword, "walletpassword")
+	loadFromEnv(&rpc.WalletTLSCertFile, "walletcert")
+	loadFromEnv(&rpc.WalletTLSEnable, "wallettls")
 
 	loadFromEnv(&rpc.FactomdServer, "s")
 	loadFromEnv(&rpc.FactomdTimeout, "factomdtimeout")
@@ -253,12 +546,22 @@ func Parse() {
 	loadFromEnv(&rpc.FactomdRPCPassword, "factomdpassword")
 	loadFromEnv(&rpc.FactomdTLSCertFile, "factomdcert")
 	loadFromEnv(&rpc.FactomdTLSEnable, "factomdtls")
+
+	fatd = &client.Client{URL: APIAddress,
+		HTTPClient: &http.Client{Timeout: APITimeout}}
 }
 
 func Validate() error {
 	if len(cmd) == 0 {
 		return nil
 	}
+	switch outputFormat {
+	case outputTable:
+	case outputJSON, outputCSV:
+		infoOut = os.Stderr
+	default:
+		return fmt.Errorf("invalid -format: %v", outputFormat)
+	}
 	// Redact private data from debug output.
 	factomdRPCPassword := "\"\""
 	if len(rpc.FactomdRPCPassword) > 0 {
@@ -270,6 +573,7 @@ func Validate() error {
 	}
 
 	log.Debugf("-apiaddress      %#v", APIAddress)
+	log.Debugf("-apitimeout      %v ", APITimeout)
 	debugPrintln()
 
 	log.Debugf("-w             %#v", rpc.WalletServer)
@@ -286,20 +590,53 @@ func Validate() error {
 	log.Debugf("-factomdtimeout %v ", rpc.FactomdTimeout)
 	debugPrintln()
 
+	if len(sk1Label) > 0 {
+		if err := resolveSK1Label(); err != nil {
+			return err
+		}
+	}
+
 	// Validate cmd
 	switch cmd {
 	// These cmds require further flag validation.
 	case "issue":
 	case "balance":
+	case "nfbalance":
+	case "nftoken":
 	case "transact":
+	case "distribute":
+	case "sweep":
+	case "airdrop":
+	case "build":
 	case "gettransaction":
-	case "stats":
+	case "explain":
+	case "getstats":
 	case "getissuance":
+	case "history":
+	case "watch":
 	// These cmds do not require any flags.
 	case "listtokens":
 		fallthrough
 	case "help":
 		return nil
+	// These cmds do not require a token chain.
+	case "portfolio":
+		if len(portfolioAddresses) == 0 {
+			return fmt.Errorf("no addresses specified")
+		}
+		return nil
+	case "sign":
+		fallthrough
+	case "submit":
+		return requireFlags("txfile")
+	case "keystore":
+		return validateKeystoreCmd()
+	case "combine":
+		if len(partialFiles) == 0 {
+			return fmt.Errorf("no partially signed transaction " +
+				"files specified")
+		}
+		return requireFlags("txfile")
 
 	case "":
 		return fmt.Errorf("No command supplied")
@@ -316,16 +653,87 @@ func Validate() error {
 		if err := requireFlags("sk1", "supply", "ecpub"); err != nil {
 			return err
 		}
+		md, err := loadMetadata()
+		if err != nil {
+			return err
+		}
+		issuance.Metadata = md
 		if err := issuance.ValidData(); err != nil {
 			return err
 		}
+		if !waitIssue && (flagIsSet["csv"] || flagIsSet["timeout"]) {
+			return fmt.Errorf("-csv and -timeout require -wait")
+		}
+		if flagIsSet["csv"] && issuance.Type != fat.TypeFAT0 {
+			return fmt.Errorf("-csv is only supported for FAT-0 tokens")
+		}
+		if len(airdropStatePath) == 0 {
+			airdropStatePath = airdropCSVPath + ".state.json"
+		}
+		issuanceStatePath = chainID.String() + ".issuance.json"
 	case "balance":
 		zero := factom.Address{}
 		if address.RCDHash() == zero.RCDHash() {
 			return fmt.Errorf("no address specified")
 		}
+	case "nfbalance":
+		zero := factom.Address{}
+		if *address.RCDHash() == *zero.RCDHash() {
+			return fmt.Errorf("no address specified")
+		}
+	case "nftoken":
+		if nfTokenID == nil {
+			return fmt.Errorf("no NFTokenID specified")
+		}
 	case "transact":
+		md, err := loadMetadata()
+		if err != nil {
+			return err
+		}
+		transaction.Metadata = md
+		nfTransaction.Metadata = md
+		if len(nfTransaction.Inputs) > 0 ||
+			len(nfTransaction.Outputs) > 0 {
+			return validateTransactNF()
+		}
+		if flagIsSet["tokenmetadata"] {
+			return fmt.Errorf("-tokenmetadata requires FAT-1 " +
+				"NFTokenIDs in -input and -output")
+		}
 		required := []string{"output"}
+		if fromWallet {
+			if flagIsSet["input"] || flagIsSet["coinbase"] ||
+				flagIsSet["sk1"] {
+				return fmt.Errorf("cannot specify -input, " +
+					"-coinbase or -sk1 with -from-wallet")
+			}
+			switch inputStrategy {
+			case strategyFewest, strategyOldest:
+				if flagIsSet["from"] {
+					return fmt.Errorf("-from requires " +
+						`-strategy "addresses"`)
+				}
+			case strategyAddresses:
+				required = append(required, "from")
+			default:
+				return fmt.Errorf("invalid -strategy: %v",
+					inputStrategy)
+			}
+			if _, ok := transaction.Outputs[changeAddress]; ok &&
+				flagIsSet["change"] {
+				return fmt.Errorf("-change address may not " +
+					"be an output")
+			}
+			if err := requireFlags(required...); err != nil {
+				return err
+			}
+			break
+		}
+		if flagIsSet["strategy"] || flagIsSet["from"] ||
+			flagIsSet["change"] {
+			return fmt.Errorf("-strategy, -from and -change " +
+				"require -from-wallet")
+		}
 		if flagIsSet["coinbase"] || flagIsSet["sk1"] {
 			if flagIsSet["input"] {
 				return fmt.Errorf(
@@ -343,18 +751,162 @@ func Validate() error {
 		if err := requireFlags(required...); err != nil {
 			return err
 		}
+	case "distribute":
+		if err := requireFlags("height", "total"); err != nil {
+			return err
+		}
+		if flagIsSet["sk1"] == flagIsSet["source"] {
+			return fmt.Errorf("you must specify either -sk1 or -source")
+		}
+		if distributeTotal == 0 {
+			return fmt.Errorf("-total may not be zero")
+		}
+	case "sweep":
+		if err := requireFlags("to"); err != nil {
+			return err
+		}
+	case "airdrop":
+		if err := requireFlags("csv", "sk1"); err != nil {
+			return err
+		}
+		if len(airdropStatePath) == 0 {
+			airdropStatePath = airdropCSVPath + ".state.json"
+		}
+	case "build":
+		required := []string{"output", "txfile"}
+		if flagIsSet["coinbase"] {
+			if flagIsSet["input"] {
+				return fmt.Errorf(
+					"cannot specify -input with -coinbase")
+			}
+			if coinbaseAmount == 0 {
+				return fmt.Errorf("-coinbase amount may not be zero")
+			}
+			a := factom.Address{}
+			transaction.Inputs[*a.RCDHash()] = coinbaseAmount
+		} else {
+			required = append(required, "input")
+		}
+		if err := requireFlags(required...); err != nil {
+			return err
+		}
 	case "gettransaction":
 		if txHash == nil {
 			return fmt.Errorf("no transaction entry hash specified")
 		}
-	case "stats":
+	case "explain":
+		if txHash == nil {
+			return fmt.Errorf("no entry hash specified")
+		}
+	case "getstats":
 	case "getissuance":
+	case "history":
+		if flagIsSet["limit"] && historyLimit == 0 {
+			return fmt.Errorf("-limit must be greater than 0")
+		}
+		switch historyParams.Order {
+		case "asc", "desc":
+		default:
+			return fmt.Errorf(`-order must be "asc" or "desc"`)
+		}
+		if flagIsSet["starttime"] {
+			historyParams.StartTime = &historyStartTime
+		}
+		if flagIsSet["endtime"] {
+			historyParams.EndTime = &historyEndTime
+		}
+		if flagIsSet["starttime"] && flagIsSet["endtime"] &&
+			historyStartTime.After(historyEndTime.Time) {
+			return fmt.Errorf("-starttime is after -endtime")
+		}
+		if historyParams.EndHeight > 0 &&
+			historyParams.StartHeight > historyParams.EndHeight {
+			return fmt.Errorf("-startheight is above -endheight")
+		}
+		limit, start := uint(historyLimit), uint(historyStart)
+		historyParams.Limit, historyParams.Start = &limit, &start
+		return validateHistoryAddress()
+	case "watch":
+		if watchInterval <= 0 {
+			return fmt.Errorf("-interval must be greater than 0")
+		}
+		return validateHistoryAddress()
 	default:
 		return fmt.Errorf("Invalid command: %v", cmd)
 	}
 	return nil
 }
 
+// validateHistoryAddress sets the address of historyParams from -address and
+// validates -tofrom.
+func validateHistoryAddress() error {
+	switch historyParams.ToFrom {
+	case "", "to", "from":
+	default:
+		return fmt.Errorf(`-tofrom must be "to" or "from"`)
+	}
+	if !flagIsSet["address"] {
+		if len(historyParams.ToFrom) > 0 {
+			return fmt.Errorf("-tofrom requires -address")
+		}
+		return nil
+	}
+	adr := factom.NewAddress(&historyAddress)
+	historyParams.FactoidAddress = &adr
+	return nil
+}
+
+// loadMetadata returns the compacted JSON given by -metadata or the contents of
+// -metadata-file, or nil if neither was set.
+func loadMetadata() (json.RawMessage, error) {
+	if flagIsSet["metadata"] && flagIsSet["metadata-file"] {
+		return nil, fmt.Errorf(
+			"cannot specify both -metadata and -metadata-file")
+	}
+	data, name := []byte(metadata), "-metadata"
+	if flagIsSet["metadata-file"] {
+		var err error
+		if data, err = ioutil.ReadFile(metadataFile); err != nil {
+			return nil, err
+		}
+		name = metadataFile
+	} else if !flagIsSet["metadata"] {
+		return nil, nil
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("%v: empty", name)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, fmt.Errorf("%v: invalid JSON: %v", name, err)
+	}
+	return compact.Bytes(), nil
+}
+
+// validateTransactNF validates the transact flags for a FAT-1 transaction.
+func validateTransactNF() error {
+	if len(transaction.Inputs) > 0 || len(transaction.Outputs) > 0 {
+		return fmt.Errorf("cannot mix amounts and NFTokenIDs " +
+			"in -input and -output")
+	}
+	if fromWallet || flagIsSet["coinbase"] {
+		return fmt.Errorf("-from-wallet and -coinbase are not " +
+			"supported for FAT-1, use -input coinbase:[NFTokenIDs]")
+	}
+	if err := requireFlags("input", "output"); err != nil {
+		return err
+	}
+	if nfTransaction.IsCoinbase() {
+		if err := requireFlags("sk1"); err != nil {
+			return err
+		}
+	} else if flagIsSet["sk1"] || flagIsSet["tokenmetadata"] {
+		return fmt.Errorf("-sk1 and -tokenmetadata require " +
+			"-input coinbase:[NFTokenIDs]")
+	}
+	return nfTransaction.ValidData()
+}
+
 func requireTokenChain() error {
 	if !flagIsSet["chainid"] {
 		if !flagIsSet["tokenid"] || !flagIsSet["identity"] {
@@ -495,6 +1047,109 @@ func (sk *SecretKey) Set(data string) error {
 	return nil
 }
 
+// sk1OrLabel accepts either an sk1 key or the label or id1 key of an sk1 key
+// in the keystore, which is resolved by resolveSK1Label.
+type sk1OrLabel struct{ *SecretKey }
+
+func (s sk1OrLabel) Set(data string) error {
+	if strings.HasPrefix(data, "sk1") && len(data) == 53 {
+		return s.SecretKey.Set(data)
+	}
+	sk1Label = data
+	return nil
+}
+
+// resolveSK1Label sets sk1 to the key in the keystore for sk1Label.
+func resolveSK1Label() error {
+	pk, err := getKeystoreKey(sk1Label)
+	if err != nil {
+		return err
+	}
+	*sk1.PrivateKey() = *pk
+	return nil
+}
+
+func validateKeystoreCmd() error {
+	switch keystoreSubCmd {
+	case "list":
+	case "import":
+	case "generate":
+		switch keystoreKeyType {
+		case "Fs", "Es", "sk1":
+		default:
+			return fmt.Errorf("invalid -type: %v", keystoreKeyType)
+		}
+	case "label":
+		if len(keystoreArgs) != 1 {
+			return fmt.Errorf("no key specified")
+		}
+		return requireFlags("label")
+	default:
+		return fmt.Errorf("invalid keystore command: %#v", keystoreSubCmd)
+	}
+	return nil
+}
+
+type flagFAAddress factom.RCDHash
+
+// String returns the human readable Factoid Address.
+func (a *flagFAAddress) String() string {
+	if a == nil {
+		return ""
+	}
+	return (*factom.RCDHash)(a).String()
+}
+func (a *flagFAAddress) Set(data string) error {
+	return (*factom.RCDHash)(a).FromString(data)
+}
+
+// flagTime is a time flag which accepts a date such as 2006-01-02, an RFC3339
+// time or a Unix timestamp.
+type flagTime factom.Time
+
+func (t *flagTime) String() string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+func (t *flagTime) Set(data string) error {
+	if sec, err := strconv.ParseInt(data, 10, 64); err == nil {
+		t.Time = time.Unix(sec, 0)
+		return nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if tm, err := time.ParseInLocation(
+			layout, data, time.Local); err == nil {
+			t.Time = tm
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time: %v", data)
+}
+
+type flagFAAddresses []factom.RCDHash
+
+func (a *flagFAAddresses) String() string {
+	if a == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", []factom.RCDHash(*a))
+}
+func (a *flagFAAddresses) Set(data string) error {
+	var rcdHash factom.RCDHash
+	if err := rcdHash.FromString(data); err != nil {
+		return err
+	}
+	for _, r := range *a {
+		if r == rcdHash {
+			return fmt.Errorf("duplicate address: %v", rcdHash)
+		}
+	}
+	*a = append(*a, rcdHash)
+	return nil
+}
+
 type ecpub string
 
 // String returns the hex encoded data of b.
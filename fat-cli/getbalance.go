@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 
-	"github.com/Factom-Asset-Tokens/fatd/factom"
 	"github.com/Factom-Asset-Tokens/fatd/srv"
 )
 
@@ -14,11 +13,13 @@ func getBalance() error {
 		},
 		Address: &address,
 	}
-	var balance uint64
-	err := factom.Request(APIAddress, "get-balance", params, &balance)
+	balance, err := fatd.GetBalance(ctx, params)
 	if err != nil {
 		return err
 	}
-	fmt.Println(balance)
-	return nil
+	return printResult(balance, func() {
+		fmt.Println(balance)
+	}, func() [][]string {
+		return [][]string{{"balance"}, {csvValue(balance)}}
+	})
 }
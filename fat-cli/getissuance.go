@@ -3,26 +3,42 @@ package main
 import (
 	"fmt"
 
-	"github.com/Factom-Asset-Tokens/fatd/factom"
 	"github.com/Factom-Asset-Tokens/fatd/srv"
 )
 
 func getIssuance() error {
 	params := srv.ParamsToken{ChainID: chainID}
-	var issuance srv.ResultsGetIssuance
-	err := factom.Request(APIAddress, "get-issuance", params, &issuance)
+	issuance, err := fatd.GetIssuance(ctx, params)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Chain ID: %v\n", issuance.ChainID)
-	fmt.Printf("Token ID: %v\n", issuance.TokenID)
-	fmt.Printf("Issuer Identity Chain ID: %v\n", issuance.IssuerChainID)
-	fmt.Printf("Time of Issuance: %v\n", issuance.Timestamp.Time)
-	fmt.Printf("Issuance:\n")
-	fmt.Printf("\tType: %v\n", issuance.Issuance.Type)
-	fmt.Printf("\tSupply: %v\n", issuance.Issuance.Supply)
-	fmt.Printf("\tSymbol: %v\n", issuance.Issuance.Symbol)
-	fmt.Printf("\tName: %v\n", issuance.Issuance.Name)
-	fmt.Printf("\tMetadata: %v\n", issuance.Issuance.Metadata)
-	return nil
+	return printResult(issuance, func() {
+		fmt.Printf("Chain ID: %v\n", issuance.ChainID)
+		fmt.Printf("Token ID: %v\n", issuance.TokenID)
+		fmt.Printf("Issuer Identity Chain ID: %v\n", issuance.IssuerChainID)
+		fmt.Printf("Time of Issuance: %v\n", issuance.Timestamp.Time)
+		fmt.Printf("Issuance:\n")
+		fmt.Printf("\tType: %v\n", issuance.Issuance.Type)
+		fmt.Printf("\tSupply: %v\n", issuance.Issuance.Supply)
+		fmt.Printf("\tSymbol: %v\n", issuance.Issuance.Symbol)
+		fmt.Printf("\tName: %v\n", issuance.Issuance.Name)
+		if len(issuance.Issuance.Metadata) > 0 {
+			fmt.Printf("\tMetadata: %s\n", issuance.Issuance.Metadata)
+		}
+	}, func() [][]string {
+		return [][]string{
+			{"chainid", "tokenid", "issuerid", "entryhash",
+				"timestamp", "type", "supply", "symbol", "name",
+				"metadata"},
+			{csvValue(issuance.ChainID), issuance.TokenID,
+				csvValue(issuance.IssuerChainID),
+				csvValue(issuance.Hash),
+				csvValue(issuance.Timestamp),
+				issuance.Issuance.Type.String(),
+				csvValue(issuance.Issuance.Supply),
+				issuance.Issuance.Symbol,
+				issuance.Issuance.Name,
+				csvValue(issuance.Issuance.Metadata)},
+		}
+	})
 }
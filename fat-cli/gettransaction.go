@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
 	"github.com/Factom-Asset-Tokens/fatd/srv"
 )
 
@@ -14,32 +17,109 @@ func getTransaction() error {
 		},
 		Hash: txHash,
 	}
-	result := srv.ResultsGetTransaction{}
-	err := factom.Request(APIAddress, "get-transaction", params, &result)
+	result, err := fatd.GetTransaction(ctx, params)
 	if err != nil {
 		return err
 	}
-	transaction = result.Tx
+	data := result.Tx
+	header := []string{"entryhash", "timestamp", "direction", "address",
+		"amount"}
+	if err := json.Unmarshal(data, &transaction); err != nil {
+		// FAT-1 transactions list NFTokenIDs instead of amounts.
+		var tx fat1.Transaction
+		if err := json.Unmarshal(data, &tx); err != nil {
+			return err
+		}
+		tx.Hash = result.Hash
+		tx.Timestamp = result.Timestamp
+		return printResult(result, func() {
+			printNFTransaction(tx)
+		}, func() [][]string {
+			header[len(header)-1] = "nftokenids"
+			records := [][]string{header}
+			add := func(direction string,
+				tkns fat1.AddressNFTokensMap) {
+				for _, rcdHash := range sortedNFRCDHashes(tkns) {
+					records = append(records, []string{
+						csvValue(tx.Hash),
+						csvValue(tx.Timestamp), direction,
+						factom.NewAddress(&rcdHash).String(),
+						formatNFTokens(tkns[rcdHash])})
+				}
+			}
+			add("input", tx.Inputs)
+			add("output", tx.Outputs)
+			return records
+		})
+	}
 	transaction.Hash = result.Hash
 	transaction.Timestamp = result.Timestamp
+	return printResult(result, func() {
+		fmt.Printf("Transaction: \n")
+		fmt.Printf("\tHash: %v\n", transaction.Hash)
+		fmt.Printf("\tTimestamp: %v\n", transaction.Timestamp.Time)
+		fmt.Printf("\tInputs: \n")
+		for rcdHash, amount := range transaction.Inputs {
+			if transaction.IsCoinbase() {
+				fmt.Printf("\t\tCoinbase: %v\n", amount)
+				break
+			}
+			adr := factom.NewAddress(&rcdHash)
+			fmt.Printf("\t\t%v: %v\n", adr, amount)
+		}
+		fmt.Printf("\tOutputs: \n")
+		for rcdHash, amount := range transaction.Outputs {
+			adr := factom.NewAddress(&rcdHash)
+			fmt.Printf("\t\t%v: %v\n", adr, amount)
+		}
+		if len(transaction.Metadata) > 0 {
+			fmt.Printf("\tMetadata: %s\n", transaction.Metadata)
+		}
+		fmt.Printf("\n")
+	}, func() [][]string {
+		records := [][]string{header}
+		add := func(direction string, amounts fat0.AddressAmountMap) {
+			for _, rcdHash := range sortedRCDHashes(amounts) {
+				records = append(records, []string{
+					csvValue(transaction.Hash),
+					csvValue(transaction.Timestamp), direction,
+					factom.NewAddress(&rcdHash).String(),
+					csvValue(amounts[rcdHash])})
+			}
+		}
+		add("input", transaction.Inputs)
+		add("output", transaction.Outputs)
+		return records
+	})
+}
+
+func printNFTransaction(tx fat1.Transaction) {
 	fmt.Printf("Transaction: \n")
-	fmt.Printf("\tHash: %v\n", transaction.Hash)
-	fmt.Printf("\tTimestamp: %v\n", transaction.Timestamp.Time)
+	fmt.Printf("\tHash: %v\n", tx.Hash)
+	fmt.Printf("\tTimestamp: %v\n", tx.Timestamp.Time)
 	fmt.Printf("\tInputs: \n")
-	for rcdHash, amount := range transaction.Inputs {
-		if transaction.IsCoinbase() {
-			fmt.Printf("\t\tCoinbase: %v\n", amount)
+	for rcdHash, tkns := range tx.Inputs {
+		if tx.IsCoinbase() {
+			fmt.Printf("\t\tCoinbase: %v\n",
+				formatNFTokens(tkns))
 			break
 		}
 		adr := factom.NewAddress(&rcdHash)
-		fmt.Printf("\t\t%v: %v\n", adr, amount)
+		fmt.Printf("\t\t%v: %v\n", adr, formatNFTokens(tkns))
 	}
 	fmt.Printf("\tOutputs: \n")
-	for rcdHash, amount := range transaction.Inputs {
+	for rcdHash, tkns := range tx.Outputs {
 		adr := factom.NewAddress(&rcdHash)
-		fmt.Printf("\t\t%v: %v\n", adr, amount)
+		fmt.Printf("\t\t%v: %v\n", adr, formatNFTokens(tkns))
+	}
+	if len(tx.TokenMetadata) > 0 {
+		data, err := tx.TokenMetadata.MarshalJSON()
+		if err == nil {
+			fmt.Printf("\tToken Metadata: %s\n", data)
+		}
+	}
+	if len(tx.Metadata) > 0 {
+		fmt.Printf("\tMetadata: %s\n", tx.Metadata)
 	}
-	fmt.Printf("\tMetadata: %v\n", transaction.Metadata)
 	fmt.Printf("\n")
-	return nil
 }
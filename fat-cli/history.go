@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+// historyTransaction is a transaction returned by get-transactions along with
+// its effect on the queried address.
+type historyTransaction struct {
+	srv.ResultsGetTransaction
+	// Delta is the signed change in the balance of the queried address.
+	// For FAT-1 it is the NFTokenIDs received (+) or sent (-).
+	Delta string `json:"delta,omitempty"`
+}
+
+type historyResult struct {
+	Transactions []historyTransaction `json:"transactions"`
+	NextCursor   uint64               `json:"nextcursor,omitempty"`
+}
+
+// history prints a page of the transactions selected by historyParams.
+func history() error {
+	result, err := getTransactions(historyParams)
+	if err != nil {
+		return err
+	}
+	if outputFormat == outputJSON {
+		return printResult(result, nil, nil)
+	}
+	p := newTransactionPrinter()
+	for _, tx := range result.Transactions {
+		if err := p.print(tx); err != nil {
+			return err
+		}
+	}
+	if len(result.Transactions) == 0 {
+		fmt.Fprintln(infoOut, "No transactions")
+	}
+	if result.NextCursor > 0 {
+		fmt.Fprintf(infoOut, "More transactions: -cursor %v\n",
+			result.NextCursor)
+	}
+	return p.flush()
+}
+
+// watch prints new transactions selected by historyParams as fatd applies
+// them until interrupted.
+func watch() error {
+	params := historyParams
+	var limit uint = 100
+	params.Limit = &limit
+
+	// Start after the latest existing transaction.
+	latest := params
+	var one uint = 1
+	latest.Limit, latest.Order = &one, "desc"
+	result, err := getTransactions(latest)
+	if err != nil {
+		return err
+	}
+	var last *factom.Bytes32
+	if len(result.Transactions) > 0 {
+		last = result.Transactions[0].Hash
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	fmt.Fprintln(infoOut, "Watching for new transactions, "+
+		"press Ctrl+C to stop...")
+	p := newTransactionPrinter()
+	for {
+		params.Order, params.Hash, params.Cursor = "asc", last, 0
+		for {
+			result, err := getTransactions(params)
+			if err != nil {
+				return err
+			}
+			txs := result.Transactions
+			if params.Hash != nil && len(txs) > 0 &&
+				*txs[0].Hash == *params.Hash {
+				// Results begin with the last transaction
+				// already printed.
+				txs = txs[1:]
+			}
+			for _, tx := range txs {
+				if err := p.print(tx); err != nil {
+					return err
+				}
+				last = tx.Hash
+			}
+			if err := p.flush(); err != nil {
+				return err
+			}
+			if result.NextCursor == 0 {
+				break
+			}
+			params.Hash, params.Cursor = nil, result.NextCursor
+		}
+		select {
+		case <-interrupt:
+			return nil
+		case <-time.After(watchInterval):
+		}
+	}
+}
+
+// getTransactions calls get-transactions and computes the Delta of each
+// transaction for params.FactoidAddress. No transactions is not an error.
+func getTransactions(params srv.ParamsGetTransactions) (historyResult, error) {
+	params.ChainID = chainID
+	result, err := fatd.GetTransactionsPage(ctx, params)
+	if err != nil {
+		return historyResult{}, err
+	}
+	hist := historyResult{NextCursor: result.NextCursor,
+		Transactions: make([]historyTransaction, len(result.Transactions))}
+	for i, res := range result.Transactions {
+		tx := &hist.Transactions[i]
+		tx.Hash, tx.Timestamp = res.Hash, res.Timestamp
+		if tx0, err := res.FAT0(); err == nil {
+			tx.Tx = tx0
+			tx.Delta = fat0Delta(tx0, params.FactoidAddress)
+			continue
+		}
+		// FAT-1 transactions list NFTokenIDs instead of amounts.
+		tx1, err := res.FAT1()
+		if err != nil {
+			return hist, fmt.Errorf("%v: %v", res.Hash, err)
+		}
+		tx.Tx = tx1
+		tx.Delta = fat1Delta(tx1, params.FactoidAddress)
+	}
+	return hist, nil
+}
+
+func fat0Delta(tx fat0.Transaction, adr *factom.Address) string {
+	if adr == nil {
+		return ""
+	}
+	in, out := tx.Inputs[*adr.RCDHash()], tx.Outputs[*adr.RCDHash()]
+	if in > out {
+		return fmt.Sprintf("-%v", in-out)
+	}
+	return fmt.Sprintf("+%v", out-in)
+}
+
+func fat1Delta(tx fat1.Transaction, adr *factom.Address) string {
+	if adr == nil {
+		return ""
+	}
+	if tkns, ok := tx.Inputs[*adr.RCDHash()]; ok {
+		return "-" + formatNFTokens(tkns)
+	}
+	return "+" + formatNFTokens(tx.Outputs[*adr.RCDHash()])
+}
+
+// transactionPrinter prints one historyTransaction per line in the -output
+// format so that transactions may be printed as they arrive.
+type transactionPrinter struct {
+	csv    *csv.Writer
+	header bool
+}
+
+func newTransactionPrinter() *transactionPrinter {
+	return &transactionPrinter{csv: csv.NewWriter(os.Stdout)}
+}
+
+func (p *transactionPrinter) print(tx historyTransaction) error {
+	kind, amount := describeTransaction(tx)
+	switch outputFormat {
+	case outputJSON:
+		data, err := json.Marshal(tx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", data)
+	case outputCSV:
+		if !p.header {
+			p.header = true
+			if err := p.csv.Write([]string{"timestamp", "entryhash",
+				"type", "amount"}); err != nil {
+				return err
+			}
+		}
+		return p.csv.Write([]string{csvValue(tx.Timestamp),
+			csvValue(tx.Hash), kind, amount})
+	default:
+		fmt.Printf("%v  %v  %-8v  %v\n",
+			tx.Timestamp.Format("2006-01-02 15:04:05"),
+			tx.Hash, kind, amount)
+	}
+	return nil
+}
+
+func (p *transactionPrinter) flush() error {
+	p.csv.Flush()
+	return p.csv.Error()
+}
+
+// describeTransaction returns whether tx is a coinbase, burn or transfer
+// transaction and its Delta, or the total amount sent if there is no Delta.
+func describeTransaction(tx historyTransaction) (kind, amount string) {
+	coinbase := *coinbaseAddress.RCDHash()
+	amount = tx.Delta
+	kind = "transfer"
+	switch tx := tx.Tx.(type) {
+	case fat0.Transaction:
+		if len(amount) == 0 {
+			amount = fmt.Sprint(tx.Outputs.Sum())
+		}
+		if tx.IsCoinbase() {
+			kind = "coinbase"
+		} else if _, ok := tx.Outputs[coinbase]; ok {
+			kind = "burn"
+		}
+	case fat1.Transaction:
+		if len(amount) == 0 {
+			tkns := make(fat1.NFTokens)
+			for _, t := range tx.Outputs {
+				tkns.Append(t)
+			}
+			amount = formatNFTokens(tkns)
+		}
+		if tx.IsCoinbase() {
+			kind = "coinbase"
+		} else if _, ok := tx.Outputs[coinbase]; ok {
+			kind = "burn"
+		}
+	}
+	return
+}
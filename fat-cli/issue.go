@@ -1,7 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 
 	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
 	"github.com/Factom-Asset-Tokens/fatd/factom"
@@ -10,7 +13,7 @@ import (
 
 func issue() error {
 	eb := factom.EBlock{ChainID: chainID}
-	if err := eb.GetFirst(); err != nil {
+	if err := eb.GetFirst(ctx); err != nil {
 		if _, ok := err.(jrpc.Error); !ok {
 			return err
 		}
@@ -24,7 +27,7 @@ func issue() error {
 		}
 		// Get NameIDs for chain to check if this chain is valid.
 		first := eb.Entries[0]
-		if err := first.Get(); err != nil {
+		if err := first.Get(ctx); err != nil {
 			return err
 		}
 		if !fat.ValidTokenNameIDs(first.ExtIDs) {
@@ -35,40 +38,163 @@ func issue() error {
 	} else if !eb.IsPopulated() {
 		// Create the chain
 		e := factom.Entry{ExtIDs: fat.NameIDs(tokenID, identity.ChainID)}
-		txID, err := e.Create(ECPub)
+		txID, err := createEntry(&e)
 		if err != nil {
 			return err
 		}
-		fmt.Println("Created Token Chain")
-		fmt.Println("Token Chain ID: ", e.ChainID)
-		fmt.Println("First Entry Hash: ", e.Hash)
-		fmt.Println("Factom TxID: ", txID)
-		fmt.Println("You must wait until the Token Chain is created " +
-			"before issuing the token. \nThis can take up to 10 minutes.")
-		return nil
+		fmt.Fprintln(infoOut, "Created Token Chain")
+		fmt.Fprintln(infoOut, "Token Chain ID: ", e.ChainID)
+		fmt.Fprintln(infoOut, "First Entry Hash: ", e.Hash)
+		fmt.Fprintln(infoOut, "Factom TxID: ", txID)
+		recordSubmitted(e.ChainID, e.Hash, txID)
+		if !waitIssue {
+			fmt.Fprintln(infoOut, "You must wait until the Token Chain is "+
+				"created before issuing the token. \n"+
+				"This can take up to 10 minutes.")
+			return nil
+		}
+		fmt.Fprintln(infoOut, "Waiting for the Token Chain to be created. "+
+			"This can take up to 10 minutes...")
+		if err := pollUntil(func() (bool, error) {
+			return isChainCreated(chainID)
+		}); err != nil {
+			return err
+		}
 	}
-	if err := identity.Get(); err != nil {
+	if err := identity.Get(ctx); err != nil {
 		return err
 	}
 	if *identity.IDKey != *sk1.RCDHash() {
 		return fmt.Errorf("Invalid SK1 key for Identity%+v", identity)
 	}
 
-	// Create issuance entry
-	if err := issuance.MarshalEntry(); err != nil {
-		return err
+	var state issuanceState
+	if waitIssue {
+		issued, err := isIssued()
+		if err != nil {
+			return err
+		}
+		if issued {
+			fmt.Fprintln(infoOut, "Token already issued")
+			return issueAirdrop()
+		}
+		if state, err = loadIssuanceState(); err != nil {
+			return err
+		}
+	}
+
+	if state.Submitted {
+		fmt.Fprintln(infoOut, "Resuming from ", issuanceStatePath)
+		fmt.Fprintln(infoOut, "Issuance Entry Hash: ", state.Hash)
+	} else {
+		if len(state.ExtIDs) > 0 {
+			// This may have been submitted before the previous run
+			// was interrupted so the same entry must be used.
+			fmt.Fprintln(infoOut, "Resuming from ", issuanceStatePath)
+			issuance.Entry.Entry = factom.Entry{ChainID: chainID,
+				ExtIDs: state.ExtIDs, Content: state.Content}
+		} else {
+			// Create issuance entry
+			if err := issuance.MarshalEntry(); err != nil {
+				return err
+			}
+			issuance.Sign(sk1)
+			if err := issuance.Valid(identity.IDKey); err != nil {
+				return err
+			}
+			hash := issuance.ComputeHash()
+			state = issuanceState{Hash: &hash, txFile: txFile{
+				ChainID: chainID,
+				Content: issuance.Content,
+				ExtIDs:  issuance.ExtIDs,
+			}}
+			if err := saveIssuanceState(state); err != nil {
+				return err
+			}
+		}
+		txID, err := createEntry(&issuance.Entry.Entry)
+		if err != nil {
+			if waitIssue {
+				return fmt.Errorf("%v\nThe Issuance may already "+
+					"have been submitted. Run the same "+
+					"command again to resume.", err)
+			}
+			return err
+		}
+		fmt.Fprintln(infoOut, "Created Issuance Entry")
+		fmt.Fprintln(infoOut, "Token Chain ID: ", chainID)
+		fmt.Fprintln(infoOut, "Issuance Entry Hash: ", issuance.Hash)
+		fmt.Fprintln(infoOut, "Factom TxID: ", txID)
+		recordSubmitted(chainID, issuance.Hash, txID)
+		if !waitIssue {
+			return nil
+		}
+		state.Submitted = true
+		if err := saveIssuanceState(state); err != nil {
+			return err
+		}
 	}
-	issuance.Sign(sk1)
-	if err := issuance.Valid(identity.IDKey); err != nil {
+	fmt.Fprintln(infoOut, "Waiting for fatd to process the Issuance...")
+	if err := pollUntil(isIssued); err != nil {
 		return err
 	}
-	txID, err := issuance.Create(ECPub)
+	fmt.Fprintln(infoOut, "Token issued")
+	return issueAirdrop()
+}
+
+// issuanceState is the format of the file used to resume issue -wait. The
+// signed Issuance entry is saved before it is submitted so that if the command
+// is interrupted, the same entry is resubmitted or waited on rather than a
+// second Issuance being created.
+type issuanceState struct {
+	txFile
+	Hash      *factom.Bytes32 `json:"entryhash"`
+	Submitted bool            `json:"submitted"`
+}
+
+// loadIssuanceState returns the state saved at issuanceStatePath, or an empty
+// state if the file does not exist.
+func loadIssuanceState() (issuanceState, error) {
+	var state issuanceState
+	data, err := ioutil.ReadFile(issuanceStatePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
 	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("%v: %v", issuanceStatePath, err)
+	}
+	if state.ChainID == nil || *state.ChainID != *chainID ||
+		state.Hash == nil || len(state.ExtIDs) == 0 {
+		return state, fmt.Errorf("%v: does not match the token chain",
+			issuanceStatePath)
+	}
+	return state, nil
+}
+
+// saveIssuanceState atomically writes state to issuanceStatePath. Nothing is
+// saved without -wait since the Issuance is not resumed.
+func saveIssuanceState(state issuanceState) error {
+	if !waitIssue {
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := issuanceStatePath + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
 		return err
 	}
-	fmt.Println("Created Issuance Entry")
-	fmt.Println("Token Chain ID: ", chainID)
-	fmt.Println("Issuance Entry Hash: ", issuance.Hash)
-	fmt.Println("Factom TxID: ", txID)
-	return nil
+	return os.Rename(tmp, issuanceStatePath)
+}
+
+// issueAirdrop runs the initial coinbase airdrop if -csv was given.
+func issueAirdrop() error {
+	if !flagIsSet["csv"] {
+		return nil
+	}
+	return airdrop()
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadIssuanceState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fatd-issue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	issuanceStatePath = filepath.Join(dir, "issuance.json")
+	waitIssue = true
+	defer func() { issuanceStatePath, waitIssue = "", false }()
+
+	// Without a saved state an empty state is returned.
+	state, err := loadIssuanceState()
+	require.NoError(t, err)
+	assert.Equal(t, issuanceState{}, state)
+
+	hash := factom.Bytes32{1}
+	state = issuanceState{Hash: &hash, Submitted: true, txFile: txFile{
+		ChainID: chainID,
+		Content: factom.Bytes(`{"type":"FAT-0","supply":-1}`),
+		ExtIDs:  []factom.Bytes{factom.Bytes("sig")},
+	}}
+	require.NoError(t, saveIssuanceState(state))
+	loaded, err := loadIssuanceState()
+	require.NoError(t, err)
+	assert.Equal(t, state, loaded)
+
+	state.ChainID = &factom.Bytes32{2}
+	require.NoError(t, saveIssuanceState(state))
+	_, err = loadIssuanceState()
+	assert.EqualError(t, err, issuanceStatePath+
+		": does not match the token chain")
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Factom-Asset-Tokens/base58"
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/FactomProject/ed25519"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// The keystore is a local file of Fs, Es and sk1 keys which is encrypted with
+// NaCl secretbox using a key derived from a passphrase with scrypt. It is used
+// in place of factom-walletd when factom-walletd is not configured.
+
+const (
+	keystoreVersion = 1
+	// scrypt parameters recommended for interactive logins.
+	keystoreScryptN = 1 << 15
+	keystoreScryptR = 8
+	keystoreScryptP = 1
+
+	keystorePassphraseEnv = envNamePrefix + "KEYSTORE_PASSPHRASE"
+)
+
+// Key type prefixes of the human readable private and public keys.
+var (
+	fsPrefix  = []byte{0x64, 0x78}
+	esPrefix  = []byte{0x5d, 0xb6}
+	ecPrefix  = []byte{0x59, 0x2a}
+	sk1Prefix = []byte{0x4d, 0xb6, 0xc9}
+	id1Prefix = []byte{0x3f, 0xbe, 0xba}
+)
+
+// keystoreFile is the format of the encrypted keystore file.
+type keystoreFile struct {
+	Version int          `json:"version"`
+	KDF     string       `json:"kdf"`
+	N       int          `json:"n"`
+	R       int          `json:"r"`
+	P       int          `json:"p"`
+	Salt    factom.Bytes `json:"salt"`
+	Nonce   factom.Bytes `json:"nonce"`
+	Data    factom.Bytes `json:"data"`
+}
+
+// keystoreKey is a single labeled key in the keystore. Key is the human
+// readable Fs, Es or sk1 private key.
+type keystoreKey struct {
+	Label string `json:"label"`
+	Key   string `json:"key"`
+}
+
+type keystore struct {
+	Keys       []keystoreKey
+	passphrase []byte
+}
+
+// keystoreExists returns true if the keystore file exists.
+func keystoreExists() bool {
+	_, err := os.Stat(keystorePath)
+	return err == nil
+}
+
+// useKeystore returns true if keys should be resolved from the keystore rather
+// than factom-walletd. The keystore is used when it exists and
+// factom-walletd has not been configured by flag or environment variable.
+func useKeystore() bool {
+	if flagIsSet["w"] {
+		return false
+	}
+	if _, ok := os.LookupEnv(envName("w")); ok {
+		return false
+	}
+	return keystoreExists()
+}
+
+// loadKeystore prompts for the passphrase and decrypts the keystore. If the
+// keystore does not exist and create is true, then a new empty keystore is
+// returned, which is not saved until save is called.
+func loadKeystore(create bool) (*keystore, error) {
+	data, err := ioutil.ReadFile(keystorePath)
+	if os.IsNotExist(err) && create {
+		fmt.Fprintf(infoOut, "Creating new keystore: %v\n", keystorePath)
+		passphrase, err := readPassphrase(true)
+		if err != nil {
+			return nil, err
+		}
+		return &keystore{passphrase: passphrase}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f keystoreFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%v: %v", keystorePath, err)
+	}
+	if f.Version != keystoreVersion || f.KDF != "scrypt" {
+		return nil, fmt.Errorf("%v: unsupported keystore version",
+			keystorePath)
+	}
+	if len(f.Nonce) != 24 {
+		return nil, fmt.Errorf("%v: invalid nonce", keystorePath)
+	}
+
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveKeystoreKey(passphrase, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [24]byte
+	copy(nonce[:], f.Nonce)
+	plaintext, ok := secretbox.Open(nil, f.Data, &nonce, key)
+	if !ok {
+		return nil, fmt.Errorf("%v: incorrect passphrase", keystorePath)
+	}
+	ks := keystore{passphrase: passphrase}
+	if err := json.Unmarshal(plaintext, &ks.Keys); err != nil {
+		return nil, fmt.Errorf("%v: %v", keystorePath, err)
+	}
+	return &ks, nil
+}
+
+// save encrypts and writes the keystore to disk using a new salt and nonce.
+func (ks keystore) save() error {
+	plaintext, err := json.Marshal(ks.Keys)
+	if err != nil {
+		return err
+	}
+	f := keystoreFile{Version: keystoreVersion, KDF: "scrypt",
+		N: keystoreScryptN, R: keystoreScryptR, P: keystoreScryptP,
+		Salt: make(factom.Bytes, 32), Nonce: make(factom.Bytes, 24)}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	key, err := deriveKeystoreKey(ks.passphrase, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return err
+	}
+	var nonce [24]byte
+	copy(nonce[:], f.Nonce)
+	f.Data = secretbox.Seal(nil, plaintext, &nonce, key)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(keystorePath), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that the keystore is never left
+	// partially written.
+	tmpPath := keystorePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, keystorePath)
+}
+
+func deriveKeystoreKey(passphrase, salt []byte, n, r, p int) (*[32]byte, error) {
+	k, err := scrypt.Key(passphrase, salt, n, r, p, 32)
+	if err != nil {
+		return nil, fmt.Errorf("scrypt: %v", err)
+	}
+	var key [32]byte
+	copy(key[:], k)
+	return &key, nil
+}
+
+// readPassphrase returns the keystore passphrase from the environment or
+// prompts for it on the terminal. If confirm is true the passphrase must be
+// entered twice.
+func readPassphrase(confirm bool) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(keystorePassphraseEnv); ok {
+		return []byte(passphrase), nil
+	}
+	passphrase, err := readSecret("Keystore passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		again, err := readSecret("Confirm keystore passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if string(passphrase) != string(again) {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// readSecret prompts for a line of input. Input is not echoed if stdin is a
+// terminal.
+func readSecret(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		defer fmt.Fprintln(os.Stderr)
+		return terminal.ReadPassword(fd)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && len(line) == 0 {
+		return nil, err
+	}
+	return []byte(strings.TrimSpace(line)), nil
+}
+
+// parseKey parses a human readable Fs, Es or sk1 private key and returns the
+// private key and the human readable FA, EC or id1 public key.
+func parseKey(key string) (*factom.PrivateKey, string, error) {
+	var prefix []byte
+	switch {
+	case strings.HasPrefix(key, "Fs"):
+		prefix = fsPrefix
+	case strings.HasPrefix(key, "Es"):
+		prefix = esPrefix
+	case strings.HasPrefix(key, "sk1"):
+		prefix = sk1Prefix
+	default:
+		return nil, "", fmt.Errorf("unsupported key type")
+	}
+	seed, version, err := base58.CheckDecode(key, len(prefix))
+	if err != nil {
+		return nil, "", err
+	}
+	if string(version) != string(prefix) || len(seed) != 32 {
+		return nil, "", fmt.Errorf("invalid key")
+	}
+	pk := factom.NewPrivateKey(seed)
+	pk.PublicKey()
+	return pk, publicKey(pk, prefix), nil
+}
+
+// publicKey returns the human readable public key corresponding to pk which
+// has the private key type prefix.
+func publicKey(pk *factom.PrivateKey, prefix []byte) string {
+	switch string(prefix) {
+	case string(esPrefix):
+		return base58.CheckEncode(pk.PublicKey()[:], ecPrefix...)
+	case string(sk1Prefix):
+		var adr factom.Address
+		*adr.PrivateKey() = *pk
+		return base58.CheckEncode(adr.RCDHash()[:], id1Prefix...)
+	}
+	var adr factom.Address
+	*adr.PrivateKey() = *pk
+	return adr.String()
+}
+
+// find returns the private key for the given human readable FA, EC or id1
+// public key or label.
+func (ks keystore) find(pubOrLabel string) (*factom.PrivateKey, error) {
+	for _, k := range ks.Keys {
+		pk, pub, err := parseKey(k.Key)
+		if err != nil {
+			return nil, err
+		}
+		if pub == pubOrLabel || k.Label == pubOrLabel {
+			return pk, nil
+		}
+	}
+	return nil, fmt.Errorf("%v: key not found: %v", keystorePath, pubOrLabel)
+}
+
+// add adds key to the keystore with label. Labels must be unique.
+func (ks *keystore) add(key, label string) (string, error) {
+	_, pub, err := parseKey(key)
+	if err != nil {
+		return "", err
+	}
+	for _, k := range ks.Keys {
+		if k.Key == key {
+			return "", fmt.Errorf("key already exists: %v", pub)
+		}
+		if len(label) > 0 && k.Label == label {
+			return "", fmt.Errorf("label already exists: %v", label)
+		}
+	}
+	ks.Keys = append(ks.Keys, keystoreKey{Label: label, Key: key})
+	return pub, nil
+}
+
+// relabel sets the label of the key with the given human readable public key
+// or label and returns its public key. Labels must be unique, but a key may be
+// relabeled with its current label.
+func (ks *keystore) relabel(pubOrLabel, label string) (string, error) {
+	for i, k := range ks.Keys {
+		_, pub, err := parseKey(k.Key)
+		if err != nil {
+			return "", err
+		}
+		if pub != pubOrLabel && k.Label != pubOrLabel {
+			continue
+		}
+		for j, k := range ks.Keys {
+			if j != i && k.Label == label {
+				return "", fmt.Errorf("label already exists: %v",
+					label)
+			}
+		}
+		ks.Keys[i].Label = label
+		return pub, nil
+	}
+	return "", fmt.Errorf("key not found: %v", pubOrLabel)
+}
+
+// getKeystoreKey loads the keystore, if it has not already been loaded, and
+// returns the private key for the given public key or label.
+func getKeystoreKey(pubOrLabel string) (*factom.PrivateKey, error) {
+	if loadedKeystore == nil {
+		var err error
+		if loadedKeystore, err = loadKeystore(false); err != nil {
+			return nil, err
+		}
+	}
+	return loadedKeystore.find(pubOrLabel)
+}
+
+var loadedKeystore *keystore
+
+// getAddress populates the private key of adr from the keystore if it is in
+// use, otherwise from factom-walletd.
+func getAddress(adr *factom.Address) error {
+	if !useKeystore() {
+		return adr.Get(ctx)
+	}
+	pk, err := getKeystoreKey(adr.String())
+	if err != nil {
+		return err
+	}
+	*adr.PrivateKey() = *pk
+	return nil
+}
+
+// createEntry commits and reveals e, paying with ECPub. The entry is composed
+// locally with the Es key from the keystore if it is in use, otherwise
+// factom-walletd composes the entry.
+func createEntry(e *factom.Entry) (*factom.Bytes32, error) {
+	if !useKeystore() {
+		return e.Create(ctx, ECPub)
+	}
+	es, err := getKeystoreKey(ECPub)
+	if err != nil {
+		return nil, err
+	}
+	return e.CreateWithKey(ctx, es)
+}
+
+func keystoreCmd() error {
+	switch keystoreSubCmd {
+	case "list":
+		ks, err := loadKeystore(false)
+		if err != nil {
+			return err
+		}
+		keys := make([]keyResult, 0, len(ks.Keys))
+		for _, k := range ks.Keys {
+			_, pub, err := parseKey(k.Key)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, keyResult{pub, k.Label})
+		}
+		return printKeystoreKeys(keys...)
+	case "label":
+		ks, err := loadKeystore(false)
+		if err != nil {
+			return err
+		}
+		pub, err := ks.relabel(keystoreArgs[0], keystoreLabel)
+		if err != nil {
+			return err
+		}
+		if err := ks.save(); err != nil {
+			return err
+		}
+		return printKeystoreKeys(keyResult{pub, keystoreLabel})
+	}
+
+	var key string
+	switch keystoreSubCmd {
+	case "import":
+		// Read the key from stdin so that it does not end up in the
+		// shell history.
+		k, err := readSecret("Private key (Fs, Es or sk1): ")
+		if err != nil {
+			return err
+		}
+		key = string(k)
+	case "generate":
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		var prefix []byte
+		switch keystoreKeyType {
+		case "Fs":
+			prefix = fsPrefix
+		case "Es":
+			prefix = esPrefix
+		case "sk1":
+			prefix = sk1Prefix
+		}
+		key = base58.CheckEncode(private[:32], prefix...)
+	}
+
+	ks, err := loadKeystore(true)
+	if err != nil {
+		return err
+	}
+	pub, err := ks.add(key, keystoreLabel)
+	if err != nil {
+		return err
+	}
+	if err := ks.save(); err != nil {
+		return err
+	}
+	return printKeystoreKeys(keyResult{pub, keystoreLabel})
+}
+
+// keyResult is the public key and label of a key in the keystore.
+type keyResult struct {
+	Public string `json:"public"`
+	Label  string `json:"label"`
+}
+
+func printKeystoreKeys(keys ...keyResult) error {
+	return printResult(keys, func() {
+		for _, k := range keys {
+			fmt.Printf("%v\t%v\n", k.Public, k.Label)
+		}
+	}, func() [][]string {
+		records := [][]string{{"public", "label"}}
+		for _, k := range keys {
+			records = append(records, []string{k.Public, k.Label})
+		}
+		return records
+	})
+}
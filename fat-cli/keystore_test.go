@@ -0,0 +1,239 @@
+package main
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/base58"
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestKey returns a human readable private key with a random seed and the
+// given key type prefix.
+func newTestKey(t *testing.T, prefix []byte) (string, []byte) {
+	seed := make([]byte, 32)
+	_, err := rand.Read(seed)
+	require.NoError(t, err)
+	return base58.CheckEncode(seed, prefix...), seed
+}
+
+func TestKeystoreSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fatd-keystore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keystorePath = filepath.Join(dir, "keystore.json")
+	defer func() { keystorePath = "" }()
+	defer os.Unsetenv(keystorePassphraseEnv)
+
+	fs, _ := newTestKey(t, fsPrefix)
+	es, _ := newTestKey(t, esPrefix)
+	sk1, _ := newTestKey(t, sk1Prefix)
+	ks := keystore{Keys: []keystoreKey{
+		{Label: "fs", Key: fs}, {Label: "es", Key: es}, {Key: sk1}},
+		passphrase: []byte("passphrase")}
+	require.NoError(t, ks.save())
+
+	// The keys are not stored in plaintext.
+	data, err := ioutil.ReadFile(keystorePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), fs)
+
+	require.NoError(t, os.Setenv(keystorePassphraseEnv, "passphrase"))
+	loaded, err := loadKeystore(false)
+	require.NoError(t, err)
+	assert.Equal(t, ks, *loaded)
+
+	// Saving again uses a new salt and nonce.
+	require.NoError(t, loaded.save())
+	resaved, err := ioutil.ReadFile(keystorePath)
+	require.NoError(t, err)
+	assert.NotEqual(t, data, resaved)
+	loaded, err = loadKeystore(false)
+	require.NoError(t, err)
+	assert.Equal(t, ks, *loaded)
+
+	require.NoError(t, os.Setenv(keystorePassphraseEnv, "wrong"))
+	_, err = loadKeystore(false)
+	assert.EqualError(t, err, keystorePath+": incorrect passphrase")
+}
+
+func TestParseKey(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Prefix    []byte
+		PubPrefix []byte
+	}{{
+		Name:      "Fs",
+		Prefix:    fsPrefix,
+		PubPrefix: []byte{0x5f, 0xb1},
+	}, {
+		Name:      "Es",
+		Prefix:    esPrefix,
+		PubPrefix: ecPrefix,
+	}, {
+		Name:      "sk1",
+		Prefix:    sk1Prefix,
+		PubPrefix: id1Prefix,
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			key, seed := newTestKey(t, test.Prefix)
+			require.True(t, strings.HasPrefix(key, test.Name))
+			pk, pub, err := parseKey(key)
+			require.NoError(t, err)
+			assert.Equal(t, seed, pk[:32])
+			assert.Equal(t, pub, publicKey(pk, test.Prefix))
+
+			data, version, err := base58.CheckDecode(pub,
+				len(test.PubPrefix))
+			require.NoError(t, err)
+			assert.Equal(t, test.PubPrefix, version)
+			var adr factom.Address
+			*adr.PrivateKey() = *pk
+			switch test.Name {
+			case "Fs":
+				assert.Equal(t, adr.String(), pub)
+				assert.True(t, strings.HasPrefix(pub, "FA"))
+			case "Es":
+				assert.Equal(t, pk.PublicKey()[:], data)
+				assert.True(t, strings.HasPrefix(pub, "EC"))
+			case "sk1":
+				assert.Equal(t, adr.RCDHash()[:], data)
+				assert.True(t, strings.HasPrefix(pub, "id1"))
+			}
+		})
+	}
+
+	fs, _ := newTestKey(t, fsPrefix)
+	for _, test := range []struct {
+		Name  string
+		Key   string
+		Error string
+	}{{
+		Name:  "public key",
+		Key:   factom.Address{}.String(),
+		Error: "unsupported key type",
+	}, {
+		Name:  "empty",
+		Error: "unsupported key type",
+	}, {
+		Name:  "checksum",
+		Key:   corruptLastChar(fs),
+		Error: "checksum error",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			_, _, err := parseKey(test.Key)
+			assert.EqualError(t, err, test.Error)
+		})
+	}
+}
+
+// corruptLastChar returns key with its last base58 character changed.
+func corruptLastChar(key string) string {
+	c := byte('1')
+	if key[len(key)-1] == c {
+		c = '2'
+	}
+	return key[:len(key)-1] + string(c)
+}
+
+func TestKeystoreRelabel(t *testing.T) {
+	a, _ := newTestKey(t, fsPrefix)
+	b, _ := newTestKey(t, sk1Prefix)
+	_, pubA, err := parseKey(a)
+	require.NoError(t, err)
+	for _, test := range []struct {
+		Name       string
+		PubOrLabel string
+		Label      string
+		Labels     []string
+		Error      string
+	}{{
+		Name:       "by label",
+		PubOrLabel: "a",
+		Label:      "c",
+		Labels:     []string{"c", "b"},
+	}, {
+		Name:       "by public key",
+		PubOrLabel: pubA,
+		Label:      "c",
+		Labels:     []string{"c", "b"},
+	}, {
+		Name:       "same label",
+		PubOrLabel: "a",
+		Label:      "a",
+		Labels:     []string{"a", "b"},
+	}, {
+		Name:       "label exists",
+		PubOrLabel: "a",
+		Label:      "b",
+		Error:      "label already exists: b",
+	}, {
+		Name:       "not found",
+		PubOrLabel: "c",
+		Label:      "d",
+		Error:      "key not found: c",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			ks := keystore{Keys: []keystoreKey{
+				{Label: "a", Key: a}, {Label: "b", Key: b}}}
+			pub, err := ks.relabel(test.PubOrLabel, test.Label)
+			if len(test.Error) > 0 {
+				assert.EqualError(t, err, test.Error)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, pubA, pub)
+			for i, label := range test.Labels {
+				assert.Equal(t, label, ks.Keys[i].Label)
+			}
+		})
+	}
+}
+
+func TestResolveSK1Label(t *testing.T) {
+	key, _ := newTestKey(t, sk1Prefix)
+	fs, _ := newTestKey(t, fsPrefix)
+	pk, id1, err := parseKey(key)
+	require.NoError(t, err)
+	loadedKeystore = &keystore{Keys: []keystoreKey{
+		{Label: "fs", Key: fs}, {Label: "issuer", Key: key}}}
+	defer func() {
+		loadedKeystore = nil
+		sk1Label = ""
+		sk1 = factom.Address{}
+	}()
+
+	for _, test := range []struct {
+		Name  string
+		Label string
+		Error string
+	}{{
+		Name:  "label",
+		Label: "issuer",
+	}, {
+		Name:  "id1 key",
+		Label: id1,
+	}, {
+		Name:  "not found",
+		Label: "unknown",
+		Error: keystorePath + ": key not found: unknown",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			sk1 = factom.Address{}
+			sk1Label = test.Label
+			err := resolveSK1Label()
+			if len(test.Error) > 0 {
+				assert.EqualError(t, err, test.Error)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, *pk, *sk1.PrivateKey())
+		})
+	}
+}
@@ -2,21 +2,26 @@ package main
 
 import (
 	"fmt"
-
-	"github.com/Factom-Asset-Tokens/fatd/factom"
-	"github.com/Factom-Asset-Tokens/fatd/srv"
 )
 
 func listTokens() error {
-	var tkns []srv.ParamsToken
-	err := factom.Request(APIAddress, "get-daemon-tokens", nil, &tkns)
+	tkns, err := fatd.GetDaemonTokens(ctx)
 	if err != nil {
 		return err
 	}
-	for _, tkn := range tkns {
-		fmt.Printf("Chain ID: %v\n", tkn.ChainID)
-		fmt.Printf("Token ID: %v\n", tkn.TokenID)
-		fmt.Printf("Issuer Identity Chain ID: %v\n\n", tkn.IssuerChainID)
-	}
-	return nil
+	return printResult(tkns, func() {
+		for _, tkn := range tkns {
+			fmt.Printf("Chain ID: %v\n", tkn.ChainID)
+			fmt.Printf("Token ID: %v\n", tkn.TokenID)
+			fmt.Printf("Issuer Identity Chain ID: %v\n\n",
+				tkn.IssuerChainID)
+		}
+	}, func() [][]string {
+		records := [][]string{{"chainid", "tokenid", "issuerid"}}
+		for _, tkn := range tkns {
+			records = append(records, []string{csvValue(tkn.ChainID),
+				tkn.TokenID, csvValue(tkn.IssuerChainID)})
+		}
+		return records
+	})
 }
@@ -11,59 +11,100 @@ func _main() (ret int) {
 	// Attempt to run the completion program.
 	if Completion.Complete() {
 		// The completion program ran, so just return.
-		return 0
+		return exitOK
 	}
 	if err := Validate(); err != nil {
-		fmt.Println(err)
-		return 1
+		fmt.Fprintln(os.Stderr, err)
+		return exitInvalidParams
 	}
 
+	var err error
 	switch cmd {
 	case "issue":
-		if err := issue(); err != nil {
-			fmt.Println(err)
-			return 1
-		}
+		err = issue()
 	case "transact":
-		if err := transact(); err != nil {
-			fmt.Println(err)
-			return 1
-		}
+		err = transact()
+	case "distribute":
+		err = distribute()
+	case "sweep":
+		err = sweep()
+	case "airdrop":
+		err = airdrop()
+	case "build":
+		err = build()
+	case "sign":
+		err = sign()
+	case "keystore":
+		err = keystoreCmd()
+	case "combine":
+		err = combine()
+	case "submit":
+		err = submitTx()
 	case "balance":
-		if err := getBalance(); err != nil {
-			fmt.Println(err)
-			return 1
-		}
+		err = getBalance()
+	case "nfbalance":
+		err = getNFBalance()
+	case "nftoken":
+		err = getNFToken()
 	case "getissuance":
-		if err := getIssuance(); err != nil {
-			fmt.Println(err)
-			return 1
-		}
+		err = getIssuance()
 	case "getstats":
-		if err := getStats(); err != nil {
-			fmt.Println(err)
-			return 1
-		}
+		err = getStats()
+	case "portfolio":
+		err = portfolio()
 	case "listtokens":
-		if err := listTokens(); err != nil {
-			fmt.Println(err)
-			return 1
-		}
+		err = listTokens()
 	case "gettransaction":
-		if err := getTransaction(); err != nil {
-			fmt.Println(err)
-			return 1
-		}
+		err = getTransaction()
+	case "explain":
+		err = explain()
+	case "history":
+		err = history()
+	case "watch":
+		err = watch()
 	default:
 		usage()
+		return exitOK
 	}
 
-	return 0
+	switch cmd {
+	case "issue", "transact", "distribute", "sweep", "airdrop", "submit":
+		// Print any entries that were submitted, even if the command
+		// later failed.
+		if err := printSubmitted(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	return exitOK
 }
 
 func usage() {
 	fmt.Println(`usage: fat-cli CHAIN_FLAGS [GLOBAL_FLAGS] COMMAND COMMAND_FLAGS
         CHAIN_FLAGS: -chainid OR -token AND -identity
-        GLOBAL_FLAGS: -s, -w, -apiaddress, ...
-        COMMAND: balance OR issue OR transact`)
+        GLOBAL_FLAGS: -s, -w, -apiaddress, -format table|json|csv, ...
+        COMMAND: balance OR issue OR transact OR distribute OR sweep OR airdrop
+usage: fat-cli [GLOBAL_FLAGS] portfolio ADDRESS...
+usage: fat-cli CHAIN_FLAGS [GLOBAL_FLAGS] history [-address ADDRESS [-tofrom to|from]] [-limit N] [-cursor N] [-starttime TIME] [-endtime TIME]
+usage: fat-cli CHAIN_FLAGS [GLOBAL_FLAGS] watch [-address ADDRESS [-tofrom to|from]] [-interval DURATION]
+usage: fat-cli CHAIN_FLAGS [GLOBAL_FLAGS] explain ENTRYHASH
+usage: fat-cli CHAIN_FLAGS [GLOBAL_FLAGS] nfbalance ADDRESS
+usage: fat-cli CHAIN_FLAGS [GLOBAL_FLAGS] nftoken NFTOKENID
+usage: fat-cli CHAIN_FLAGS [GLOBAL_FLAGS] transact -input ADDRESS:[NFTOKENIDS] -output ADDRESS:[NFTOKENIDS]
+usage: fat-cli CHAIN_FLAGS [GLOBAL_FLAGS] airdrop -csv FILE -sk1 SK1 [-state FILE] [-dryrun]
+usage: fat-cli CHAIN_FLAGS [GLOBAL_FLAGS] build -txfile FILE COMMAND_FLAGS
+usage: fat-cli [GLOBAL_FLAGS] sign OR submit -txfile FILE COMMAND_FLAGS
+usage: fat-cli [GLOBAL_FLAGS] combine -txfile FILE PARTIAL_FILE...
+usage: fat-cli [-keystore FILE] keystore import OR generate OR list [-label LABEL] [-type Fs|Es|sk1]
+usage: fat-cli [-keystore FILE] keystore label -label LABEL PUBLIC_KEY_OR_LABEL
+
+Exit codes:
+        0 success
+        1 error
+        2 invalid flags, arguments or params
+        3 token, transaction or entry not found
+        4 unable to connect to fatd, factomd or factom-walletd`)
 }
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+// addressAmountOrNFTokensMap is the flag type for the transact -input and
+// -output flags. Each value is either ADDRESS:AMOUNT for a FAT-0 transaction
+// or ADDRESS:[NFTokenIDs] for a FAT-1 transaction, where NFTokenIDs is a comma
+// separated list of NFTokenIDs and inclusive MIN-MAX ranges, e.g. [1,5-100].
+type addressAmountOrNFTokensMap struct {
+	amounts fat0.AddressAmountMap
+	tkns    fat1.AddressNFTokensMap
+}
+
+func (m addressAmountOrNFTokensMap) Set(data string) error {
+	s := strings.SplitN(data, ":", 2)
+	if len(s) != 2 || !strings.HasPrefix(s[1], "[") {
+		return addressAmountMap(m.amounts).Set(data)
+	}
+	adr := factom.Address{}
+	if s[0] != "coinbase" {
+		if err := adr.UnmarshalJSON(
+			[]byte(fmt.Sprintf("%#v", s[0]))); err != nil {
+			return fmt.Errorf("invalid address: %v", err)
+		}
+	}
+	if _, ok := m.tkns[*adr.RCDHash()]; ok {
+		return fmt.Errorf("duplicate address: %v", adr.RCDHash())
+	}
+	tkns, err := parseNFTokens(s[1])
+	if err != nil {
+		return fmt.Errorf("invalid NFTokenIDs: %v", err)
+	}
+	m.tkns[*adr.RCDHash()] = tkns
+	return nil
+}
+func (m addressAmountOrNFTokensMap) String() string {
+	if len(m.tkns) > 0 {
+		return fmt.Sprintf("%v", m.tkns)
+	}
+	return fmt.Sprintf("%v", m.amounts)
+}
+
+// parseNFTokens parses a list of NFTokenIDs and NFTokenIDRanges such as
+// [1,5-100].
+func parseNFTokens(data string) (fat1.NFTokens, error) {
+	if !strings.HasPrefix(data, "[") || !strings.HasSuffix(data, "]") {
+		return nil, fmt.Errorf("must be enclosed in []")
+	}
+	data = strings.TrimSpace(data[1 : len(data)-1])
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty")
+	}
+	var ids []fat1.NFTokensSetter
+	for _, idStr := range strings.Split(data, ",") {
+		minMax := strings.SplitN(strings.TrimSpace(idStr), "-", 2)
+		min, err := strconv.ParseUint(minMax[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if len(minMax) == 1 {
+			ids = append(ids, fat1.NFTokenID(min))
+			continue
+		}
+		max, err := strconv.ParseUint(minMax[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		idRange := fat1.NewNFTokenIDRange(fat1.NFTokenID(min),
+			fat1.NFTokenID(max))
+		if err := idRange.Valid(); err != nil {
+			return nil, err
+		}
+		ids = append(ids, idRange)
+	}
+	return fat1.NewNFTokens(ids...)
+}
+
+// readTokenMetadata loads the NFTokenIDMetadataMap for a FAT-1 coinbase
+// transaction from the JSON file at path. The file uses the same format as the
+// "tokenmetadata" field of a FAT-1 transaction:
+//
+//	[{"ids": [1, {"min": 5, "max": 100}], "metadata": {...}}, ...]
+func readTokenMetadata(path string) (fat1.NFTokenIDMetadataMap, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m fat1.NFTokenIDMetadataMap
+	if err := m.UnmarshalJSON(data); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return m, nil
+}
+
+// transactNF signs and submits the FAT-1 nfTransaction.
+func transactNF() error {
+	inputAddresses := make([]factom.Address, 0, len(nfTransaction.Inputs))
+	if nfTransaction.IsCoinbase() {
+		if err := verifySK1(); err != nil {
+			return err
+		}
+		inputAddresses = append(inputAddresses, sk1)
+		if flagIsSet["tokenmetadata"] {
+			m, err := readTokenMetadata(tokenMetadataPath)
+			if err != nil {
+				return err
+			}
+			if err := m.IsSubsetOf(
+				nfTransaction.Inputs[*coinbaseAddress.RCDHash()]); err != nil {
+				return fmt.Errorf("%v: %v", tokenMetadataPath, err)
+			}
+			nfTransaction.TokenMetadata = m
+		}
+	} else {
+		for rcd := range nfTransaction.Inputs {
+			adr := factom.NewAddress(&rcd)
+			if err := getAddress(&adr); err != nil {
+				return err
+			}
+			inputAddresses = append(inputAddresses, adr)
+		}
+	}
+	nfTransaction.ChainID = chainID
+	if err := nfTransaction.MarshalEntry(); err != nil {
+		return err
+	}
+	nfTransaction.Sign(inputAddresses...)
+	if err := nfTransaction.Valid(sk1.RCDHash()); err != nil {
+		return err
+	}
+	txID, err := submit(&nfTransaction.Entry.Entry)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(infoOut, "Created Transaction Entry")
+	fmt.Fprintln(infoOut, "Token Chain ID: ", chainID)
+	fmt.Fprintln(infoOut, "Transaction Entry Hash: ", nfTransaction.Hash)
+	fmt.Fprintln(infoOut, "Factom TxID: ", txID)
+	recordSubmitted(chainID, nfTransaction.Hash, txID)
+	return nil
+}
+
+func getNFToken() error {
+	params := srv.ParamsGetNFToken{
+		ParamsToken:        srv.ParamsToken{ChainID: chainID},
+		NonFungibleTokenID: nfTokenID,
+	}
+	result, err := fatd.GetNFToken(ctx, params)
+	if err != nil {
+		return err
+	}
+	return printResult(result, func() {
+		fmt.Printf("NFTokenID: %v\n", result.NFTokenID)
+		fmt.Printf("Owner: %v\n", result.Owner)
+		if len(result.Metadata) > 0 {
+			fmt.Printf("Metadata: %s\n", result.Metadata)
+		}
+	}, func() [][]string {
+		return [][]string{{"id", "owner", "metadata"},
+			{csvValue(result.NFTokenID), result.Owner.String(),
+				csvValue(result.Metadata)}}
+	})
+}
+
+func getNFBalance() error {
+	params := srv.ParamsGetNFBalance{
+		ParamsToken: srv.ParamsToken{ChainID: chainID},
+		Address:     &address,
+	}
+	tkns, err := fatd.GetNFBalance(ctx, params)
+	if err != nil {
+		return err
+	}
+	return printResult(json.RawMessage(formatNFTokens(tkns)), func() {
+		fmt.Printf("Balance: %v\n", len(tkns))
+		fmt.Printf("NFTokenIDs: %v\n", formatNFTokens(tkns))
+	}, func() [][]string {
+		return [][]string{{"balance", "nftokenids"},
+			{csvValue(len(tkns)), formatNFTokens(tkns)}}
+	})
+}
+
+// sortedNFRCDHashes returns the addresses of m in a deterministic order.
+func sortedNFRCDHashes(m fat1.AddressNFTokensMap) []factom.RCDHash {
+	rcdHashes := make([]factom.RCDHash, 0, len(m))
+	for rcdHash := range m {
+		rcdHashes = append(rcdHashes, rcdHash)
+	}
+	sort.Slice(rcdHashes, func(i, j int) bool {
+		return bytes.Compare(rcdHashes[i][:], rcdHashes[j][:]) < 0
+	})
+	return rcdHashes
+}
+
+// formatNFTokens returns the compact JSON representation of tkns, e.g.
+// [1,{"min":5,"max":100}].
+func formatNFTokens(tkns fat1.NFTokens) string {
+	if len(tkns) == 0 {
+		return "[]"
+	}
+	data, err := tkns.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("%v", tkns.Slice())
+	}
+	return string(data)
+}
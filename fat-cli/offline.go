@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+)
+
+// The RCD/signature pair for each input of a partially signed transaction is
+// at the index of the input's RCDHash in sortedRCDHashes(tx.Inputs) so that
+// every party agrees on where to put their signature.
+
+// txFile is the format of the file that is passed between the build, sign and
+// submit commands. The Content is hex encoded so that it is preserved exactly
+// as it was signed.
+type txFile struct {
+	ChainID *factom.Bytes32 `json:"chainid"`
+	Content factom.Bytes    `json:"content"`
+	ExtIDs  []factom.Bytes  `json:"extids,omitempty"`
+}
+
+// readTxFile returns the Transaction saved in the file at path.
+func readTxFile(path string) (fat0.Transaction, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fat0.Transaction{}, err
+	}
+	var f txFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fat0.Transaction{}, fmt.Errorf("%v: %v", path, err)
+	}
+	if f.ChainID == nil || len(f.Content) == 0 {
+		return fat0.Transaction{}, fmt.Errorf(
+			"%v: missing chainid or content", path)
+	}
+	tx := fat0.NewTransaction(factom.Entry{
+		ChainID: f.ChainID, Content: f.Content, ExtIDs: f.ExtIDs})
+	if err := tx.UnmarshalEntry(); err != nil {
+		return fat0.Transaction{}, fmt.Errorf("%v: %v", path, err)
+	}
+	return tx, nil
+}
+
+// writeTxFile saves the ChainID, Content and ExtIDs of tx to the file at path.
+func writeTxFile(path string, tx fat0.Transaction) error {
+	f := txFile{ChainID: tx.ChainID, Content: tx.Content, ExtIDs: tx.ExtIDs}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// printTx prints a summary of tx so that it can be reviewed before it is
+// signed or submitted.
+func printTx(tx fat0.Transaction) {
+	fmt.Fprintln(infoOut, "Token Chain ID: ", tx.ChainID)
+	for _, rcdHash := range sortedRCDHashes(tx.Inputs) {
+		fmt.Fprintf(infoOut, "Input: %v: %v\n", rcdHash, tx.Inputs[rcdHash])
+	}
+	for _, rcdHash := range sortedRCDHashes(tx.Outputs) {
+		fmt.Fprintf(infoOut, "Output: %v: %v\n", rcdHash, tx.Outputs[rcdHash])
+	}
+}
+
+func build() error {
+	if err := transaction.MarshalEntry(); err != nil {
+		return err
+	}
+	if partial {
+		// Fix the timestamp salt now so that all parties sign the
+		// same message.
+		transaction.InitRCDSigs(len(transaction.Inputs))
+	}
+	if err := writeTxFile(txFilePath, transaction); err != nil {
+		return err
+	}
+	printTx(transaction)
+	fmt.Fprintln(infoOut, "Saved unsigned Transaction to ", txFilePath)
+	return nil
+}
+
+func sign() error {
+	tx, err := readTxFile(txFilePath)
+	if err != nil {
+		return err
+	}
+	printTx(tx)
+	if partial && tx.NumRCDSigPairs() != len(tx.Inputs) {
+		return fmt.Errorf("%v: not built with -partial", txFilePath)
+	}
+
+	var keys map[factom.RCDHash]factom.Address
+	if flagIsSet["keyfile"] {
+		if keys, err = readKeyFile(keyFilePath); err != nil {
+			return err
+		}
+	}
+	var idKey *factom.RCDHash
+	signingSet := make([]factom.Address, 0, len(tx.Inputs))
+	if tx.IsCoinbase() {
+		if !flagIsSet["sk1"] {
+			return fmt.Errorf("-sk1 is required to sign a " +
+				"coinbase transaction")
+		}
+		idKey = sk1.RCDHash()
+		signingSet = append(signingSet, sk1)
+	} else {
+		for _, rcdHash := range sortedRCDHashes(tx.Inputs) {
+			adr, ok := keys[rcdHash]
+			if !ok {
+				if flagIsSet["keyfile"] {
+					if partial {
+						// Another party will sign for
+						// this input.
+						signingSet = append(signingSet,
+							factom.Address{})
+						continue
+					}
+					return fmt.Errorf("%v: no key for %v",
+						keyFilePath, rcdHash)
+				}
+				rcdHash := rcdHash
+				adr = factom.NewAddress(&rcdHash)
+				if err := getAddress(&adr); err != nil {
+					if partial {
+						fmt.Fprintf(infoOut, "Skipping %v: %v\n",
+							rcdHash, err)
+						signingSet = append(signingSet,
+							factom.Address{})
+						continue
+					}
+					return err
+				}
+			}
+			signingSet = append(signingSet, adr)
+		}
+	}
+
+	if !partial {
+		tx.Sign(signingSet...)
+		if err := tx.Valid(idKey); err != nil {
+			return err
+		}
+		if err := writeTxFile(txFilePath, tx); err != nil {
+			return err
+		}
+		fmt.Fprintln(infoOut, "Saved signed Transaction to ", txFilePath)
+		return nil
+	}
+
+	var zero factom.Address
+	numSigned := 0
+	for rcdSigID, adr := range signingSet {
+		if *adr.PrivateKey() != *zero.PrivateKey() {
+			if err := tx.SignRCDSigID(rcdSigID, adr); err != nil {
+				return err
+			}
+		}
+		if tx.IsRCDSigSet(rcdSigID) {
+			numSigned++
+		}
+	}
+	if err := writeTxFile(txFilePath, tx); err != nil {
+		return err
+	}
+	fmt.Fprintf(infoOut, "Saved partially signed Transaction to %v, "+
+		"%v of %v inputs signed\n", txFilePath, numSigned, len(tx.Inputs))
+	return nil
+}
+
+func combine() error {
+	txs := make([]fat0.Transaction, len(partialFiles))
+	partials := make([]fat.Entry, len(partialFiles))
+	for i, path := range partialFiles {
+		var err error
+		if txs[i], err = readTxFile(path); err != nil {
+			return err
+		}
+		partials[i] = txs[i].Entry
+	}
+	tx := txs[0]
+	printTx(tx)
+	if err := tx.CombineRCDSigs(partials[1:]...); err != nil {
+		return err
+	}
+	if !tx.IsCoinbase() && !tx.ValidRCDs() {
+		return fmt.Errorf("invalid RCDs")
+	}
+	if err := writeTxFile(txFilePath, tx); err != nil {
+		return err
+	}
+	fmt.Fprintln(infoOut, "Saved signed Transaction to ", txFilePath)
+	return nil
+}
+
+// readKeyFile returns the addresses for the Fs private Factoid addresses and
+// sk1 keys in the file at path, one per line. Empty lines and lines starting
+// with # are ignored.
+func readKeyFile(path string) (map[factom.RCDHash]factom.Address, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys := make(map[factom.RCDHash]factom.Address)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		key := strings.TrimSpace(scanner.Text())
+		if len(key) == 0 || key[0] == '#' {
+			continue
+		}
+		var adr factom.Address
+		if strings.HasPrefix(key, "sk1") {
+			err = (*SecretKey)(adr.PrivateKey()).Set(key)
+		} else {
+			err = adr.FromString(key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", path, line, err)
+		}
+		keys[*adr.RCDHash()] = adr
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func submitTx() error {
+	tx, err := readTxFile(txFilePath)
+	if err != nil {
+		return err
+	}
+	printTx(tx)
+
+	// The timestamp salt must be within 12 hours of when the entry is
+	// submitted.
+	tx.SetTimestampToNow()
+	if err := tx.ValidExtIDs(); err != nil {
+		return fmt.Errorf("invalid signatures: %v", err)
+	}
+	if !tx.IsCoinbase() && !tx.ValidRCDs() {
+		return fmt.Errorf("invalid RCDs")
+	}
+
+	txID, err := submit(&tx.Entry.Entry)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(infoOut, "Created Transaction Entry")
+	fmt.Fprintln(infoOut, "Transaction Entry Hash: ", tx.Hash)
+	fmt.Fprintln(infoOut, "Factom TxID: ", txID)
+	recordSubmitted(tx.ChainID, tx.Hash, txID)
+	return nil
+}
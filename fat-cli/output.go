@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+// Valid values of -output.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// Exit codes returned by fat-cli. These are stable so that scripts may rely
+// on them.
+const (
+	exitOK = 0
+	// Any error not covered below.
+	exitError = 1
+	// Invalid flags or arguments, or params rejected by fatd or factomd.
+	exitInvalidParams = 2
+	// The token, transaction, address or entry does not exist.
+	exitNotFound = 3
+	// fatd, factomd or factom-walletd could not be reached.
+	exitNetwork = 4
+)
+
+// factomd JSON RPC error codes.
+const (
+	// Returned for any missing block, entry or other object.
+	factomdErrorObjectNotFound   = -32008
+	factomdErrorMissingChainHead = -32009
+)
+
+var (
+	outputFormat string
+
+	// infoOut is where progress and other human readable messages are
+	// written. These are sent to stderr for -format json and csv so that
+	// stdout only contains the result.
+	infoOut io.Writer = os.Stdout
+)
+
+// printResult writes v to stdout in the format given by -format. For -format
+// table, printTable is called instead, if it is not nil. For -format csv,
+// records returns the header and rows to write.
+func printResult(v interface{}, printTable func(), records func() [][]string) error {
+	switch outputFormat {
+	case outputJSON:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", data)
+	case outputCSV:
+		w := csv.NewWriter(os.Stdout)
+		if err := w.WriteAll(records()); err != nil {
+			return err
+		}
+	default:
+		if printTable != nil {
+			printTable()
+		}
+	}
+	return nil
+}
+
+// csvValue formats v for a CSV record. Timestamps use Unix seconds, as in the
+// JSON output.
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case *factom.Bytes32:
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	case *factom.Time:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(v.Unix(), 10)
+	case json.RawMessage:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// submitResult is the result of a command that submits an entry to Factom.
+type submitResult struct {
+	ChainID   *factom.Bytes32 `json:"chainid"`
+	EntryHash *factom.Bytes32 `json:"entryhash"`
+	TxID      *factom.Bytes32 `json:"txid,omitempty"`
+}
+
+// submitted holds the entries submitted by the command so that they can be
+// printed as its result once it returns.
+var submitted = []submitResult{}
+
+func recordSubmitted(chainID, entryHash, txID *factom.Bytes32) {
+	submitted = append(submitted, submitResult{
+		ChainID: chainID, EntryHash: entryHash, TxID: txID})
+}
+
+// printSubmitted prints the entries submitted by the command. Nothing is
+// printed for -format table since each command prints its entries as they are
+// submitted.
+func printSubmitted() error {
+	return printResult(submitted, nil, func() [][]string {
+		records := [][]string{{"chainid", "entryhash", "txid"}}
+		for _, r := range submitted {
+			records = append(records, []string{csvValue(r.ChainID),
+				csvValue(r.EntryHash), csvValue(r.TxID)})
+		}
+		return records
+	})
+}
+
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	switch err := err.(type) {
+	case jrpc.Error:
+		switch err.Code {
+		case srv.ErrorTokenNotFound.Code,
+			srv.ErrorTransactionNotFound.Code,
+			factomdErrorObjectNotFound,
+			factomdErrorMissingChainHead:
+			return exitNotFound
+		case jrpc.InvalidParamsCode,
+			srv.ErrorInvalidAddress.Code,
+			srv.ErrorInvalidTransaction.Code:
+			return exitInvalidParams
+		}
+	case *url.Error, net.Error:
+		return exitNetwork
+	}
+	return exitError
+}
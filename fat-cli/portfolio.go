@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/fatd/fat"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+func portfolio() error {
+	params := srv.ParamsGetAddressBalances{Addresses: portfolioAddresses}
+	results, err := fatd.GetAddressBalances(ctx, params)
+	if err != nil {
+		return err
+	}
+	return printResult(results, func() {
+		for _, res := range results {
+			fmt.Printf("Address: %v\n", res.Address)
+			if len(res.Balances) == 0 {
+				fmt.Printf("\tNo tokens\n\n")
+				continue
+			}
+			for _, b := range res.Balances {
+				fmt.Printf("\tChain ID: %v\n", b.ChainID)
+				fmt.Printf("\tToken ID: %v\n", b.TokenID)
+				fmt.Printf("\tIssuer Identity Chain ID: %v\n",
+					b.IssuerChainID)
+				if b.Type == fat.TypeFAT1 {
+					fmt.Printf("\tTokens Held: %v\n\n", b.Balance)
+					continue
+				}
+				fmt.Printf("\tBalance: %v\n\n", b.Balance)
+			}
+		}
+	}, func() [][]string {
+		records := [][]string{{"address", "chainid", "tokenid",
+			"issuerid", "type", "balance"}}
+		for _, res := range results {
+			for _, b := range res.Balances {
+				records = append(records, []string{
+					res.Address.String(),
+					csvValue(b.ChainID), b.TokenID,
+					csvValue(b.IssuerChainID),
+					b.Type.String(), csvValue(b.Balance)})
+			}
+		}
+		return records
+	})
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+// Input selection strategies for -from-wallet.
+const (
+	strategyFewest    = "fewest"
+	strategyOldest    = "oldest"
+	strategyAddresses = "addresses"
+)
+
+// walletBalance is the balance of the token held by a wallet address.
+type walletBalance struct {
+	rcdHash factom.RCDHash
+	balance uint64
+	// firstTx is the time the address first received the token and is only
+	// populated for strategyOldest.
+	firstTx factom.Time
+}
+
+// selectInputs populates transaction.Inputs from the wallet's addresses using
+// the inputStrategy so that they cover the outputs. If a change address was
+// given then the full balances of the selected inputs are spent and the
+// surplus is added as an output to the change address. Otherwise only the
+// amount required is taken from the last selected input.
+func selectInputs() error {
+	rcdHashes, err := listWalletAddresses()
+	if err != nil {
+		return err
+	}
+	if inputStrategy == strategyAddresses {
+		for _, rcdHash := range fromAddresses {
+			if !containsRCDHash(rcdHashes, rcdHash) {
+				return fmt.Errorf("address not in wallet: %v",
+					rcdHash)
+			}
+		}
+		rcdHashes = fromAddresses
+	}
+	var change *factom.RCDHash
+	if flagIsSet["change"] {
+		change = &changeAddress
+	}
+	candidates := inputCandidates(rcdHashes, transaction.Outputs, change)
+
+	balances, err := getWalletBalances(candidates)
+	if err != nil {
+		return err
+	}
+	sortBalances(balances, inputStrategy)
+
+	need := transaction.Outputs.Sum()
+	inputs, err := takeInputs(balances, need, change != nil)
+	if err != nil {
+		return err
+	}
+	for rcdHash, amount := range inputs {
+		transaction.Inputs[rcdHash] = amount
+	}
+	total := inputs.Sum()
+	if surplus := total - need; surplus > 0 {
+		transaction.Outputs[changeAddress] = surplus
+	}
+
+	// Each input requires its own RCD/signature pair in the ExtIDs.
+	entryLen := extIDsLen(len(transaction.Inputs)) +
+		txContentLen(addressAmountMapJSONLen(transaction.Inputs),
+			len(transaction.Inputs),
+			addressAmountMapJSONLen(transaction.Outputs),
+			len(transaction.Outputs), transaction.MetadataJSONLen())
+	if entryLen > maxEntryDataLen {
+		return fmt.Errorf("%v inputs are required which exceeds "+
+			"the maximum entry size", len(transaction.Inputs))
+	}
+	fmt.Fprintf(infoOut, "Selected %v inputs, estimated cost %v EC\n",
+		len(transaction.Inputs), (entryLen+1023)/1024)
+	for _, rcdHash := range sortedRCDHashes(transaction.Inputs) {
+		fmt.Fprintf(infoOut, "Input: %v: %v\n", rcdHash, transaction.Inputs[rcdHash])
+	}
+	return nil
+}
+
+// inputCandidates returns the rcdHashes that may be used as inputs, excluding
+// the outputs and the change address, if not nil, since addresses may not be
+// both inputs and outputs.
+func inputCandidates(rcdHashes []factom.RCDHash, outputs fat0.AddressAmountMap,
+	change *factom.RCDHash) []factom.RCDHash {
+	candidates := rcdHashes[:0:0]
+	for _, rcdHash := range rcdHashes {
+		if _, ok := outputs[rcdHash]; ok {
+			continue
+		}
+		if change != nil && rcdHash == *change {
+			continue
+		}
+		candidates = append(candidates, rcdHash)
+	}
+	return candidates
+}
+
+// sortBalances orders balances by the input selection strategy. The order of
+// balances is unchanged for strategyAddresses.
+func sortBalances(balances []walletBalance, strategy string) {
+	switch strategy {
+	case strategyFewest:
+		sort.SliceStable(balances, func(i, j int) bool {
+			return balances[i].balance > balances[j].balance
+		})
+	case strategyOldest:
+		sort.SliceStable(balances, func(i, j int) bool {
+			return balances[i].firstTx.Before(balances[j].firstTx.Time)
+		})
+	}
+}
+
+// takeInputs returns inputs from balances, in order, until they cover need.
+// If spendAll is true then the full balance of each selected input is spent,
+// otherwise only the amount required is taken from the last selected input.
+func takeInputs(balances []walletBalance, need uint64,
+	spendAll bool) (fat0.AddressAmountMap, error) {
+	inputs := make(fat0.AddressAmountMap)
+	var total uint64
+	for _, b := range balances {
+		if total >= need {
+			break
+		}
+		amount := b.balance
+		if !spendAll && total+amount > need {
+			amount = need - total
+		}
+		inputs[b.rcdHash] = amount
+		total += amount
+	}
+	if total < need {
+		return nil, fmt.Errorf("insufficient wallet balance: "+
+			"have %v, need %v", total, need)
+	}
+	return inputs, nil
+}
+
+// addressAmountMapJSONLen returns the combined length of the members of the
+// compact JSON encoding of m.
+func addressAmountMapJSONLen(m fat0.AddressAmountMap) int {
+	l := 0
+	for _, amount := range m {
+		l += addressAmountJSONLen(amount)
+	}
+	return l
+}
+
+func containsRCDHash(rcdHashes []factom.RCDHash, rcdHash factom.RCDHash) bool {
+	for _, r := range rcdHashes {
+		if r == rcdHash {
+			return true
+		}
+	}
+	return false
+}
+
+// listWalletAddresses returns the FA addresses in the keystore if it is in use,
+// otherwise those in factom-walletd.
+func listWalletAddresses() ([]factom.RCDHash, error) {
+	var pubs []string
+	if useKeystore() {
+		if loadedKeystore == nil {
+			var err error
+			if loadedKeystore, err = loadKeystore(false); err != nil {
+				return nil, err
+			}
+		}
+		for _, k := range loadedKeystore.Keys {
+			_, pub, err := parseKey(k.Key)
+			if err != nil {
+				return nil, err
+			}
+			pubs = append(pubs, pub)
+		}
+	} else {
+		var result struct {
+			Addresses []struct {
+				Public string `json:"public"`
+			} `json:"addresses"`
+		}
+		if err := factom.WalletRequest(ctx, "all-addresses", nil,
+			&result); err != nil {
+			return nil, err
+		}
+		for _, adr := range result.Addresses {
+			pubs = append(pubs, adr.Public)
+		}
+	}
+	var rcdHashes []factom.RCDHash
+	for _, pub := range pubs {
+		if !strings.HasPrefix(pub, "FA") {
+			continue
+		}
+		var rcdHash factom.RCDHash
+		if err := rcdHash.FromString(pub); err != nil {
+			return nil, err
+		}
+		rcdHashes = append(rcdHashes, rcdHash)
+	}
+	return rcdHashes, nil
+}
+
+// getWalletBalances returns the non-zero balances of the token for each of
+// rcdHashes, in the same order.
+func getWalletBalances(rcdHashes []factom.RCDHash) ([]walletBalance, error) {
+	if len(rcdHashes) == 0 {
+		return nil, nil
+	}
+	params := srv.ParamsGetAddressBalances{
+		Addresses: make([]factom.Address, len(rcdHashes))}
+	for i := range rcdHashes {
+		params.Addresses[i] = factom.NewAddress(&rcdHashes[i])
+	}
+	results, err := fatd.GetAddressBalances(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var balances []walletBalance
+	for i, res := range results {
+		for _, b := range res.Balances {
+			if *b.ChainID != *chainID || b.Balance == 0 {
+				continue
+			}
+			wb := walletBalance{rcdHash: rcdHashes[i], balance: b.Balance}
+			if inputStrategy == strategyOldest {
+				ts, err := getFirstReceived(params.Addresses[i])
+				if err != nil {
+					return nil, err
+				}
+				wb.firstTx = ts
+			}
+			balances = append(balances, wb)
+		}
+	}
+	return balances, nil
+}
+
+// getFirstReceived returns the timestamp of the first transaction in which adr
+// received the token.
+func getFirstReceived(adr factom.Address) (factom.Time, error) {
+	limit := uint(1)
+	params := srv.ParamsGetTransactions{
+		ParamsToken:    srv.ParamsToken{ChainID: chainID},
+		FactoidAddress: &adr,
+		ToFrom:         "to",
+		Limit:          &limit,
+	}
+	result, err := fatd.GetTransactionsPage(ctx, params)
+	if err != nil {
+		return factom.Time{}, err
+	}
+	if len(result.Transactions) == 0 {
+		return factom.Time{}, fmt.Errorf("no transactions for %v", adr)
+	}
+	return *result.Transactions[0].Timestamp, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectInputs(t *testing.T) {
+	a, b, c := testRCDHashes[0], testRCDHashes[1], testRCDHashes[2]
+	x := factom.RCDHash{0xff}
+	day := func(d int) factom.Time {
+		return factom.Time{Time: time.Date(2019, 1, d, 0, 0, 0, 0,
+			time.UTC)}
+	}
+	wallet := []walletBalance{
+		{rcdHash: a, balance: 10, firstTx: day(1)},
+		{rcdHash: b, balance: 50, firstTx: day(3)},
+		{rcdHash: c, balance: 30, firstTx: day(2)},
+	}
+	for _, test := range []struct {
+		Name     string
+		Strategy string
+		Outputs  fat0.AddressAmountMap
+		Change   *factom.RCDHash
+		Inputs   fat0.AddressAmountMap
+		Error    string
+	}{{
+		Name:     "fewest",
+		Strategy: strategyFewest,
+		Outputs:  fat0.AddressAmountMap{x: 60},
+		Inputs:   fat0.AddressAmountMap{b: 50, c: 10},
+	}, {
+		Name:     "fewest with change",
+		Strategy: strategyFewest,
+		Outputs:  fat0.AddressAmountMap{x: 60},
+		Change:   &x,
+		Inputs:   fat0.AddressAmountMap{b: 50, c: 30},
+	}, {
+		Name:     "oldest",
+		Strategy: strategyOldest,
+		Outputs:  fat0.AddressAmountMap{x: 60},
+		Inputs:   fat0.AddressAmountMap{a: 10, c: 30, b: 20},
+	}, {
+		Name:     "change address is not an input",
+		Strategy: strategyFewest,
+		Outputs:  fat0.AddressAmountMap{x: 60},
+		Change:   &c,
+		Inputs:   fat0.AddressAmountMap{b: 50, a: 10},
+	}, {
+		Name:     "insufficient",
+		Strategy: strategyFewest,
+		Outputs:  fat0.AddressAmountMap{x: 91},
+		Error:    "insufficient wallet balance: have 90, need 91",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			rcdHashes := make([]factom.RCDHash, len(wallet))
+			for i, wb := range wallet {
+				rcdHashes[i] = wb.rcdHash
+			}
+			candidates := inputCandidates(rcdHashes, test.Outputs,
+				test.Change)
+			var balances []walletBalance
+			for _, wb := range wallet {
+				if containsRCDHash(candidates, wb.rcdHash) {
+					balances = append(balances, wb)
+				}
+			}
+
+			sortBalances(balances, test.Strategy)
+			inputs, err := takeInputs(balances, test.Outputs.Sum(),
+				test.Change != nil)
+			if len(test.Error) > 0 {
+				assert.EqualError(t, err, test.Error)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Inputs, inputs)
+		})
+	}
+}
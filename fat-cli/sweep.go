@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+)
+
+// sweep moves the entire balance of each source address to sweepDestination
+// using as many multi-input Transactions as required. The source addresses
+// are those given with -from, or all of the wallet's addresses otherwise.
+func sweep() error {
+	sources := fromAddresses
+	if len(sources) == 0 {
+		var err error
+		if sources, err = listWalletAddresses(); err != nil {
+			return err
+		}
+	}
+	// An address may not be both an input and an output.
+	candidates := sources[:0:0]
+	for _, rcdHash := range sources {
+		if rcdHash != sweepDestination &&
+			!containsRCDHash(candidates, rcdHash) {
+			candidates = append(candidates, rcdHash)
+		}
+	}
+
+	balances, err := getWalletBalances(candidates)
+	if err != nil {
+		return err
+	}
+	if len(balances) == 0 {
+		return fmt.Errorf("no balances to sweep")
+	}
+	inputs := make(fat0.AddressAmountMap, len(balances))
+	keys := make(map[factom.RCDHash]factom.Address, len(balances))
+	for _, b := range balances {
+		rcdHash := b.rcdHash
+		adr := factom.NewAddress(&rcdHash)
+		if err := getAddress(&adr); err != nil {
+			return err
+		}
+		inputs[rcdHash] = b.balance
+		keys[rcdHash] = adr
+	}
+
+	txs := splitInputs(inputs, sweepDestination)
+	fmt.Fprintf(infoOut, "Sweeping %v tokens from %v addresses to %v "+
+		"in %v transactions\n", inputs.Sum(), len(inputs),
+		factom.NewAddress(&sweepDestination), len(txs))
+	hashes, err := signAndSubmit(txs, dryRun, keys)
+	if len(hashes) > 0 {
+		if err := waitForTransactions(hashes); err != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -5,44 +5,28 @@ import (
 
 	"github.com/Factom-Asset-Tokens/fatd/factom"
 	"github.com/Factom-Asset-Tokens/fatd/fat"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
 )
 
 func transact() error {
-	inputAddresses := make([]factom.Address, 0, len(transaction.Inputs))
-	if flagIsSet["coinbase"] {
-		eb := factom.EBlock{ChainID: chainID}
-		if err := eb.GetFirst(); err != nil {
-			return err
-		}
-		if !eb.IsPopulated() {
-			return fmt.Errorf("Token Chain not found")
-		}
-		// Get NameIDs for chain to check if this chain is valid.
-		first := eb.Entries[0]
-		if err := first.Get(); err != nil {
+	if len(nfTransaction.Inputs) > 0 || len(nfTransaction.Outputs) > 0 {
+		return transactNF()
+	}
+	if fromWallet {
+		if err := selectInputs(); err != nil {
 			return err
 		}
-		if !first.IsPopulated() {
-			return fmt.Errorf("Failed to populate Entry%+v", eb.Entries[0])
-		}
-		if !fat.ValidTokenNameIDs(first.ExtIDs) {
-			return fmt.Errorf("Not a valid token chain")
-		}
-		copy(identity.ChainID[:], first.ExtIDs[3])
-		if err := identity.Get(); err != nil {
+	}
+	inputAddresses := make([]factom.Address, 0, len(transaction.Inputs))
+	if flagIsSet["coinbase"] {
+		if err := verifySK1(); err != nil {
 			return err
 		}
-		if !identity.IsPopulated() {
-			return fmt.Errorf("Identity Chain does not exist")
-		}
-		if *identity.IDKey != *sk1.RCDHash() {
-			return fmt.Errorf("Invalid SK1 key for Identity%+v", identity)
-		}
 		inputAddresses = append(inputAddresses, sk1)
 	} else {
 		for rcd := range transaction.Inputs {
 			adr := factom.NewAddress(&rcd)
-			if err := adr.Get(); err != nil {
+			if err := getAddress(&adr); err != nil {
 				return err
 			}
 			inputAddresses = append(inputAddresses, adr)
@@ -55,30 +39,67 @@ func transact() error {
 	if err := transaction.Valid(sk1.RCDHash()); err != nil {
 		return err
 	}
-	var txID *factom.Bytes32
-	var err error
-	if len(ECPub) != 0 {
-		txID, err = transaction.Create(ECPub)
-		if err != nil {
-			return err
-		}
-	} else {
-		transaction.Timestamp = nil
-		result := struct {
-			*factom.Entry
-			TxID *factom.Bytes32 `json:"txid"`
-		}{Entry: &transaction.Entry.Entry}
-		err := factom.Request(APIAddress, "send-transaction",
-			transaction.Entry.Entry, &result)
-		if err != nil {
-			return err
-		}
-		txID = result.TxID
+	txID, err := submit(&transaction.Entry.Entry)
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("Created Transaction Entry")
-	fmt.Println("Token Chain ID: ", chainID)
-	fmt.Println("Transaction Entry Hash: ", transaction.Hash)
-	fmt.Println("Factom TxID: ", txID)
+	fmt.Fprintln(infoOut, "Created Transaction Entry")
+	fmt.Fprintln(infoOut, "Token Chain ID: ", chainID)
+	fmt.Fprintln(infoOut, "Transaction Entry Hash: ", transaction.Hash)
+	fmt.Fprintln(infoOut, "Factom TxID: ", txID)
+	recordSubmitted(chainID, transaction.Hash, txID)
 	return nil
 }
+
+// verifySK1 looks up the Identity of the token chain's issuer and ensures that
+// sk1 corresponds to its IDKey, as required to sign coinbase transactions.
+func verifySK1() error {
+	eb := factom.EBlock{ChainID: chainID}
+	if err := eb.GetFirst(ctx); err != nil {
+		return err
+	}
+	if !eb.IsPopulated() {
+		return fmt.Errorf("Token Chain not found")
+	}
+	// Get NameIDs for chain to check if this chain is valid.
+	first := eb.Entries[0]
+	if err := first.Get(ctx); err != nil {
+		return err
+	}
+	if !first.IsPopulated() {
+		return fmt.Errorf("Failed to populate Entry%+v", eb.Entries[0])
+	}
+	if !fat.ValidTokenNameIDs(first.ExtIDs) {
+		return fmt.Errorf("Not a valid token chain")
+	}
+	copy(identity.ChainID[:], first.ExtIDs[3])
+	if err := identity.Get(ctx); err != nil {
+		return err
+	}
+	if !identity.IsPopulated() {
+		return fmt.Errorf("Identity Chain does not exist")
+	}
+	if *identity.IDKey != *sk1.RCDHash() {
+		return fmt.Errorf("Invalid SK1 key for Identity%+v", identity)
+	}
+	return nil
+}
+
+// submit the signed transaction entry to Factom. If -ecpub was given then the
+// entry is paid for with those Entry Credits, otherwise it is sent to fatd
+// using the send-transaction method.
+func submit(e *factom.Entry) (*factom.Bytes32, error) {
+	if len(ECPub) != 0 {
+		return createEntry(e)
+	}
+	result, err := fatd.SendTransaction(ctx, srv.ParamsSendTransaction{
+		ParamsToken: srv.ParamsToken{ChainID: e.ChainID},
+		ExtIDs:      e.ExtIDs,
+		Content:     e.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.TxID, nil
+}
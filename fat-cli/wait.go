@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/srv"
+)
+
+// pollInterval is how often factomd or fatd is polled while waiting for an
+// entry to be confirmed.
+const pollInterval = 10 * time.Second
+
+// pollUntil calls done every pollInterval until it returns true or an error.
+// If waitTimeout is not zero, an error is returned once it has elapsed.
+func pollUntil(done func() (bool, error)) error {
+	var deadline time.Time
+	if waitTimeout > 0 {
+		deadline = time.Now().Add(waitTimeout)
+	}
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if !deadline.IsZero() && time.Now().Add(pollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %v\n"+
+				"Run the same command again to resume.", waitTimeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// waitForTransactions polls fatd until each of the transactions with the
+// given Entry Hashes has been applied, printing progress as each is
+// confirmed.
+func waitForTransactions(hashes []*factom.Bytes32) error {
+	pending := append(hashes[:0:0], hashes...)
+	var numConfirmed int
+	fmt.Fprintf(infoOut, "Waiting for %v transactions to be confirmed...\n",
+		len(pending))
+	return pollUntil(func() (bool, error) {
+		unconfirmed := pending[:0]
+		for _, hash := range pending {
+			confirmed, err := isTransactionConfirmed(hash)
+			if err != nil {
+				return false, err
+			}
+			if !confirmed {
+				unconfirmed = append(unconfirmed, hash)
+				continue
+			}
+			numConfirmed++
+			fmt.Fprintf(infoOut, "Confirmed %v/%v: %v\n",
+				numConfirmed, len(hashes), hash)
+		}
+		pending = unconfirmed
+		return len(pending) == 0, nil
+	})
+}
+
+// isTransactionConfirmed returns true if fatd has applied the transaction with
+// the given Entry Hash.
+func isTransactionConfirmed(hash *factom.Bytes32) (bool, error) {
+	params := srv.ParamsGetTransaction{
+		ParamsToken: srv.ParamsToken{ChainID: chainID},
+		Hash:        hash,
+	}
+	_, err := fatd.GetTransaction(ctx, params)
+	if err, ok := err.(jrpc.Error); ok &&
+		err.Code == srv.ErrorTransactionNotFound.Code {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// isIssued returns true if fatd has applied the Issuance of the token.
+func isIssued() (bool, error) {
+	params := srv.ParamsToken{ChainID: chainID}
+	_, err := fatd.GetIssuance(ctx, params)
+	if err, ok := err.(jrpc.Error); ok &&
+		(err.Code == srv.ErrorTokenNotFound.Code ||
+			err.Code == srv.ErrorTokenSyncing.Code) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// isChainCreated returns true if the first entry of the chain is in a block.
+func isChainCreated(chainID *factom.Bytes32) (bool, error) {
+	eb := factom.EBlock{ChainID: chainID}
+	if err := eb.GetFirst(ctx); err != nil {
+		if _, ok := err.(jrpc.Error); ok {
+			// factomd returns an error for chains that do not
+			// exist yet.
+			return false, nil
+		}
+		return false, err
+	}
+	return eb.IsPopulated(), nil
+}
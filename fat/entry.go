@@ -1,6 +1,7 @@
 package fat
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/json"
@@ -44,26 +45,13 @@ func (e *Entry) MarshalEntry(v interface{}) error {
 // RCD/signature pairs.
 func (e Entry) ValidExtIDs(numRCDSigPairs int) error {
 	if numRCDSigPairs == 0 || len(e.ExtIDs) != 2*numRCDSigPairs+1 {
-		return fmt.Errorf("invalid number of ExtIDs")
+		return ValidationError{CheckExtIDs,
+			fmt.Errorf("invalid number of ExtIDs")}
 	}
 	if err := e.validTimestamp(); err != nil {
-		return err
+		return ValidationError{CheckTimestamp, err}
 	}
-	extIDs := e.ExtIDs[1:]
-	for i := 0; i < len(extIDs)/2; i++ {
-		rcd := extIDs[i*2]
-		if len(rcd) != factom.RCDSize {
-			return fmt.Errorf("ExtIDs[%v]: invalid RCD size", i+1)
-		}
-		if rcd[0] != factom.RCDType {
-			return fmt.Errorf("ExtIDs[%v]: invalid RCD type", i+1)
-		}
-		sig := extIDs[i*2+1]
-		if len(sig) != factom.SignatureSize {
-			return fmt.Errorf("ExtIDs[%v]: invalid signature size", i+1)
-		}
-	}
-	return e.validSignatures()
+	return e.validRCDSigs()
 }
 
 func (e Entry) validTimestamp() error {
@@ -79,62 +67,185 @@ func (e Entry) validTimestamp() error {
 	return nil
 }
 
+// validRCDSigs validates the size and type of each RCD and signature in the
+// ExtIDs and then validates the signatures.
+func (e Entry) validRCDSigs() error {
+	extIDs := e.ExtIDs[1:]
+	for i := 0; i < len(extIDs)/2; i++ {
+		rcd := extIDs[i*2]
+		if len(rcd) != factom.RCDSize {
+			return ValidationError{CheckExtIDs,
+				fmt.Errorf("ExtIDs[%v]: invalid RCD size", i+1)}
+		}
+		if rcd[0] != factom.RCDType {
+			return ValidationError{CheckExtIDs,
+				fmt.Errorf("ExtIDs[%v]: invalid RCD type", i+1)}
+		}
+		sig := extIDs[i*2+1]
+		if len(sig) != factom.SignatureSize {
+			return ValidationError{CheckExtIDs,
+				fmt.Errorf("ExtIDs[%v]: invalid signature size", i+1)}
+		}
+	}
+	return e.validSignatures()
+}
+
 // validSignatures returns true if the first num RCD/signature pairs in the
 // ExtIDs are valid.
 func (e Entry) validSignatures() error {
-	numRcdSigPairs := uint64(len(e.ExtIDs) / 2)
-	maxRcdSigIDSaltStrLen := jsonlen.Uint64(numRcdSigPairs)
-	timeSalt := e.ExtIDs[0]
-	maxMsgLen := maxRcdSigIDSaltStrLen + len(timeSalt) + len(e.ChainID) + len(e.Content)
-	msg := make(factom.Bytes, maxMsgLen)
-	i := maxRcdSigIDSaltStrLen
-	i += copy(msg[i:], timeSalt[:])
-	i += copy(msg[i:], e.ChainID[:])
-	copy(msg[i:], e.Content)
+	msg := e.newRCDSigMsg()
 	var pubKey [ed25519.PublicKeySize]byte
 	var sig [ed25519.SignatureSize]byte
-	var msgHash [sha512.Size]byte
 	rcdSigs := e.ExtIDs[1:]
-	for rcdSigID := uint64(0); rcdSigID < numRcdSigPairs; rcdSigID++ {
-		rcdSigIDSalt := strconv.FormatUint(rcdSigID, 10)
-		i = maxRcdSigIDSaltStrLen - len(rcdSigIDSalt)
-		copy(msg[i:], rcdSigIDSalt)
-		msgHash = sha512.Sum512(msg)
-
+	for rcdSigID := 0; rcdSigID < len(rcdSigs)/2; rcdSigID++ {
+		msgHash := msg.hash(rcdSigID)
 		copy(pubKey[:], rcdSigs[rcdSigID*2][1:])
 		copy(sig[:], rcdSigs[rcdSigID*2+1])
 		if !ed25519.VerifyCanonical(&pubKey, msgHash[:], &sig) {
-			return fmt.Errorf("ExtIDs[%v]: invalid signature", rcdSigID*2+2)
+			return ValidationError{CheckSignatures, fmt.Errorf(
+				"ExtIDs[%v]: invalid signature", rcdSigID*2+2)}
 		}
 	}
 	return nil
 }
 
-// Sign the RCD/Sig ID Salt + Timestamp Salt + Chain ID Salt + Content of the
-// factom.Entry and add the RCD + signature pairs for the given addresses to
-// the ExtIDs. This clears any existing ExtIDs.
-func (e *Entry) Sign(signingSet ...factom.Address) {
-	e.SetTimestampToNow()
-	maxRcdSigIDSaltStrLen := jsonlen.Uint64(uint64(len(signingSet)))
-	timeSalt := newTimestampSalt()
+// rcdSigMsg is the RCD/Sig ID Salt + Timestamp Salt + Chain ID Salt + Content
+// of a factom.Entry that is signed by each RCD/signature pair.
+type rcdSigMsg struct {
+	msg                factom.Bytes
+	rcdSigIDSaltStrLen int
+}
+
+// newRCDSigMsg returns the rcdSigMsg for the timestamp salt and number of
+// RCD/signature pairs in the ExtIDs of e.
+func (e Entry) newRCDSigMsg() rcdSigMsg {
+	numRcdSigPairs := uint64(len(e.ExtIDs) / 2)
+	maxRcdSigIDSaltStrLen := jsonlen.Uint64(numRcdSigPairs)
+	timeSalt := e.ExtIDs[0]
 	maxMsgLen := maxRcdSigIDSaltStrLen + len(timeSalt) + len(e.ChainID) + len(e.Content)
 	msg := make(factom.Bytes, maxMsgLen)
 	i := maxRcdSigIDSaltStrLen
 	i += copy(msg[i:], timeSalt[:])
 	i += copy(msg[i:], e.ChainID[:])
 	copy(msg[i:], e.Content)
-	e.ExtIDs = make([]factom.Bytes, 1, len(signingSet)*2+1)
+	return rcdSigMsg{msg: msg, rcdSigIDSaltStrLen: maxRcdSigIDSaltStrLen}
+}
+
+// hash returns the SHA512 hash of the message for rcdSigID. The RCD/Sig ID
+// Salt is right aligned and padded with zeros.
+func (m rcdSigMsg) hash(rcdSigID int) [sha512.Size]byte {
+	for i := 0; i < m.rcdSigIDSaltStrLen; i++ {
+		m.msg[i] = 0
+	}
+	rcdSigIDSalt := strconv.FormatUint(uint64(rcdSigID), 10)
+	copy(m.msg[m.rcdSigIDSaltStrLen-len(rcdSigIDSalt):], rcdSigIDSalt)
+	return sha512.Sum512(m.msg)
+}
+
+// sign sets the RCD/signature pair at rcdSigID to the RCD and signature of a.
+func (e *Entry) sign(msg rcdSigMsg, rcdSigID int, a factom.Address) {
+	msgHash := msg.hash(rcdSigID)
+	sig := ed25519.Sign(a.PrivateKey().Bytes(), msgHash[:])
+	e.ExtIDs[rcdSigID*2+1] = a.RCD()
+	e.ExtIDs[rcdSigID*2+2] = sig[:]
+}
+
+// initRCDSigs clears the ExtIDs and sets the timestamp salt, leaving space for
+// numRCDSigPairs RCD/signature pairs.
+func (e *Entry) initRCDSigs(timeSalt []byte, numRCDSigPairs int) {
+	e.ExtIDs = make([]factom.Bytes, numRCDSigPairs*2+1)
 	e.ExtIDs[0] = timeSalt
-	var msgHash [sha512.Size]byte
+}
+
+// Sign the RCD/Sig ID Salt + Timestamp Salt + Chain ID Salt + Content of the
+// factom.Entry and add the RCD + signature pairs for the given addresses to
+// the ExtIDs. This clears any existing ExtIDs.
+func (e *Entry) Sign(signingSet ...factom.Address) {
+	e.SetTimestampToNow()
+	e.initRCDSigs(newTimestampSalt(), len(signingSet))
+	msg := e.newRCDSigMsg()
 	for rcdSigID, a := range signingSet {
-		rcdSigIDSalt := strconv.FormatUint(uint64(rcdSigID), 10)
-		i = maxRcdSigIDSaltStrLen - len(rcdSigIDSalt)
-		copy(msg[i:], rcdSigIDSalt)
-		msgHash = sha512.Sum512(msg)
+		e.sign(msg, rcdSigID, a)
+	}
+}
+
+// InitRCDSigs prepares the factom.Entry to be signed by multiple parties. The
+// timestamp salt is fixed to the current time and the ExtIDs are cleared,
+// leaving space for numRCDSigPairs RCD/signature pairs. Each party may then
+// add their own pair with SignRCDSigID and the results may be assembled with
+// CombineRCDSigs. The Content and ChainID must not change after this is
+// called.
+func (e *Entry) InitRCDSigs(numRCDSigPairs int) {
+	e.initRCDSigs([]byte(strconv.FormatInt(time.Now().Unix(), 10)),
+		numRCDSigPairs)
+}
+
+// NumRCDSigPairs returns the number of RCD/signature pairs that the ExtIDs
+// have space for.
+func (e Entry) NumRCDSigPairs() int {
+	return len(e.ExtIDs) / 2
+}
 
-		sig := ed25519.Sign(a.PrivateKey().Bytes(), msgHash[:])
-		e.ExtIDs = append(e.ExtIDs, a.RCD(), sig[:])
+// IsRCDSigSet returns true if the RCD/signature pair at rcdSigID is set.
+func (e Entry) IsRCDSigSet(rcdSigID int) bool {
+	if rcdSigID < 0 || rcdSigID >= e.NumRCDSigPairs() {
+		return false
+	}
+	return len(e.ExtIDs[rcdSigID*2+1]) > 0 && len(e.ExtIDs[rcdSigID*2+2]) > 0
+}
+
+// SignRCDSigID signs the factom.Entry with a and sets the RCD/signature pair
+// at rcdSigID, leaving all other pairs untouched. InitRCDSigs must have been
+// called first.
+func (e *Entry) SignRCDSigID(rcdSigID int, a factom.Address) error {
+	if len(e.ExtIDs) < 3 || len(e.ExtIDs)%2 != 1 {
+		return fmt.Errorf("RCD/signature pairs not initialized")
+	}
+	if rcdSigID < 0 || rcdSigID >= e.NumRCDSigPairs() {
+		return fmt.Errorf("invalid RCD/signature ID: %v", rcdSigID)
+	}
+	e.sign(e.newRCDSigMsg(), rcdSigID, a)
+	return nil
+}
+
+// CombineRCDSigs adds the RCD/signature pairs set in each of partials to the
+// factom.Entry and then validates all RCD/signature pairs. All partials must
+// have the same ChainID, Content, timestamp salt and number of RCD/signature
+// pairs as the factom.Entry. An error is returned if two partials set a
+// different RCD/signature pair at the same ID, or if any pair is still missing
+// after combining.
+func (e *Entry) CombineRCDSigs(partials ...Entry) error {
+	if len(e.ExtIDs) < 3 || len(e.ExtIDs)%2 != 1 {
+		return fmt.Errorf("RCD/signature pairs not initialized")
+	}
+	for i, p := range partials {
+		if *p.ChainID != *e.ChainID ||
+			!bytes.Equal(p.Content, e.Content) ||
+			len(p.ExtIDs) != len(e.ExtIDs) ||
+			!bytes.Equal(p.ExtIDs[0], e.ExtIDs[0]) {
+			return fmt.Errorf("partials[%v]: different entry", i)
+		}
+		for rcdSigID := 0; rcdSigID < p.NumRCDSigPairs(); rcdSigID++ {
+			if !p.IsRCDSigSet(rcdSigID) {
+				continue
+			}
+			rcd, sig := p.ExtIDs[rcdSigID*2+1], p.ExtIDs[rcdSigID*2+2]
+			if e.IsRCDSigSet(rcdSigID) &&
+				(!bytes.Equal(e.ExtIDs[rcdSigID*2+1], rcd) ||
+					!bytes.Equal(e.ExtIDs[rcdSigID*2+2], sig)) {
+				return fmt.Errorf("partials[%v]: conflicting "+
+					"RCD/signature pair: %v", i, rcdSigID)
+			}
+			e.ExtIDs[rcdSigID*2+1], e.ExtIDs[rcdSigID*2+2] = rcd, sig
+		}
+	}
+	for rcdSigID := 0; rcdSigID < e.NumRCDSigPairs(); rcdSigID++ {
+		if !e.IsRCDSigSet(rcdSigID) {
+			return fmt.Errorf("missing RCD/signature pair: %v",
+				rcdSigID)
+		}
 	}
+	return e.validRCDSigs()
 }
 
 func newTimestampSalt() []byte {
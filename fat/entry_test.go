@@ -163,3 +163,58 @@ func twoAddresses() []factom.Address {
 	}
 	return adrs
 }
+
+func TestEntryPartialSignatures(t *testing.T) {
+	assert := assert.New(t)
+	adrs := twoAddresses()
+
+	var e Entry
+	e.Content = factom.Bytes{0x00, 0x01, 0x02}
+	e.ChainID = factom.NewBytes32(nil)
+	assert.EqualError(e.SignRCDSigID(0, adrs[0]),
+		"RCD/signature pairs not initialized")
+	e.InitRCDSigs(len(adrs))
+	assert.Equal(2, e.NumRCDSigPairs())
+	assert.EqualError(e.SignRCDSigID(2, adrs[0]),
+		"invalid RCD/signature ID: 2")
+
+	// Each party signs their own copy.
+	partials := make([]Entry, len(adrs))
+	for i := range partials {
+		partials[i] = e
+		partials[i].ExtIDs = append([]factom.Bytes{}, e.ExtIDs...)
+		assert.NoError(partials[i].SignRCDSigID(i, adrs[i]))
+		assert.True(partials[i].IsRCDSigSet(i))
+		assert.False(partials[i].IsRCDSigSet(1 - i))
+	}
+
+	missing := e
+	missing.ExtIDs = append([]factom.Bytes{}, e.ExtIDs...)
+	assert.EqualError(missing.CombineRCDSigs(partials[0]),
+		"missing RCD/signature pair: 1")
+
+	swapped := partials[1]
+	swapped.ExtIDs = append([]factom.Bytes{}, partials[1].ExtIDs...)
+	assert.NoError(swapped.SignRCDSigID(0, adrs[1]))
+	assert.EqualError(e.CombineRCDSigs(partials[0], swapped),
+		"partials[1]: conflicting RCD/signature pair: 0")
+
+	different := partials[1]
+	different.Content = factom.Bytes{0x03}
+	assert.EqualError(partials[0].CombineRCDSigs(different),
+		"partials[0]: different entry")
+
+	combined := e
+	combined.ExtIDs = append([]factom.Bytes{}, e.ExtIDs...)
+	assert.NoError(combined.CombineRCDSigs(partials...))
+	combined.SetTimestampToNow()
+	assert.NoError(combined.ValidExtIDs(len(adrs)))
+
+	// The result is the same as signing with all addresses at once.
+	signed := combined
+	signed.ExtIDs = append([]factom.Bytes{}, combined.ExtIDs...)
+	for i, adr := range adrs {
+		assert.NoError(signed.SignRCDSigID(i, adr))
+	}
+	assert.Equal(combined.ExtIDs, signed.ExtIDs)
+}
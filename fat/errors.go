@@ -0,0 +1,70 @@
+package fat
+
+import "errors"
+
+// Check identifies one of the validation checks performed on a FAT entry. The
+// checks are listed in the order that they are performed.
+type Check int
+
+const (
+	// CheckJSON is the decoding of the entry content.
+	CheckJSON Check = iota + 1
+	// CheckData is the validation of the decoded data, such as the
+	// inputs and outputs of a transaction.
+	CheckData
+	// CheckExtIDs is the number, size and type of the ExtIDs.
+	CheckExtIDs
+	// CheckTimestamp ensures the timestamp salt is within 12 hours of the
+	// entry's timestamp.
+	CheckTimestamp
+	// CheckSignatures verifies each RCD/signature pair.
+	CheckSignatures
+	// CheckRCDs ensures the RCDs match the inputs, or the issuer's IDKey
+	// for an Issuance or coinbase transaction.
+	CheckRCDs
+)
+
+var checkNames = map[Check]string{
+	CheckJSON:       "JSON decoding",
+	CheckData:       "data",
+	CheckExtIDs:     "ExtIDs",
+	CheckTimestamp:  "timestamp salt",
+	CheckSignatures: "signatures",
+	CheckRCDs:       "RCDs",
+}
+
+func (c Check) String() string {
+	if name, ok := checkNames[c]; ok {
+		return name
+	}
+	return "unknown check"
+}
+
+// ValidationError is returned when an entry fails one of the validation
+// checks. Err describes why the Check failed.
+type ValidationError struct {
+	Check Check
+	Err   error
+}
+
+func (err ValidationError) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err ValidationError) Unwrap() error {
+	return err.Err
+}
+
+// NewValidationError returns err as a ValidationError for check. If err is
+// nil or already wraps a ValidationError then it is returned unchanged.
+func NewValidationError(check Check, err error) error {
+	if err == nil {
+		return nil
+	}
+	var vErr ValidationError
+	if errors.As(err, &vErr) {
+		return err
+	}
+	return ValidationError{Check: check, Err: err}
+}
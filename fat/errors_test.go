@@ -0,0 +1,44 @@
+package fat_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/Factom-Asset-Tokens/fatd/fat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewValidationError(t *testing.T) {
+	err := errors.New("invalid")
+	vErr := ValidationError{Check: CheckRCDs, Err: err}
+	wrapped := fmt.Errorf("wrapped: %w", vErr)
+	for _, test := range []struct {
+		Name  string
+		Err   error
+		Check Check
+		Exp   error
+	}{{
+		Name: "nil",
+	}, {
+		Name:  "new",
+		Err:   err,
+		Check: CheckJSON,
+		Exp:   ValidationError{Check: CheckJSON, Err: err},
+	}, {
+		Name:  "ValidationError",
+		Err:   vErr,
+		Check: CheckJSON,
+		Exp:   vErr,
+	}, {
+		Name:  "wrapped ValidationError",
+		Err:   wrapped,
+		Check: CheckJSON,
+		Exp:   wrapped,
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Exp, NewValidationError(test.Check,
+				test.Err))
+		})
+	}
+}
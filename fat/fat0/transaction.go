@@ -47,7 +47,8 @@ func (t *Transaction) UnmarshalJSON(data []byte) error {
 	t.Metadata = tRaw.Metadata
 
 	if err := t.ValidData(); err != nil {
-		return fmt.Errorf("%T: %v", t, err)
+		return fat.NewValidationError(fat.CheckData,
+			fmt.Errorf("%T: %v", t, err))
 	}
 
 	expectedJSONLen := len(`{"inputs":,"outputs":}`) +
@@ -91,18 +92,20 @@ func (t Transaction) IsCoinbase() bool {
 // the RCD. Otherwise RCDs are checked against the input addresses.
 func (t *Transaction) Valid(idKey *factom.RCDHash) error {
 	if err := t.UnmarshalEntry(); err != nil {
-		return err
+		return fat.NewValidationError(fat.CheckJSON, err)
 	}
 	if err := t.ValidExtIDs(); err != nil {
 		return err
 	}
 	if t.IsCoinbase() {
 		if t.RCDHash(0) != *idKey {
-			return fmt.Errorf("invalid RCD")
+			return fat.NewValidationError(fat.CheckRCDs,
+				fmt.Errorf("invalid RCD"))
 		}
 	} else {
 		if !t.ValidRCDs() {
-			return fmt.Errorf("invalid RCDs")
+			return fat.NewValidationError(fat.CheckRCDs,
+				fmt.Errorf("invalid RCDs"))
 		}
 	}
 	return nil
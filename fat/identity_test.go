@@ -1,6 +1,7 @@
 package fat_test
 
 import (
+	"context"
 	"encoding/hex"
 	"testing"
 
@@ -10,6 +11,8 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+var ctx = context.Background()
+
 var validIdentityChainIDStr = "88888807e4f3bbb9a2b229645ab6d2f184224190f83e78761674c2362aca4425"
 
 func validIdentityChainID() factom.Bytes {
@@ -193,7 +196,7 @@ func TestIdentity(t *testing.T) {
 			}
 			factom.RpcConfig.FactomdServer = test.FactomServer
 			i := test.Identity
-			err := i.Get()
+			err := i.Get(ctx)
 			populated := i.IsPopulated()
 			if len(test.Error) > 0 {
 				assert.EqualError(err, test.Error)
@@ -207,7 +210,7 @@ func TestIdentity(t *testing.T) {
 			assert.True(populated)
 			assert.Equal(int(test.Height), int(i.Height))
 			assert.Equal(*test.IDKey, *i.IDKey)
-			assert.NoError(i.Get())
+			assert.NoError(i.Get(ctx))
 		})
 	}
 }
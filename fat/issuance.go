@@ -26,7 +26,7 @@ func (i *Issuance) UnmarshalJSON(data []byte) error {
 		return fmt.Errorf("%T: %v", i, err)
 	}
 	if err := i.ValidData(); err != nil {
-		return fmt.Errorf("%T: %v", i, err)
+		return ValidationError{CheckData, fmt.Errorf("%T: %v", i, err)}
 	}
 	if i.expectedJSONLength() != len(data) {
 		return fmt.Errorf("%T: unexpected JSON length", i)
@@ -75,13 +75,13 @@ func (i *Issuance) MarshalEntry() error {
 // Issuance.
 func (i *Issuance) Valid(idKey *factom.RCDHash) error {
 	if err := i.UnmarshalEntry(); err != nil {
-		return err
+		return NewValidationError(CheckJSON, err)
 	}
 	if err := i.ValidExtIDs(); err != nil {
 		return err
 	}
 	if i.RCDHash(0) != *idKey {
-		return fmt.Errorf("invalid RCD")
+		return ValidationError{CheckRCDs, fmt.Errorf("invalid RCD")}
 	}
 	return nil
 }
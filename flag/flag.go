@@ -1,14 +1,17 @@
 package flag
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/AdamSLevy/factom"
 	"github.com/Factom-Asset-Tokens/base58"
+	_factom "github.com/Factom-Asset-Tokens/fatd/factom"
 	"github.com/posener/complete"
 	"github.com/sirupsen/logrus"
 )
@@ -22,8 +25,26 @@ var (
 		"debug":           "DEBUG",
 
 		"dbpath": "DB_PATH",
-
-		"apiaddress": "API_ADDRESS",
+		"cache":  "CACHE",
+
+		"apiaddress":     "API_ADDRESS",
+		"apiauth":        "API_AUTH",
+		"apirequireauth": "API_REQUIRE_AUTH",
+		"apiratelimit":   "API_RATE_LIMIT",
+		"apiiplimit":     "API_IP_RATE_LIMIT",
+		"apicors":        "API_CORS_ORIGINS",
+		"apiaccesslog":   "API_ACCESS_LOG",
+		"apimethods":     "API_METHODS",
+		"apitlscert":     "API_TLS_CERT",
+		"apitlskey":      "API_TLS_KEY",
+		"apitlsclientca": "API_TLS_CLIENT_CA",
+
+		"apisocket":     "API_SOCKET",
+		"socketmethods": "API_SOCKET_METHODS",
+
+		"adminaddress":  "ADMIN_ADDRESS",
+		"adminuser":     "ADMIN_USER",
+		"adminpassword": "ADMIN_PASSWORD",
 
 		"s":               "FACTOMD_SERVER",
 		"factomdtimeout":  "FACTOMD_TIMEOUT",
@@ -31,6 +52,7 @@ var (
 		"factomdpassword": "FACTOMD_PASSWORD",
 		"factomdcert":     "FACTOMD_TLS_CERT",
 		"factomdtls":      "FACTOMD_TLS_ENABLE",
+		"factomdquorum":   "FACTOMD_QUORUM",
 
 		"w":              "WALLETD_SERVER",
 		"wallettimeout":  "WALLETD_TIMEOUT",
@@ -46,8 +68,26 @@ var (
 		"debug":           false,
 
 		"dbpath": "./fatd.db",
-
-		"apiaddress": ":8078",
+		"cache":  false,
+
+		"apiaddress":     ":8078",
+		"apiauth":        "",
+		"apirequireauth": false,
+		"apiratelimit":   uint64(0),
+		"apiiplimit":     uint64(0),
+		"apicors":        "*",
+		"apiaccesslog":   false,
+		"apimethods":     "read,send",
+		"apitlscert":     "",
+		"apitlskey":      "",
+		"apitlsclientca": "",
+
+		"apisocket":     "",
+		"socketmethods": "read,send,admin",
+
+		"adminaddress":  "",
+		"adminuser":     "admin",
+		"adminpassword": "",
 
 		"s":               "localhost:8088",
 		"factomdtimeout":  time.Duration(0),
@@ -55,6 +95,7 @@ var (
 		"factomdpassword": "",
 		"factomdcert":     "",
 		"factomdtls":      false,
+		"factomdquorum":   uint64(1),
 
 		"w":              "localhost:8089",
 		"wallettimeout":  time.Duration(0),
@@ -70,15 +111,34 @@ var (
 		"debug":           "Log debug messages",
 
 		"dbpath": "Path to the folder containing all database files",
-
-		"apiaddress": "IPAddr:port# to bind to for serving the JSON RPC 2.0 API",
-
-		"s":               "IPAddr:port# of factomd API to use to access blockchain",
+		"cache":  "Cache all blocks and entries fetched from factomd under -dbpath so that chains can be reindexed",
+
+		"apiaddress":     "IPAddr:port# to bind to for serving the JSON RPC 2.0 API",
+		"apiauth":        "Path to a JSON file of API credentials with their permissions and rate limits",
+		"apirequireauth": "Reject API requests that do not use a credential from -apiauth",
+		"apiratelimit":   "Maximum API requests per second for each credential, 0 means no limit",
+		"apiiplimit":     "Maximum API requests per second from each client IP address, 0 means no limit",
+		"apicors":        `Comma separated list of origins allowed to make cross-origin API requests, "*" allows all origins and "" allows none`,
+		"apiaccesslog":   "Log every API request",
+		"apimethods":     "Comma separated list of methods, or permissions (read, send, admin) granting methods, to serve on -apiaddress",
+		"apitlscert":     "Path to the TLS certificate for -apiaddress and -adminaddress, reloaded on SIGHUP",
+		"apitlskey":      "Path to the TLS private key for -apitlscert",
+		"apitlsclientca": "Path to the CA certificates used to verify TLS client certificates, which are then required",
+
+		"apisocket":     "Path of a Unix domain socket to serve the API on without authentication, disabled if empty",
+		"socketmethods": "Comma separated list of methods, or permissions (read, send, admin) granting methods, to serve on -apisocket",
+
+		"adminaddress":  "IPAddr:port# to bind to for serving the admin JSON RPC 2.0 API, disabled if empty",
+		"adminuser":     "Username required for connections to the admin API",
+		"adminpassword": "Password required for connections to the admin API",
+
+		"s":               "Comma separated list of IPAddr:port# of factomd APIs to use to access blockchain",
 		"factomdtimeout":  "Timeout for factomd API requests, 0 means never timeout",
 		"factomduser":     "Username for API connections to factomd",
 		"factomdpassword": "Password for API connections to factomd",
 		"factomdcert":     "The TLS certificate that will be provided by the factomd API server",
 		"factomdtls":      "Set to true to use TLS when accessing the factomd API",
+		"factomdquorum":   "Number of factomd APIs that must agree on each DBlock KeyMR before it is applied",
 
 		"w":              "IPAddr:port# of factom-walletd API to use to access wallet",
 		"wallettimeout":  "Timeout for factom-walletd API requests, 0 means never timeout",
@@ -94,8 +154,26 @@ var (
 		"-debug":           complete.PredictNothing,
 
 		"-dbpath": complete.PredictFiles("*"),
-
-		"-apiaddress": complete.PredictAnything,
+		"-cache":  complete.PredictNothing,
+
+		"-apiaddress":     complete.PredictAnything,
+		"-apiauth":        complete.PredictFiles("*.json"),
+		"-apirequireauth": complete.PredictNothing,
+		"-apiratelimit":   complete.PredictAnything,
+		"-apiiplimit":     complete.PredictAnything,
+		"-apicors":        complete.PredictAnything,
+		"-apiaccesslog":   complete.PredictNothing,
+		"-apimethods":     complete.PredictAnything,
+		"-apitlscert":     complete.PredictFiles("*"),
+		"-apitlskey":      complete.PredictFiles("*"),
+		"-apitlsclientca": complete.PredictFiles("*"),
+
+		"-apisocket":     complete.PredictFiles("*"),
+		"-socketmethods": complete.PredictAnything,
+
+		"-adminaddress":  complete.PredictAnything,
+		"-adminuser":     complete.PredictAnything,
+		"-adminpassword": complete.PredictAnything,
 
 		"-s":               complete.PredictAnything,
 		"-factomdtimeout":  complete.PredictAnything,
@@ -103,6 +181,7 @@ var (
 		"-factomdpassword": complete.PredictAnything,
 		"-factomdcert":     complete.PredictFiles("*"),
 		"-factomdtls":      complete.PredictNothing,
+		"-factomdquorum":   complete.PredictAnything,
 
 		"-w":              complete.PredictAnything,
 		"-wallettimeout":  complete.PredictAnything,
@@ -125,8 +204,31 @@ var (
 	ECPub string
 
 	DBPath string
-
-	APIAddress string
+	Cache  bool
+
+	// Reindex is set by the reindex command, which rebuilds the databases
+	// of the ReindexChainIDs, or all chains if empty, from the cache.
+	Reindex         bool
+	ReindexChainIDs []*_factom.Bytes32
+
+	APIAddress     string
+	APIAuth        string
+	APIRequireAuth bool
+	APIRateLimit   uint64
+	APIIPRateLimit uint64
+	APICORS        string
+	APIAccessLog   bool
+	APIMethods     string
+	APITLSCert     string
+	APITLSKey      string
+	APITLSClientCA string
+
+	APISocket     string
+	SocketMethods string
+
+	AdminAddress  string
+	AdminUser     string
+	AdminPassword string
 
 	rpc = factom.RpcConfig
 
@@ -140,8 +242,26 @@ func init() {
 	flagVar(&LogDebug, "debug")
 
 	flagVar(&DBPath, "dbpath")
+	flagVar(&Cache, "cache")
 
 	flagVar(&APIAddress, "apiaddress")
+	flagVar(&APIAuth, "apiauth")
+	flagVar(&APIRequireAuth, "apirequireauth")
+	flagVar(&APIRateLimit, "apiratelimit")
+	flagVar(&APIIPRateLimit, "apiiplimit")
+	flagVar(&APICORS, "apicors")
+	flagVar(&APIAccessLog, "apiaccesslog")
+	flagVar(&APIMethods, "apimethods")
+	flagVar(&APITLSCert, "apitlscert")
+	flagVar(&APITLSKey, "apitlskey")
+	flagVar(&APITLSClientCA, "apitlsclientca")
+
+	flagVar(&APISocket, "apisocket")
+	flagVar(&SocketMethods, "socketmethods")
+
+	flagVar(&AdminAddress, "adminaddress")
+	flagVar(&AdminUser, "adminuser")
+	flagVar(&AdminPassword, "adminpassword")
 
 	flagVar((*ecpub)(&ECPub), "ecpub")
 
@@ -151,6 +271,7 @@ func init() {
 	flagVar(&rpc.FactomdRPCPassword, "factomdpassword")
 	flagVar(&rpc.FactomdTLSCertFile, "factomdcert")
 	flagVar(&rpc.FactomdTLSEnable, "factomdtls")
+	flagVar(&_factom.FactomdQuorum, "factomdquorum")
 
 	flagVar(&rpc.WalletServer, "w")
 	flagVar(&rpc.WalletTimeout, "wallettimeout")
@@ -160,7 +281,8 @@ func init() {
 	flagVar(&rpc.WalletTLSEnable, "wallettls")
 
 	// Add flags for self installing the CLI completion tool
-	Completion = complete.New(os.Args[0], complete.Command{Flags: flags})
+	Completion = complete.New(os.Args[0], complete.Command{Flags: flags,
+		Sub: complete.Commands{"reindex": complete.Command{}}})
 	Completion.CLI.InstallName = "installcompletion"
 	Completion.CLI.UninstallName = "uninstallcompletion"
 	Completion.AddFlags(nil)
@@ -179,8 +301,26 @@ func Parse() {
 	loadFromEnv(&LogDebug, "debug")
 
 	loadFromEnv(&DBPath, "dbpath")
+	loadFromEnv(&Cache, "cache")
 
 	loadFromEnv(&APIAddress, "apiaddress")
+	loadFromEnv(&APIAuth, "apiauth")
+	loadFromEnv(&APIRequireAuth, "apirequireauth")
+	loadFromEnv(&APIRateLimit, "apiratelimit")
+	loadFromEnv(&APIIPRateLimit, "apiiplimit")
+	loadFromEnv(&APICORS, "apicors")
+	loadFromEnv(&APIAccessLog, "apiaccesslog")
+	loadFromEnv(&APIMethods, "apimethods")
+	loadFromEnv(&APITLSCert, "apitlscert")
+	loadFromEnv(&APITLSKey, "apitlskey")
+	loadFromEnv(&APITLSClientCA, "apitlsclientca")
+
+	loadFromEnv(&APISocket, "apisocket")
+	loadFromEnv(&SocketMethods, "socketmethods")
+
+	loadFromEnv(&AdminAddress, "adminaddress")
+	loadFromEnv(&AdminUser, "adminuser")
+	loadFromEnv(&AdminPassword, "adminpassword")
 
 	loadFromEnv(&rpc.FactomdServer, "s")
 	loadFromEnv(&rpc.FactomdTimeout, "factomdtimeout")
@@ -188,19 +328,44 @@ func Parse() {
 	loadFromEnv(&rpc.FactomdRPCPassword, "factomdpassword")
 	loadFromEnv(&rpc.FactomdTLSCertFile, "factomdcert")
 	loadFromEnv(&rpc.FactomdTLSEnable, "factomdtls")
+	loadFromEnv(&_factom.FactomdQuorum, "factomdquorum")
 
 	loadFromEnv(&rpc.WalletServer, "w")
-	loadFromEnv(&rpc.WalletTimeout, "walletdtimeout")
-	loadFromEnv(&rpc.WalletRPCUser, "factomduser")
-	loadFromEnv(&rpc.WalletRPCPassword, "factomdpassword")
-	loadFromEnv(&rpc.WalletTLSCertFile, "factomdcert")
-	loadFromEnv(&rpc.WalletTLSEnable, "factomdtls")
+	loadFromEnv(&rpc.WalletTimeout, "wallettimeout")
+	loadFromEnv(&rpc.WalletRPCUser, "walletuser")
+	loadFromEnv(&rpc.WalletRPCPassword, "walletpassword")
+	loadFromEnv(&rpc.WalletTLSCertFile, "walletcert")
+	loadFromEnv(&rpc.WalletTLSEnable, "wallettls")
 
 	loadFromEnv((*ecpub)(&ECPub), "ecpub")
 
 	if flagset["startscanheight"] {
 		StartScanHeight = int64(startScanHeight)
 	}
+
+	parseCommand()
+}
+
+// parseCommand parses the optional command following the flags:
+//
+//	fatd [flags] reindex [CHAINID...]
+func parseCommand() {
+	args := flag.Args()
+	if len(args) == 0 {
+		return
+	}
+	if args[0] != "reindex" {
+		log.Fatalf("unknown command: %#v", args[0])
+	}
+	Reindex = true
+	for _, arg := range args[1:] {
+		chainID := new(_factom.Bytes32)
+		if err := json.Unmarshal([]byte(fmt.Sprintf("%#v", arg)),
+			chainID); err != nil || len(arg) == 0 {
+			log.Fatalf("reindex: invalid Chain ID: %#v", arg)
+		}
+		ReindexChainIDs = append(ReindexChainIDs, chainID)
+	}
 }
 
 func Validate() {
@@ -211,19 +376,58 @@ func Validate() {
 	}
 
 	log.Debugf("-dbpath          %#v", DBPath)
+	log.Debugf("-cache           %v ", Cache)
 	log.Debugf("-apiaddress      %#v", APIAddress)
+	log.Debugf("-apiauth         %#v", APIAuth)
+	log.Debugf("-apirequireauth  %v ", APIRequireAuth)
+	log.Debugf("-apiratelimit    %v ", APIRateLimit)
+	log.Debugf("-apiiplimit      %v ", APIIPRateLimit)
+	log.Debugf("-apicors         %#v", APICORS)
+	log.Debugf("-apiaccesslog    %v ", APIAccessLog)
+	log.Debugf("-apimethods      %#v", APIMethods)
+	log.Debugf("-apitlscert      %#v", APITLSCert)
+	log.Debugf("-apitlskey       %#v", APITLSKey)
+	log.Debugf("-apitlsclientca  %#v", APITLSClientCA)
+	log.Debugf("-apisocket       %#v", APISocket)
+	log.Debugf("-socketmethods   %#v", SocketMethods)
 	log.Debugf("-startscanheight %v ", StartScanHeight)
 	debugPrintln()
 
+	adminPassword := "\"\""
+	if len(AdminPassword) > 0 {
+		adminPassword = "<redacted>"
+	}
+	log.Debugf("-adminaddress   %#v", AdminAddress)
+	log.Debugf("-adminuser      %#v", AdminUser)
+	log.Debugf("-adminpassword  %v ", adminPassword)
+	debugPrintln()
+
 	log.Debugf("-s              %#v", rpc.FactomdServer)
 	log.Debugf("-factomduser    %#v", rpc.FactomdRPCUser)
 	log.Debugf("-factomdpass    %v ", factomdRPCPassword)
 	log.Debugf("-factomdcert    %#v", rpc.FactomdTLSCertFile)
 	log.Debugf("-factomdtimeout %v ", rpc.FactomdTimeout)
+	log.Debugf("-factomdquorum  %v ", _factom.FactomdQuorum)
 	debugPrintln()
 
 	// Validate options
-
+	if len(AdminAddress) > 0 && len(AdminPassword) == 0 && len(APIAuth) == 0 {
+		log.Fatalf("-adminpassword or -apiauth is required with -adminaddress")
+	}
+	if APIRequireAuth && len(APIAuth) == 0 {
+		log.Fatalf("-apiauth is required with -apirequireauth")
+	}
+	if (len(APITLSCert) == 0) != (len(APITLSKey) == 0) {
+		log.Fatalf("-apitlscert and -apitlskey must be used together")
+	}
+	if len(APITLSClientCA) > 0 && len(APITLSCert) == 0 {
+		log.Fatalf("-apitlscert is required with -apitlsclientca")
+	}
+	servers := strings.Split(rpc.FactomdServer, ",")
+	if _factom.FactomdQuorum > uint64(len(servers)) {
+		log.Fatalf("-factomdquorum %v is greater than the number of "+
+			"factomd servers: %v", _factom.FactomdQuorum, len(servers))
+	}
 }
 
 func flagVar(v interface{}, name string) {
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"os/signal"
+	"syscall"
 
 	"github.com/Factom-Asset-Tokens/fatd/engine"
 	"github.com/Factom-Asset-Tokens/fatd/flag"
@@ -22,6 +23,14 @@ func _main() (ret int) {
 
 	log := log.New("main")
 
+	if flag.Reindex {
+		if err := engine.Reindex(flag.ReindexChainIDs); err != nil {
+			log.Errorf("engine.Reindex(): %v", err)
+			return 1
+		}
+		return 0
+	}
+
 	engineErrCh, err := engine.Start()
 	if err != nil {
 		log.Errorf("engine.Start(): %v", err)
@@ -37,7 +46,10 @@ func _main() (ret int) {
 	}()
 	log.Info("State engine started.")
 
-	srv.Start()
+	if err := srv.Start(); err != nil {
+		log.Errorf("srv.Start(): %v", err)
+		return 1
+	}
 	defer func() {
 		if err := srv.Stop(); err != nil {
 			log.Errorf("srv.Stop(): %v", err)
@@ -55,12 +67,27 @@ func _main() (ret int) {
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 
-	select {
-	case <-sig:
-		log.Infof("SIGINT: Shutting down now.")
-	case err := <-engineErrCh:
-		log.Errorf("engine: %v", err)
-	}
+	// SIGHUP reloads the TLS certificates.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 
-	return
+	for {
+		select {
+		case <-sig:
+			log.Infof("SIGINT: Shutting down now.")
+			return
+		case err := <-engineErrCh:
+			log.Errorf("engine: %v", err)
+			return
+		case <-hup:
+			if len(flag.APITLSCert) == 0 {
+				continue
+			}
+			if err := srv.ReloadTLS(); err != nil {
+				log.Errorf("SIGHUP: srv.ReloadTLS(): %v", err)
+				continue
+			}
+			log.Infof("SIGHUP: Reloaded TLS certificates.")
+		}
+	}
 }
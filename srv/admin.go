@@ -0,0 +1,76 @@
+package srv
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/engine"
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+)
+
+var adminMethods = jrpc.MethodMap{
+	"admin-track-chain":  adminChain(engine.TrackChain, "backfilling"),
+	"admin-rescan-chain": adminChain(engine.RescanChain, "backfilling"),
+	"admin-ignore-chain": adminChain(engine.IgnoreChain, "ignored"),
+	"admin-pause":        adminEngine(engine.Pause),
+	"admin-resume":       adminEngine(engine.Resume),
+}
+
+// AdminHandler returns the http.Handler that serves the admin JSON RPC 2.0
+// API. All requests must use -adminuser and -adminpassword, or a credential
+// from -apiauth with the admin permission. Configure must be called first.
+func AdminHandler() http.Handler {
+	return adminAuth.handler(jrpc.HTTPRequestHandler(adminMethods))
+}
+
+// equal compares a and b in constant time.
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+type ResultsAdminChain struct {
+	ChainID *factom.Bytes32 `json:"chainid"`
+	Status  string          `json:"status"`
+}
+
+// adminChain returns a method that calls f for the chain given by the params
+// and reports status if it succeeds.
+func adminChain(f func(*factom.Bytes32) error, status string) jrpc.MethodFunc {
+	return func(data json.RawMessage) interface{} {
+		params := ParamsToken{}
+		chainID, res := validate(data, &params)
+		if chainID == nil {
+			return res
+		}
+		switch err := f(chainID); err {
+		case nil:
+		case engine.ErrTracked:
+			return ErrorChainTracked
+		case engine.ErrNotTracked:
+			return ErrorChainNotTracked
+		case engine.ErrBackfilling:
+			return ErrorChainBackfilling
+		default:
+			panic(err)
+		}
+		return ResultsAdminChain{ChainID: chainID, Status: status}
+	}
+}
+
+type ResultsAdminEngine struct {
+	Paused bool `json:"paused"`
+}
+
+// adminEngine returns a method that calls f and reports whether the engine is
+// paused.
+func adminEngine(f func()) jrpc.MethodFunc {
+	return func(data json.RawMessage) interface{} {
+		if data != nil {
+			return ParamsErrorNoParams
+		}
+		f()
+		return ResultsAdminEngine{Paused: engine.IsPaused()}
+	}
+}
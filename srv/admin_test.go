@@ -0,0 +1,85 @@
+package srv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// post serves a request with body using h. If user is not empty, the request
+// uses HTTP basic auth.
+func post(h http.Handler, body, user, password string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if len(user) > 0 {
+		r.SetBasicAuth(user, password)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestAdminHandler(t *testing.T) {
+	flag.APIMethods = "read,send"
+	flag.SocketMethods = "read,send,admin"
+	flag.AdminUser, flag.AdminPassword = "admin", "password"
+	defer func() { flag.AdminUser, flag.AdminPassword = "", "" }()
+	require.NoError(t, Configure())
+	h := AdminHandler()
+
+	// The engine is not running, so the admin method is called with
+	// params, which it rejects before doing anything. This still shows
+	// that the request reached the method.
+	const (
+		pause = `{"jsonrpc":"2.0","method":"admin-pause","params":[],"id":1}`
+		read  = `{"jsonrpc":"2.0","method":"get-daemon-properties","id":1}`
+	)
+	for _, test := range []struct {
+		Name     string
+		Body     string
+		User     string
+		Password string
+		Status   int
+	}{{
+		Name:   "anonymous",
+		Body:   pause,
+		Status: http.StatusUnauthorized,
+	}, {
+		Name:     "invalid password",
+		Body:     pause,
+		User:     "admin",
+		Password: "wrong",
+		Status:   http.StatusUnauthorized,
+	}, {
+		Name:     "valid password",
+		Body:     pause,
+		User:     "admin",
+		Password: "password",
+		Status:   http.StatusOK,
+	}, {
+		Name:     "API method",
+		Body:     read,
+		User:     "admin",
+		Password: "password",
+		Status:   http.StatusForbidden,
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			w := post(h, test.Body, test.User, test.Password)
+			require.Equal(t, test.Status, w.Code, w.Body.String())
+			if w.Code != http.StatusOK {
+				return
+			}
+			var res jrpc.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			require.NotNil(t, res.Error)
+			assert.Equal(t, ParamsErrorNoParams.Code, res.Error.Code)
+			assert.Equal(t, ParamsErrorNoParams.Data, res.Error.Data)
+		})
+	}
+}
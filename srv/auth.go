@@ -0,0 +1,427 @@
+package srv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/flag"
+)
+
+// Permission is a set of permissions granted to an API credential.
+type Permission uint
+
+const (
+	// PermissionRead allows all methods that do not change any state.
+	PermissionRead Permission = 1 << iota
+	// PermissionSend allows send-transaction, which spends the Entry
+	// Credits of -ecpub.
+	PermissionSend
+	// PermissionAdmin allows the admin methods.
+	PermissionAdmin
+)
+
+var permissionNames = map[string]Permission{
+	"read":  PermissionRead,
+	"send":  PermissionSend,
+	"admin": PermissionAdmin,
+}
+
+func (p Permission) String() string {
+	var names []string
+	for _, name := range []string{"read", "send", "admin"} {
+		if p&permissionNames[name] != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// UnmarshalJSON unmarshals a list of permission names.
+func (p *Permission) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*p = 0
+	for _, name := range names {
+		perm, ok := permissionNames[name]
+		if !ok {
+			return fmt.Errorf("invalid permission: %#v", name)
+		}
+		*p |= perm
+	}
+	return nil
+}
+
+// Credential is an API user, authenticated by either HTTP basic auth with the
+// User and Password, or by an "Authorization: Bearer" header with the Key.
+type Credential struct {
+	// Name identifies the credential in the access log. It defaults to
+	// the User.
+	Name     string `json:"name"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Key      string `json:"key"`
+
+	Permissions Permission `json:"permissions"`
+
+	// RateLimit is the maximum requests per second, overriding
+	// -apiratelimit if not zero.
+	RateLimit uint64 `json:"ratelimit"`
+
+	limiter *limiter
+}
+
+// loadCredentials reads the JSON array of Credentials in the file fpath.
+func loadCredentials(fpath string) ([]*Credential, error) {
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	var creds []*Credential
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("%v: %v", fpath, err)
+	}
+	for i, cred := range creds {
+		if (len(cred.User) == 0) == (len(cred.Key) == 0) {
+			return nil, fmt.Errorf(`%v: credential %v: `+
+				`exactly one of "user" or "key" is required`,
+				fpath, i)
+		}
+		if len(cred.Name) == 0 {
+			cred.Name = cred.User
+		}
+		if len(cred.Name) == 0 {
+			cred.Name = fmt.Sprintf("key%v", i)
+		}
+	}
+	return creds, nil
+}
+
+// auth authenticates and rate limits requests and checks that the credential
+// used has the permissions required by each method.
+type auth struct {
+	credentials []*Credential
+	// anonymous is the permissions of requests without a credential.
+	anonymous Permission
+
+	ipLimits  *limiters
+	accessLog bool
+}
+
+var (
+	// apiAuth, adminAuth and socketAuth are set by Configure.
+	apiAuth    = &auth{anonymous: PermissionRead}
+	adminAuth  = &auth{}
+	socketAuth = &auth{anonymous: PermissionRead | PermissionSend |
+		PermissionAdmin}
+)
+
+// methodGroup returns the permission that grants a method.
+func methodGroup(method string) Permission {
+	if _, ok := adminMethods[method]; ok {
+		return PermissionAdmin
+	}
+	if method == "send-transaction" {
+		return PermissionSend
+	}
+	return PermissionRead
+}
+
+// methodPermission returns the permission required for a method.
+// send-transaction only requires PermissionSend if -ecpub is set, since
+// otherwise it cannot spend any Entry Credits.
+func methodPermission(method string) Permission {
+	perm := methodGroup(method)
+	if perm == PermissionSend && len(flag.ECPub) == 0 {
+		return PermissionRead
+	}
+	return perm
+}
+
+// Configure loads the API credentials from -apiauth and applies the
+// authentication, rate limit, access log and method set settings for Handler,
+// AdminHandler and the -apisocket. It is called by Start.
+func Configure() error {
+	var err error
+	if apiMethodSet, err = methodSet(flag.APIMethods); err != nil {
+		return fmt.Errorf("-apimethods: %v", err)
+	}
+	if socketMethodSet, err = methodSet(flag.SocketMethods); err != nil {
+		return fmt.Errorf("-socketmethods: %v", err)
+	}
+
+	var creds []*Credential
+	if len(flag.APIAuth) > 0 {
+		if creds, err = loadCredentials(flag.APIAuth); err != nil {
+			return err
+		}
+	}
+
+	apiAuth = &auth{credentials: creds, accessLog: flag.APIAccessLog}
+	if !flag.APIRequireAuth {
+		apiAuth.anonymous = PermissionRead
+	}
+	if flag.APIIPRateLimit > 0 {
+		apiAuth.ipLimits = newLimiters(flag.APIIPRateLimit)
+	}
+
+	adminAuth = &auth{accessLog: flag.APIAccessLog}
+	for _, cred := range creds {
+		if cred.Permissions&PermissionAdmin != 0 {
+			adminAuth.credentials = append(adminAuth.credentials, cred)
+		}
+	}
+	if len(flag.AdminPassword) > 0 {
+		adminAuth.credentials = append(adminAuth.credentials,
+			&Credential{Name: flag.AdminUser, User: flag.AdminUser,
+				Password:    flag.AdminPassword,
+				Permissions: PermissionAdmin})
+	}
+	adminAuth.ipLimits = apiAuth.ipLimits
+
+	// Access to the socket is controlled by its file permissions.
+	socketAuth = &auth{anonymous: PermissionRead | PermissionSend |
+		PermissionAdmin, accessLog: flag.APIAccessLog}
+
+	for _, cred := range creds {
+		if rate := cred.RateLimit; rate > 0 {
+			cred.limiter = newLimiter(rate)
+		} else if flag.APIRateLimit > 0 {
+			cred.limiter = newLimiter(flag.APIRateLimit)
+		}
+	}
+	return nil
+}
+
+// authenticate returns the credential for the Authorization header of r, or
+// nil if there is none. An error is returned if the header does not match any
+// credential. The header is ignored if there are no credentials, since it may
+// be intended for a proxy in front of fatd.
+func (a *auth) authenticate(r *http.Request) (*Credential, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) == 0 || len(a.credentials) == 0 {
+		return nil, nil
+	}
+	if user, password, ok := r.BasicAuth(); ok {
+		for _, cred := range a.credentials {
+			if len(cred.User) > 0 && equal(user, cred.User) &&
+				equal(password, cred.Password) {
+				return cred, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid user or password")
+	}
+	const bearer = "Bearer "
+	if strings.HasPrefix(header, bearer) {
+		key := strings.TrimPrefix(header, bearer)
+		for _, cred := range a.credentials {
+			if len(cred.Key) > 0 && equal(key, cred.Key) {
+				return cred, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid key")
+	}
+	return nil, fmt.Errorf("unsupported Authorization scheme")
+}
+
+// requestMethods returns the method of each JSON RPC request in body, which may
+// be a batch. The body is parsed the same way as by the jrpc handler. An error
+// is returned if the method of any request cannot be determined, so that no
+// method can be called without its permission being checked.
+func requestMethods(body []byte) ([]string, error) {
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("invalid JSON")
+	}
+	rawReqs := make([]json.RawMessage, 1)
+	if err := json.Unmarshal(body, &rawReqs); err != nil {
+		// The JSON is valid, so this is a single request.
+		rawReqs[0] = json.RawMessage(body)
+	}
+	if len(rawReqs) == 0 {
+		return nil, fmt.Errorf("empty batch")
+	}
+	methods := make([]string, len(rawReqs))
+	for i, rawReq := range rawReqs {
+		var req struct {
+			Method *string `json:"method"`
+		}
+		if err := json.Unmarshal(rawReq, &req); err != nil ||
+			req.Method == nil {
+			return nil, fmt.Errorf("request %v: missing method", i)
+		}
+		methods[i] = *req.Method
+	}
+	return methods, nil
+}
+
+// statusRecorder records the status written to an http.ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// handler returns an http.Handler that serves requests with h only if they
+// are within the rate limits and are authorized for all of their methods.
+func (a *auth) handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		name := "-"
+		var methods []string
+		if a.accessLog {
+			defer func() {
+				log.Infof("%v %v %v %v %v", ip, name,
+					strings.Join(methods, ","), rec.status,
+					time.Since(start))
+			}()
+		}
+
+		if a.ipLimits != nil && !a.ipLimits.allow(ip) {
+			tooManyRequests(rec)
+			return
+		}
+		cred, err := a.authenticate(r)
+		if err != nil {
+			unauthorized(rec, err.Error())
+			return
+		}
+		perms := a.anonymous
+		if cred != nil {
+			name, perms = cred.Name, cred.Permissions
+			if cred.limiter != nil && !cred.limiter.allow() {
+				tooManyRequests(rec)
+				return
+			}
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(rec, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if methods, err = requestMethods(body); err != nil {
+			http.Error(rec, "invalid JSON RPC request: "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+		for _, method := range methods {
+			required := methodPermission(method)
+			if perms&required == required {
+				continue
+			}
+			msg := fmt.Sprintf("%v requires permission: %v",
+				method, required)
+			if cred == nil {
+				unauthorized(rec, msg)
+				return
+			}
+			http.Error(rec, msg, http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(rec, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="fatd", Bearer`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
+
+func tooManyRequests(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, http.StatusText(http.StatusTooManyRequests),
+		http.StatusTooManyRequests)
+}
+
+// limiter is a token bucket that allows rate requests per second with bursts
+// of up to rate requests.
+type limiter struct {
+	sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate uint64) *limiter {
+	return &limiter{rate: float64(rate), tokens: float64(rate),
+		last: time.Now()}
+}
+
+// allow returns true if a request is allowed now, in which case it consumes a
+// token.
+func (l *limiter) allow() bool {
+	l.Lock()
+	defer l.Unlock()
+	l.refill(time.Now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+func (l *limiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+}
+
+// limiters holds a limiter for each key, such as a client IP address.
+// Limiters that have refilled completely are pruned periodically.
+type limiters struct {
+	sync.Mutex
+	rate   uint64
+	m      map[string]*limiter
+	pruned time.Time
+}
+
+const pruneInterval = time.Minute
+
+func newLimiters(rate uint64) *limiters {
+	return &limiters{rate: rate, m: make(map[string]*limiter),
+		pruned: time.Now()}
+}
+
+func (ls *limiters) allow(key string) bool {
+	ls.Lock()
+	now := time.Now()
+	if now.Sub(ls.pruned) > pruneInterval {
+		for k, l := range ls.m {
+			l.Lock()
+			l.refill(now)
+			full := l.tokens >= l.rate
+			l.Unlock()
+			if full {
+				delete(ls.m, k)
+			}
+		}
+		ls.pruned = now
+	}
+	l, ok := ls.m[key]
+	if !ok {
+		l = newLimiter(ls.rate)
+		ls.m[key] = l
+	}
+	ls.Unlock()
+	return l.allow()
+}
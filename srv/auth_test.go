@@ -0,0 +1,308 @@
+package srv
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testECPub = "EC2DKSYyRcNWf7RS963VFYgMExoHRYLHVeCfQ9PGPmNzwrcmgm2r"
+
+// serve returns the status of the response from a.handler for a request with
+// body and the given Authorization header, and whether the request reached
+// the wrapped handler.
+func (a *auth) serve(body, authorization string) (int, bool) {
+	var reached bool
+	h := a.handler(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		reached = true
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if len(authorization) > 0 {
+		r.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, reached
+}
+
+func TestAuthRequestMethods(t *testing.T) {
+	flag.ECPub = testECPub
+	defer func() { flag.ECPub = "" }()
+	a := &auth{anonymous: PermissionRead}
+
+	const (
+		read = `{"jsonrpc":"2.0","method":"get-daemon-properties","id":1}`
+		send = `{"jsonrpc":"2.0","method":"send-transaction","id":2}`
+	)
+	for _, test := range []struct {
+		Name    string
+		Body    string
+		Status  int
+		Reached bool
+	}{{
+		Name:    "request",
+		Body:    read,
+		Status:  http.StatusOK,
+		Reached: true,
+	}, {
+		Name:   "request without permission",
+		Body:   send,
+		Status: http.StatusUnauthorized,
+	}, {
+		Name:    "batch",
+		Body:    "[" + read + "," + read + "]",
+		Status:  http.StatusOK,
+		Reached: true,
+	}, {
+		Name:   "batch without permission",
+		Body:   "[" + read + "," + send + "]",
+		Status: http.StatusUnauthorized,
+	}, {
+		Name:   "batch with malformed request",
+		Body:   "[" + send + ",1]",
+		Status: http.StatusBadRequest,
+	}, {
+		Name:   "batch with missing method",
+		Body:   `[` + read + `,{"jsonrpc":"2.0","id":3}]`,
+		Status: http.StatusBadRequest,
+	}, {
+		Name:   "empty batch",
+		Body:   "[]",
+		Status: http.StatusBadRequest,
+	}, {
+		Name:   "invalid method",
+		Body:   `{"jsonrpc":"2.0","method":1,"id":1}`,
+		Status: http.StatusBadRequest,
+	}, {
+		Name:   "invalid JSON",
+		Body:   `{"jsonrpc":"2.0","method":"send-transaction",`,
+		Status: http.StatusBadRequest,
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			status, reached := a.serve(test.Body, "")
+			assert.Equal(t, test.Status, status)
+			assert.Equal(t, test.Reached, reached)
+		})
+	}
+}
+
+func basicAuth(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString(
+		[]byte(user+":"+password))
+}
+
+func TestAuthPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fatd-auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	creds := filepath.Join(dir, "auth.json")
+	require.NoError(t, ioutil.WriteFile(creds, []byte(`[
+		{"user": "reader", "password": "pw", "permissions": ["read"]},
+		{"key": "sendkey", "permissions": ["read", "send"]},
+		{"user": "admin", "password": "pw",
+			"permissions": ["read", "send", "admin"]}
+	]`), 0600))
+
+	flag.APIMethods = "read,send"
+	flag.SocketMethods = "read,send,admin"
+	flag.APIAuth = creds
+	flag.ECPub = testECPub
+	defer func() {
+		flag.APIAuth = ""
+		flag.APIRequireAuth = false
+		flag.ECPub = ""
+	}()
+
+	const (
+		read  = `{"jsonrpc":"2.0","method":"get-daemon-properties","id":1}`
+		send  = `{"jsonrpc":"2.0","method":"send-transaction","id":1}`
+		admin = `{"jsonrpc":"2.0","method":"admin-pause","id":1}`
+	)
+	for _, test := range []struct {
+		Name          string
+		RequireAuth   bool
+		Admin         bool
+		Body          string
+		Authorization string
+		Status        int
+	}{{
+		Name:   "anonymous read",
+		Body:   read,
+		Status: http.StatusOK,
+	}, {
+		Name:   "anonymous send",
+		Body:   send,
+		Status: http.StatusUnauthorized,
+	}, {
+		Name:        "anonymous read with -apirequireauth",
+		RequireAuth: true,
+		Body:        read,
+		Status:      http.StatusUnauthorized,
+	}, {
+		Name:          "read with -apirequireauth",
+		RequireAuth:   true,
+		Body:          read,
+		Authorization: basicAuth("reader", "pw"),
+		Status:        http.StatusOK,
+	}, {
+		Name:          "send without permission",
+		Body:          send,
+		Authorization: basicAuth("reader", "pw"),
+		Status:        http.StatusForbidden,
+	}, {
+		Name:          "send with key",
+		Body:          send,
+		Authorization: "Bearer sendkey",
+		Status:        http.StatusOK,
+	}, {
+		Name:          "send with admin",
+		Body:          send,
+		Authorization: basicAuth("admin", "pw"),
+		Status:        http.StatusOK,
+	}, {
+		Name:          "invalid password",
+		Body:          read,
+		Authorization: basicAuth("reader", "wrong"),
+		Status:        http.StatusUnauthorized,
+	}, {
+		Name:          "password for key",
+		Body:          read,
+		Authorization: basicAuth("", "sendkey"),
+		Status:        http.StatusUnauthorized,
+	}, {
+		Name:          "invalid key",
+		Body:          read,
+		Authorization: "Bearer wrong",
+		Status:        http.StatusUnauthorized,
+	}, {
+		Name:          "unsupported scheme",
+		Body:          read,
+		Authorization: "Digest username=\"reader\"",
+		Status:        http.StatusUnauthorized,
+	}, {
+		Name:          "admin method without permission",
+		Body:          admin,
+		Authorization: "Bearer sendkey",
+		Status:        http.StatusForbidden,
+	}, {
+		Name:   "admin anonymous",
+		Admin:  true,
+		Body:   admin,
+		Status: http.StatusUnauthorized,
+	}, {
+		Name:          "admin without permission",
+		Admin:         true,
+		Body:          admin,
+		Authorization: basicAuth("reader", "pw"),
+		Status:        http.StatusUnauthorized,
+	}, {
+		Name:          "admin",
+		Admin:         true,
+		Body:          admin,
+		Authorization: basicAuth("admin", "pw"),
+		Status:        http.StatusOK,
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			flag.APIRequireAuth = test.RequireAuth
+			require.NoError(t, Configure())
+			a := apiAuth
+			if test.Admin {
+				a = adminAuth
+			}
+			status, reached := a.serve(test.Body, test.Authorization)
+			assert.Equal(t, test.Status, status)
+			assert.Equal(t, test.Status == http.StatusOK, reached)
+		})
+	}
+}
+
+func TestAuthIgnoresHeaderWithoutCredentials(t *testing.T) {
+	a := &auth{anonymous: PermissionRead}
+	status, reached := a.serve(
+		`{"jsonrpc":"2.0","method":"get-daemon-properties","id":1}`,
+		"Bearer for-a-proxy")
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, reached)
+}
+
+func TestLimiter(t *testing.T) {
+	assert := assert.New(t)
+	l := newLimiter(2)
+	assert.True(l.allow())
+	assert.True(l.allow())
+	assert.False(l.allow())
+
+	// Half a second refills one token.
+	l.last = l.last.Add(-500 * time.Millisecond)
+	assert.True(l.allow())
+	assert.False(l.allow())
+
+	// The bucket never holds more than rate tokens.
+	l.last = l.last.Add(-time.Hour)
+	assert.True(l.allow())
+	assert.True(l.allow())
+	assert.False(l.allow())
+}
+
+func TestLimiters(t *testing.T) {
+	assert := assert.New(t)
+	ls := newLimiters(1)
+	assert.True(ls.allow("a"))
+	assert.False(ls.allow("a"))
+	assert.True(ls.allow("b"))
+	assert.Len(ls.m, 2)
+
+	// Limiters that have refilled completely are pruned.
+	ls.m["a"].last = ls.m["a"].last.Add(-time.Second)
+	ls.pruned = ls.pruned.Add(-2 * pruneInterval)
+	assert.True(ls.allow("c"))
+	assert.Len(ls.m, 2)
+	assert.NotContains(ls.m, "a")
+	assert.Contains(ls.m, "b")
+}
+
+func TestAuthRateLimit(t *testing.T) {
+	const read = `{"jsonrpc":"2.0","method":"get-daemon-properties","id":1}`
+
+	t.Run("credential", func(t *testing.T) {
+		cred := &Credential{Name: "reader", Key: "key",
+			Permissions: PermissionRead, limiter: newLimiter(1)}
+		a := &auth{credentials: []*Credential{cred}}
+		status, _ := a.serve(read, "Bearer key")
+		assert.Equal(t, http.StatusOK, status)
+		status, reached := a.serve(read, "Bearer key")
+		assert.Equal(t, http.StatusTooManyRequests, status)
+		assert.False(t, reached)
+	})
+
+	t.Run("IP", func(t *testing.T) {
+		a := &auth{anonymous: PermissionRead, ipLimits: newLimiters(1)}
+		h := a.handler(http.HandlerFunc(func(http.ResponseWriter,
+			*http.Request) {
+		}))
+		serve := func(remoteAddr string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "/",
+				strings.NewReader(read))
+			r.RemoteAddr = remoteAddr
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			return w
+		}
+		assert.Equal(t, http.StatusOK, serve("192.0.2.1:1000").Code)
+		w := serve("192.0.2.1:1001")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+		assert.Equal(t, http.StatusOK, serve("192.0.2.2:1000").Code)
+	})
+}
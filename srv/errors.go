@@ -9,13 +9,21 @@ var (
 	ParamsErrorGetTransaction = jrpc.NewInvalidParamsError(
 		`required: "entryhash" and either "chainid" or both "tokenid" and "issuerid"`)
 	ParamsErrorGetTransactions = jrpc.NewInvalidParamsError(
-		`required: "hash" or "start" and either "chainid" or both "tokenid" and "issuerid", "limit" must be greater than 0 if provided`)
+		`required: either "chainid" or both "tokenid" and "issuerid", optional: "entryhash" or "cursor", "start", "limit" must be greater than 0 if provided, "order" must be "asc" or "desc" if provided, "startheight" and "starttime" must not be after "endheight" and "endtime"`)
 	ParamsErrorGetNFToken = jrpc.NewInvalidParamsError(
 		`required: "nftokenid" and either "chainid" or both "tokenid" and "issuerid"`)
+	ParamsErrorGetNFBalance = jrpc.NewInvalidParamsError(
+		`required: "address" and either "chainid" or both "tokenid" and "issuerid"`)
 	ParamsErrorGetBalance = jrpc.NewInvalidParamsError(
 		`required: "address" and either "chainid" or both "tokenid" and "issuerid"`)
 	ParamsErrorSendTransaction = jrpc.NewInvalidParamsError(
 		`required: "rcd-sigs" and "tx" and either "chainid" or both "tokenid" and "issuerid"`)
+	ParamsErrorGetHoldersSnapshot = jrpc.NewInvalidParamsError(
+		`required: "height" and either "chainid" or both "tokenid" and "issuerid"`)
+	ParamsErrorGetBlockTransactions = jrpc.NewInvalidParamsError(
+		`required: "height"`)
+	ParamsErrorGetAddressBalances = jrpc.NewInvalidParamsError(
+		`required: "addresses" with at least one address`)
 
 	ErrorTokenNotFound = jrpc.NewError(-32800, "Token Not Found",
 		"token may be invalid, or not yet issued or tracked")
@@ -28,4 +36,14 @@ var (
 		"token is in the process of syncing")
 	ErrorNoEC = jrpc.NewError(-32806, "No Entry Credits",
 		"not configured with entry credits")
+	ErrorBlockNotSynced = jrpc.NewError(-32807, "Block Not Synced",
+		"block height has not yet been synced")
+	ErrorChainTracked = jrpc.NewError(-32808, "Chain Already Tracked",
+		"use admin-rescan-chain to rebuild a tracked chain")
+	ErrorChainNotTracked = jrpc.NewError(-32809, "Chain Not Tracked",
+		"use admin-track-chain to track a chain")
+	ErrorChainBackfilling = jrpc.NewError(-32810, "Chain Backfilling",
+		"chain is already being backfilled")
+	ErrorTokenNotMigrated = jrpc.NewError(-32811, "Token Not Migrated",
+		"restart fatd while factomd is reachable to migrate the token database")
 )
@@ -0,0 +1,168 @@
+package srv
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/flag"
+)
+
+var (
+	// apiMethodSet and socketMethodSet are set by Configure.
+	apiMethodSet    = jrpcMethods
+	socketMethodSet = allMethods()
+)
+
+// allMethods returns a MethodMap with both the API and admin methods.
+func allMethods() jrpc.MethodMap {
+	methods := make(jrpc.MethodMap, len(jrpcMethods)+len(adminMethods))
+	for name, f := range jrpcMethods {
+		methods[name] = f
+	}
+	for name, f := range adminMethods {
+		methods[name] = f
+	}
+	return methods
+}
+
+// methodSet returns the API and admin methods selected by list, a comma
+// separated list of method names and permission names. A permission name
+// selects all methods that it grants.
+func methodSet(list string) (jrpc.MethodMap, error) {
+	all := allMethods()
+	methods := make(jrpc.MethodMap)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		if f, ok := all[name]; ok {
+			methods[name] = f
+			continue
+		}
+		perm, ok := permissionNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown method or permission: %#v",
+				name)
+		}
+		for method, f := range all {
+			if methodGroup(method) == perm {
+				methods[method] = f
+			}
+		}
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no methods")
+	}
+	return methods, nil
+}
+
+// tlsConfig holds the *tls.Config for the current -apitlscert, which is
+// replaced by ReloadTLS.
+var tlsConfig atomic.Value
+
+// ReloadTLS loads the -apitlscert, -apitlskey and -apitlsclientca files and
+// uses them for all subsequent TLS connections. It is called by Start, and
+// again on SIGHUP so that certificates can be renewed without a restart. If
+// there is an error, the previous configuration remains in use.
+func ReloadTLS() error {
+	if len(flag.APITLSCert) == 0 {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(flag.APITLSCert, flag.APITLSKey)
+	if err != nil {
+		return err
+	}
+	// The config is used as is by GetConfigForClient, so it must
+	// advertise HTTP/2 itself, as http.Server only does so for the config
+	// it is given.
+	config := &tls.Config{Certificates: []tls.Certificate{cert},
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"}}
+	if len(flag.APITLSClientCA) > 0 {
+		pem, err := ioutil.ReadFile(flag.APITLSClientCA)
+		if err != nil {
+			return err
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%v: no certificates found",
+				flag.APITLSClientCA)
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	tlsConfig.Store(config)
+	return nil
+}
+
+// newTLSConfig returns a *tls.Config for an http.Server that always uses the
+// latest configuration loaded by ReloadTLS.
+func newTLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &tlsConfig.Load().(*tls.Config).Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return tlsConfig.Load().(*tls.Config), nil
+		},
+	}
+}
+
+// serve h on the TCP address addr, using TLS if -apitlscert is set.
+func serve(name, addr string, h http.Handler) (*http.Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: h}
+	if len(flag.APITLSCert) == 0 {
+		go func() {
+			if err := srv.Serve(l); err != http.ErrServerClosed {
+				log.Errorf("%v: Serve(): %v", name, err)
+			}
+		}()
+		return srv, nil
+	}
+	srv.TLSConfig = newTLSConfig()
+	go func() {
+		if err := srv.ServeTLS(l, "", ""); err != http.ErrServerClosed {
+			log.Errorf("%v: ServeTLS(): %v", name, err)
+		}
+	}()
+	return srv, nil
+}
+
+// serveSocket serves h on the Unix domain socket at fpath, replacing any
+// socket left behind by a previous run. The socket is only accessible by the
+// user running fatd.
+func serveSocket(fpath string, h http.Handler) (*http.Server, error) {
+	if fi, err := os.Lstat(fpath); err == nil &&
+		fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(fpath); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", fpath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(fpath, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	srv := &http.Server{Handler: h}
+	go func() {
+		if err := srv.Serve(l); err != http.ErrServerClosed {
+			log.Errorf("socket: Serve(): %v", err)
+		}
+	}()
+	return srv, nil
+}
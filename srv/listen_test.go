@@ -0,0 +1,258 @@
+package srv
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodSet(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		List    string
+		Methods []string
+		Error   string
+	}{{
+		Name:    "send",
+		List:    "send",
+		Methods: []string{"send-transaction"},
+	}, {
+		Name: "admin",
+		List: "admin",
+		Methods: []string{"admin-ignore-chain", "admin-pause",
+			"admin-rescan-chain", "admin-resume",
+			"admin-track-chain"},
+	}, {
+		Name: "methods and permissions",
+		List: " get-balance,send,, admin-pause ",
+		Methods: []string{"admin-pause", "get-balance",
+			"send-transaction"},
+	}, {
+		Name:  "unknown",
+		List:  "read,get-everything",
+		Error: `unknown method or permission: "get-everything"`,
+	}, {
+		Name:  "empty",
+		List:  " , ",
+		Error: "no methods",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			methods, err := methodSet(test.List)
+			if len(test.Error) > 0 {
+				assert.EqualError(t, err, test.Error)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for name := range methods {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			assert.Equal(t, test.Methods, names)
+		})
+	}
+
+	// The read permission selects every API method except
+	// send-transaction, regardless of -ecpub.
+	methods, err := methodSet("read")
+	require.NoError(t, err)
+	assert.Len(t, methods, len(jrpcMethods)-1)
+	assert.NotContains(t, methods, "send-transaction")
+}
+
+func TestHandlerCORS(t *testing.T) {
+	defer func(cors string) { flag.APICORS = cors }(flag.APICORS)
+	for _, test := range []struct {
+		Name    string
+		CORS    string
+		Origin  string
+		Allowed string
+	}{{
+		Name:    "all",
+		CORS:    "*",
+		Origin:  "https://example.com",
+		Allowed: "*",
+	}, {
+		Name:    "listed",
+		CORS:    "https://a.example, https://example.com",
+		Origin:  "https://example.com",
+		Allowed: "https://example.com",
+	}, {
+		Name:   "not listed",
+		CORS:   "https://a.example",
+		Origin: "https://example.com",
+	}, {
+		Name:   "none",
+		CORS:   "",
+		Origin: "https://example.com",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			flag.APICORS = test.CORS
+			r := httptest.NewRequest(http.MethodOptions, "/", nil)
+			r.Header.Set("Origin", test.Origin)
+			r.Header.Set("Access-Control-Request-Method", "POST")
+			w := httptest.NewRecorder()
+			Handler().ServeHTTP(w, r)
+			assert.Equal(t, test.Allowed,
+				w.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func TestListenerMethodSets(t *testing.T) {
+	flag.APIMethods = "get-daemon-properties"
+	flag.SocketMethods = "admin"
+	defer func() {
+		flag.APIMethods = "read,send"
+		flag.SocketMethods = "read,send,admin"
+		require.NoError(t, Configure())
+	}()
+	require.NoError(t, Configure())
+
+	const (
+		read  = `{"jsonrpc":"2.0","method":"get-daemon-properties","id":1}`
+		other = `{"jsonrpc":"2.0","method":"get-sync-status","id":1}`
+		admin = `{"jsonrpc":"2.0","method":"admin-pause","params":[],"id":1}`
+	)
+	for _, test := range []struct {
+		Name    string
+		Handler http.Handler
+		Body    string
+		Error   *jrpc.Error
+	}{{
+		Name:    "API method",
+		Handler: Handler(),
+		Body:    read,
+	}, {
+		Name:    "API method not in set",
+		Handler: Handler(),
+		Body:    other,
+		Error:   &jrpc.Error{Code: jrpc.MethodNotFoundCode},
+	}, {
+		Name:    "socket method",
+		Handler: socketHandler(),
+		Body:    admin,
+		Error:   &ParamsErrorNoParams,
+	}, {
+		Name:    "socket method not in set",
+		Handler: socketHandler(),
+		Body:    read,
+		Error:   &jrpc.Error{Code: jrpc.MethodNotFoundCode},
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			w := post(test.Handler, test.Body, "", "")
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var res jrpc.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			if test.Error == nil {
+				assert.Nil(t, res.Error)
+				return
+			}
+			require.NotNil(t, res.Error)
+			assert.Equal(t, test.Error.Code, res.Error.Code)
+		})
+	}
+}
+
+// writeCert writes a new self signed certificate for 127.0.0.1 and its key to
+// certFile and keyFile, and returns the certificate.
+func writeCert(t *testing.T, certFile, keyFile string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "fatd"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl,
+		&key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(
+		&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestReloadTLS(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "fatd-tls")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	flag.APITLSCert = filepath.Join(dir, "cert.pem")
+	flag.APITLSKey = filepath.Join(dir, "key.pem")
+	defer func() { flag.APITLSCert, flag.APITLSKey = "", "" }()
+	first := writeCert(t, flag.APITLSCert, flag.APITLSKey)
+	require.NoError(ReloadTLS())
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	addr := l.Addr().String()
+	l.Close()
+	srv, err := serve("api", addr, http.HandlerFunc(
+		func(http.ResponseWriter, *http.Request) {}))
+	require.NoError(err)
+	defer srv.Close()
+
+	// peerCert returns the certificate presented by the server.
+	peerCert := func() *x509.Certificate {
+		conn, err := tls.Dial("tcp", addr,
+			&tls.Config{InsecureSkipVerify: true})
+		require.NoError(err)
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0]
+	}
+	assert.Equal(first.SerialNumber, peerCert().SerialNumber)
+
+	// HTTP/2 is negotiated with the reloadable config.
+	roots := x509.NewCertPool()
+	roots.AddCert(first)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+	res, err := client.Get("https://" + addr)
+	require.NoError(err)
+	res.Body.Close()
+	assert.Equal(2, res.ProtoMajor)
+
+	// New connections use the reloaded certificate.
+	second := writeCert(t, flag.APITLSCert, flag.APITLSKey)
+	require.NoError(ReloadTLS())
+	assert.Equal(second.SerialNumber, peerCert().SerialNumber)
+
+	// A failed reload keeps the previous certificate.
+	require.NoError(ioutil.WriteFile(flag.APITLSKey, []byte("invalid"), 0600))
+	assert.Error(ReloadTLS())
+	assert.Equal(second.SerialNumber, peerCert().SerialNumber)
+}
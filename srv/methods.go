@@ -2,13 +2,16 @@ package srv
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 
 	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
+	"github.com/Factom-Asset-Tokens/fatd/engine"
 	"github.com/Factom-Asset-Tokens/fatd/factom"
 	"github.com/Factom-Asset-Tokens/fatd/fat"
 	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
 	"github.com/Factom-Asset-Tokens/fatd/flag"
 	"github.com/Factom-Asset-Tokens/fatd/state"
 )
@@ -21,13 +24,18 @@ var jrpcMethods = jrpc.MethodMap{
 	"get-transactions":       getTransactions(false),
 	"get-transactions-entry": getTransactions(true),
 	"get-balance":            getBalance,
+	"get-holders-snapshot":   getHoldersSnapshot,
+	"get-block-transactions": getBlockTransactions,
+	"get-address-balances":   getAddressBalances,
 	"get-stats":              getStats,
 	"get-nf-token":           getNFToken,
+	"get-nf-balance":         getNFBalance,
 
 	"send-transaction": sendTransaction,
 
 	"get-daemon-tokens":     getDaemonTokens,
 	"get-daemon-properties": getDaemonProperties,
+	"get-sync-status":       getSyncStatus,
 }
 
 type ResultsGetIssuance struct {
@@ -67,9 +75,24 @@ func getIssuance(entry bool) jrpc.MethodFunc {
 }
 
 type ResultsGetTransaction struct {
-	Hash      *factom.Bytes32  `json:"entryhash"`
-	Timestamp *factom.Time     `json:"timestamp"`
-	Tx        fat0.Transaction `json:"data"`
+	Hash      *factom.Bytes32 `json:"entryhash"`
+	Timestamp *factom.Time    `json:"timestamp"`
+	// Tx is a fat0.Transaction or fat1.Transaction depending on the
+	// token's type.
+	Tx interface{} `json:"data"`
+}
+
+// unmarshalTransaction returns e unmarshaled as a transaction of the token
+// type of chain.
+func unmarshalTransaction(chain state.Chain, e factom.Entry) (interface{}, error) {
+	if chain.Issuance.Type == fat.TypeFAT1 {
+		tx := fat1.NewTransaction(e)
+		err := tx.UnmarshalEntry()
+		return tx, err
+	}
+	tx := fat0.NewTransaction(e)
+	err := tx.UnmarshalEntry()
+	return tx, err
 }
 
 func getTransaction(entry bool) jrpc.MethodFunc {
@@ -96,17 +119,28 @@ func getTransaction(entry bool) jrpc.MethodFunc {
 		if entry {
 			return transaction.Entry.Entry
 		}
-		if err := transaction.UnmarshalEntry(); err != nil {
+		tx, err := unmarshalTransaction(chain, transaction.Entry.Entry)
+		if err != nil {
 			panic(err)
 		}
 		return ResultsGetTransaction{
 			Hash:      transaction.Hash,
 			Timestamp: transaction.Timestamp,
-			Tx:        transaction,
+			Tx:        tx,
 		}
 	}
 }
 
+type ResultsGetTransactions struct {
+	Transactions []ResultsGetTransaction `json:"transactions"`
+	NextCursor   uint64                  `json:"nextcursor,omitempty"`
+}
+
+type ResultsGetTransactionsEntry struct {
+	Entries    []factom.Entry `json:"entries"`
+	NextCursor uint64         `json:"nextcursor,omitempty"`
+}
+
 func getTransactions(entry bool) jrpc.MethodFunc {
 	return func(data json.RawMessage) interface{} {
 		params := ParamsGetTransactions{}
@@ -120,9 +154,10 @@ func getTransactions(entry bool) jrpc.MethodFunc {
 		if !chain.IsIssued() {
 			return ErrorTokenNotFound
 		}
-		transactions, err := chain.GetTransactions(params.Hash,
-			params.FactoidAddress, params.ToFrom,
-			*params.Start, *params.Limit)
+		transactions, next, err := chain.GetTransactions(params.Query())
+		if err == state.ErrNotMigrated {
+			return ErrorTokenNotMigrated
+		}
 		if err != nil {
 			log.Debug(err)
 			panic(err)
@@ -136,17 +171,27 @@ func getTransactions(entry bool) jrpc.MethodFunc {
 				txs[i] = transactions[i].Entry.Entry
 				txs[i].ChainID = nil
 			}
-			return txs
+			return ResultsGetTransactionsEntry{
+				Entries: txs, NextCursor: next}
 		}
 
 		txs := make([]ResultsGetTransaction, len(transactions))
 		for i := range txs {
 			txs[i].Hash = transactions[i].Hash
 			txs[i].Timestamp = transactions[i].Timestamp
-			txs[i].Tx = transactions[i]
+			if chain.Issuance.Type == fat.TypeFAT0 {
+				txs[i].Tx = transactions[i]
+				continue
+			}
+			tx, err := unmarshalTransaction(chain,
+				transactions[i].Entry.Entry)
+			if err != nil {
+				panic(err)
+			}
+			txs[i].Tx = tx
 		}
 
-		return txs
+		return ResultsGetTransactions{Transactions: txs, NextCursor: next}
 	}
 }
 
@@ -169,6 +214,187 @@ func getBalance(data json.RawMessage) interface{} {
 	return balance
 }
 
+type ResultsGetHoldersSnapshot struct {
+	Height  uint64                `json:"height"`
+	Holders fat0.AddressAmountMap `json:"holders,omitempty"`
+}
+
+func getHoldersSnapshot(data json.RawMessage) interface{} {
+	params := ParamsGetHoldersSnapshot{}
+	chainID, res := validate(data, &params)
+	if chainID == nil {
+		return res
+	}
+
+	chain := state.Chains.Get(chainID)
+	if !chain.IsIssued() {
+		return ErrorTokenNotFound
+	}
+	// We can only compute the balances for heights that have already
+	// been synced.
+	if *params.Height > chain.Metadata.Height {
+		return ErrorTokenSyncing
+	}
+	// Databases created by older versions of fatd do not record the
+	// height of each entry until they are migrated.
+	if !chain.IsMigrated() {
+		return ErrorTokenNotMigrated
+	}
+	holders, err := chain.GetHolders(*params.Height)
+	if err != nil {
+		panic(err)
+	}
+	return ResultsGetHoldersSnapshot{Height: *params.Height, Holders: holders}
+}
+
+type ResultsGetBlockTransactions struct {
+	Height uint64                        `json:"height"`
+	Chains []ResultsGetBlockChainEntries `json:"chains"`
+}
+
+// ResultsGetBlockChainEntries holds the entries of a single token chain that
+// were processed in a block.
+type ResultsGetBlockChainEntries struct {
+	ParamsToken
+	Issuance     *ResultsGetIssuance     `json:"issuance,omitempty"`
+	Transactions []ResultsGetTransaction `json:"transactions,omitempty"`
+	Rejected     []ResultsRejectedEntry  `json:"rejected,omitempty"`
+}
+
+type ResultsRejectedEntry struct {
+	Hash  *factom.Bytes32 `json:"entryhash"`
+	Error string          `json:"error"`
+}
+
+func getBlockTransactions(data json.RawMessage) interface{} {
+	params := ParamsGetBlockTransactions{}
+	if chainID, res := validate(data, &params); chainID == nil {
+		return res
+	}
+	if *params.Height > state.SavedHeight {
+		return ErrorBlockNotSynced
+	}
+
+	bes, err := state.GetBlockEntries(*params.Height)
+	if err != nil {
+		panic(err)
+	}
+
+	// Group the entries by chain in the order that the chains were first
+	// processed.
+	results := ResultsGetBlockTransactions{Height: *params.Height,
+		Chains: []ResultsGetBlockChainEntries{}}
+	chainIndex := make(map[factom.Bytes32]int)
+	for _, be := range bes {
+		chain := state.Chains.Get(be.ChainID)
+		i, ok := chainIndex[*be.ChainID]
+		if !ok {
+			i = len(results.Chains)
+			chainIndex[*be.ChainID] = i
+			results.Chains = append(results.Chains,
+				ResultsGetBlockChainEntries{
+					ParamsToken: ParamsToken{
+						ChainID:       be.ChainID,
+						TokenID:       chain.Token,
+						IssuerChainID: chain.Identity.ChainID,
+					}})
+		}
+		res := &results.Chains[i]
+		if !be.IsValid() {
+			res.Rejected = append(res.Rejected, ResultsRejectedEntry{
+				Hash: be.Hash, Error: be.Rejected})
+			continue
+		}
+
+		if chain.IsIssued() && *chain.Issuance.Hash == *be.Hash {
+			res.Issuance = &ResultsGetIssuance{
+				ParamsToken: ParamsToken{
+					ChainID:       be.ChainID,
+					TokenID:       chain.Token,
+					IssuerChainID: chain.Identity.ChainID,
+				},
+				Hash:      chain.Issuance.Hash,
+				Timestamp: chain.Issuance.Timestamp,
+				Issuance:  chain.Issuance,
+			}
+			continue
+		}
+		transaction, err := chain.GetTransaction(be.Hash)
+		if err != nil {
+			panic(err)
+		}
+		if !transaction.IsPopulated() {
+			continue
+		}
+		tx, err := unmarshalTransaction(chain, transaction.Entry.Entry)
+		if err != nil {
+			panic(err)
+		}
+		res.Transactions = append(res.Transactions, ResultsGetTransaction{
+			Hash:      transaction.Hash,
+			Timestamp: transaction.Timestamp,
+			Tx:        tx,
+		})
+	}
+	return results
+}
+
+type ResultsGetAddressBalances struct {
+	Address  factom.Address        `json:"address"`
+	Balances []ResultsTokenBalance `json:"balances"`
+}
+
+// ResultsTokenBalance is the balance of an address for a single token. For
+// FAT-1 tokens the balance is the number of non-fungible tokens held.
+type ResultsTokenBalance struct {
+	ParamsToken
+	Type    fat.Type `json:"type"`
+	Balance uint64   `json:"balance"`
+}
+
+func getAddressBalances(data json.RawMessage) interface{} {
+	params := ParamsGetAddressBalances{}
+	if chainID, res := validate(data, &params); chainID == nil {
+		return res
+	}
+
+	results := make([]ResultsGetAddressBalances, len(params.Addresses))
+	for i, adr := range params.Addresses {
+		results[i].Address = adr
+		results[i].Balances = []ResultsTokenBalance{}
+		// Only look up the chains that the address has ever received
+		// tokens on.
+		chainIDs, err := state.GetAddressChains(adr)
+		if err != nil {
+			panic(err)
+		}
+		for _, chainID := range chainIDs {
+			chain := state.Chains.Get(chainID)
+			if !chain.IsIssued() {
+				continue
+			}
+			balance, err := chain.GetBalance(adr)
+			if err != nil {
+				panic(err)
+			}
+			if balance == 0 {
+				continue
+			}
+			results[i].Balances = append(results[i].Balances,
+				ResultsTokenBalance{
+					ParamsToken: ParamsToken{
+						ChainID:       chainID,
+						TokenID:       chain.Token,
+						IssuerChainID: chain.Identity.ChainID,
+					},
+					Type:    chain.Issuance.Type,
+					Balance: balance,
+				})
+		}
+	}
+	return results
+}
+
 type ResultsGetStats struct {
 	Supply                   int64        `json:"supply"`
 	CirculatingSupply        uint64       `json:"circulating"`
@@ -195,7 +421,7 @@ func getStats(data json.RawMessage) interface{} {
 	if err != nil {
 		panic(err)
 	}
-	txs, err := chain.GetTransactions(nil, nil, "", 0, 0)
+	txs, _, err := chain.GetTransactions(state.TransactionsQuery{})
 	if err != nil {
 		panic(err)
 	}
@@ -214,6 +440,12 @@ func getStats(data json.RawMessage) interface{} {
 	}
 }
 
+type ResultsGetNFToken struct {
+	NFTokenID fat1.NFTokenID  `json:"id"`
+	Owner     factom.Address  `json:"owner"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+}
+
 func getNFToken(data json.RawMessage) interface{} {
 	params := ParamsGetNFToken{}
 	chainID, err := validate(data, &params)
@@ -221,7 +453,50 @@ func getNFToken(data json.RawMessage) interface{} {
 		return err
 	}
 
-	return ErrorTokenNotFound
+	chain := state.Chains.Get(chainID)
+	if !chain.IsIssued() || chain.Issuance.Type != fat.TypeFAT1 {
+		return ErrorTokenNotFound
+	}
+	tkn, rerr := chain.GetNFToken(*params.NonFungibleTokenID)
+	if rerr != nil {
+		panic(rerr)
+	}
+	if tkn == nil {
+		return ErrorTokenNotFound
+	}
+	return ResultsGetNFToken{
+		NFTokenID: tkn.ID,
+		Owner:     factom.NewAddress(&tkn.Owner),
+		Metadata:  tkn.Metadata,
+	}
+}
+
+// getNFBalance returns the NFTokenIDs held by an address of a FAT-1 token.
+func getNFBalance(data json.RawMessage) interface{} {
+	params := ParamsGetNFBalance{}
+	chainID, res := validate(data, &params)
+	if chainID == nil {
+		return res
+	}
+
+	chain := state.Chains.Get(chainID)
+	if !chain.IsIssued() || chain.Issuance.Type != fat.TypeFAT1 {
+		return ErrorTokenNotFound
+	}
+	tkns, err := chain.GetNFTokens(*params.Address)
+	if err != nil {
+		panic(err)
+	}
+	if len(tkns) == 0 {
+		return []fat1.NFTokenID{}
+	}
+	return tkns
+}
+
+type ResultsSendTransaction struct {
+	ChainID *factom.Bytes32 `json:"chainid"`
+	TxID    *factom.Bytes32 `json:"txid"`
+	Hash    *factom.Bytes32 `json:"entryhash"`
 }
 
 func sendTransaction(data json.RawMessage) interface{} {
@@ -241,6 +516,10 @@ func sendTransaction(data json.RawMessage) interface{} {
 		return rpcErr
 	}
 
+	if chain.Issuance.Type == fat.TypeFAT1 {
+		return sendNFTransaction(chain, params)
+	}
+
 	tx := fat0.NewTransaction(params.Entry())
 	if err := tx.Valid(chain.IDKey); err != nil {
 		rpcErr = ErrorInvalidTransaction
@@ -272,17 +551,64 @@ func sendTransaction(data json.RawMessage) interface{} {
 		}
 	}
 
-	txID, err := tx.Create(flag.ECPub)
+	txID, err := tx.Create(context.Background(), flag.ECPub)
 	if err != nil {
 		log.Error(err)
 		panic(err)
 	}
 
-	return struct {
-		ChainID *factom.Bytes32 `json:"chainid"`
-		TxID    *factom.Bytes32 `json:"txid"`
-		Hash    *factom.Bytes32 `json:"entryhash"`
-	}{ChainID: chainID, TxID: txID, Hash: tx.Hash}
+	return ResultsSendTransaction{ChainID: chainID, TxID: txID, Hash: tx.Hash}
+}
+
+// sendNFTransaction validates and submits a FAT-1 transaction.
+func sendNFTransaction(chain state.Chain,
+	params ParamsSendTransaction) interface{} {
+	tx := fat1.NewTransaction(params.Entry())
+	if err := tx.Valid(chain.IDKey); err != nil {
+		rpcErr := ErrorInvalidTransaction
+		rpcErr.Data = err.Error()
+		return rpcErr
+	}
+
+	// check balances
+	for rcdHash, tkns := range tx.Inputs {
+		if tx.IsCoinbase() {
+			if chain.Supply > 0 && uint64(len(tkns)) >
+				uint64(chain.Supply)-chain.Issued {
+				rpcErr := ErrorInvalidTransaction
+				rpcErr.Data = "insufficient coinbase supply"
+				return rpcErr
+			}
+			issued, err := chain.CountNFTokens(tkns, nil)
+			if err != nil {
+				panic(err)
+			}
+			if issued > 0 {
+				rpcErr := ErrorInvalidTransaction
+				rpcErr.Data = "NFTokenID already issued"
+				return rpcErr
+			}
+			break
+		}
+		adr := factom.NewAddress(&rcdHash)
+		owned, err := chain.CountNFTokens(tkns, &adr)
+		if err != nil {
+			panic(err)
+		}
+		if owned != uint64(len(tkns)) {
+			rpcErr := ErrorInvalidTransaction
+			rpcErr.Data = fmt.Sprintf("insufficient balance: %v", adr)
+			return rpcErr
+		}
+	}
+
+	txID, err := tx.Create(context.Background(), flag.ECPub)
+	if err != nil {
+		log.Error(err)
+		panic(err)
+	}
+
+	return ResultsSendTransaction{ChainID: tx.ChainID, TxID: txID, Hash: tx.Hash}
 }
 
 func getDaemonTokens(data json.RawMessage) interface{} {
@@ -301,14 +627,53 @@ func getDaemonTokens(data json.RawMessage) interface{} {
 	return chains
 }
 
+type ResultsGetDaemonProperties struct {
+	FatdVersion string `json:"fatdversion"`
+	APIVersion  string `json:"apiversion"`
+}
+
 func getDaemonProperties(data json.RawMessage) interface{} {
 	if data != nil {
 		return ParamsErrorNoParams
 	}
-	return struct {
-		FatdVersion string `json:"fatdversion"`
-		APIVersion  string `json:"apiversion"`
-	}{FatdVersion: "0.0.0", APIVersion: "v0"}
+	return ResultsGetDaemonProperties{FatdVersion: "0.0.0", APIVersion: "v0"}
+}
+
+type ResultsGetSyncStatus struct {
+	Height uint64 `json:"syncheight"`
+	// Failing are the chains that failed to sync at least once since they
+	// last synced successfully.
+	Failing []ResultsFailingChain `json:"failing"`
+	// Backfilling are the chains being synced by admin-track-chain or
+	// admin-rescan-chain.
+	Backfilling []*factom.Bytes32 `json:"backfilling,omitempty"`
+	Paused      bool              `json:"paused,omitempty"`
+}
+
+type ResultsFailingChain struct {
+	ChainID     *factom.Bytes32 `json:"chainid"`
+	Failures    uint            `json:"failures"`
+	Error       string          `json:"error"`
+	Quarantined bool            `json:"quarantined"`
+}
+
+func getSyncStatus(data json.RawMessage) interface{} {
+	if data != nil {
+		return ParamsErrorNoParams
+	}
+	chains := state.Chains.GetFailing()
+	failing := make([]ResultsFailingChain, len(chains))
+	for i, chain := range chains {
+		failing[i] = ResultsFailingChain{
+			ChainID:     chain.ID,
+			Failures:    chain.Failures.Count,
+			Error:       chain.Failures.Err.Error(),
+			Quarantined: chain.IsQuarantined(),
+		}
+	}
+	return ResultsGetSyncStatus{Height: state.SavedHeight, Failing: failing,
+		Backfilling: state.Chains.GetBackfilling(),
+		Paused:      engine.IsPaused()}
 }
 
 func validate(data json.RawMessage, params Params) (*factom.Bytes32, jrpc.Error) {
@@ -7,194 +7,155 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
 	"github.com/Factom-Asset-Tokens/fatd/factom"
-	"github.com/Factom-Asset-Tokens/fatd/flag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-//// We make copies because the original is modified during the method call.
-//var tokenParamsRes = cpResponse(TokenParamsRes)
-//var tokenNotFoundRes = cpResponse(TokenNotFoundRes)
-//var transactionNotFoundRes = cpResponse(TransactionNotFoundRes)
-//var getTransactionParamsRes = cpResponse(GetTransactionParamsRes)
-//var getTransactionsParamsRes = cpResponse(GetTransactionsParamsRes)
-//var getBalanceParamsRes = cpResponse(GetBalanceParamsRes)
-var getBalanceValidRes = jrpc.NewResponse(float64(0))
-
 var tokenID = "invalid"
 
 type Test struct {
 	Params      interface{}
 	Description string
 	Result      interface{}
-	Error       interface{}
+	Error       *jrpc.Error
 }
 
+func newErr(err jrpc.Error) *jrpc.Error { return &err }
+
 var getIssuanceTests = []Test{{
 	Description: "nil params",
-	Error:       TokenParamsError,
+	Error:       newErr(ParamsErrorToken),
 }, {
-	Params:      TokenParams{},
+	Params:      ParamsToken{},
 	Description: "empty params",
-	Error:       TokenParamsError,
+	Error:       newErr(ParamsErrorToken),
 }, {
 	Params: struct {
-		TokenParams
+		ParamsToken
 		NewField string
-	}{TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)}, NewField: "hello"},
+	}{ParamsToken: ParamsToken{ChainID: factom.NewBytes32(nil)},
+		NewField: "hello"},
 	Description: "unknown field",
-	Error:       jrpc.NewInvalidParamsError(`json: unknown field "NewField"`),
+	Error: newErr(jrpc.NewInvalidParamsError(
+		`json: unknown field "NewField"`)),
 }, {
-	Params:      TokenParams{ChainID: factom.NewBytes32(nil), TokenID: &tokenID},
+	Params: ParamsToken{ChainID: factom.NewBytes32(nil),
+		TokenID: tokenID},
 	Description: "chain id and token id",
-	Error:       TokenParamsError,
+	Error:       newErr(ParamsErrorToken),
 }, {
-	Params: TokenParams{ChainID: factom.NewBytes32(nil),
+	Params: ParamsToken{ChainID: factom.NewBytes32(nil),
 		IssuerChainID: factom.NewBytes32(nil)},
 	Description: "chain id and issuer chain id",
-	Error:       TokenParamsError,
+	Error:       newErr(ParamsErrorToken),
 }, {
-	Params: TokenParams{ChainID: factom.NewBytes32(nil),
-		IssuerChainID: factom.NewBytes32(nil), TokenID: &tokenID},
+	Params: ParamsToken{ChainID: factom.NewBytes32(nil),
+		IssuerChainID: factom.NewBytes32(nil), TokenID: tokenID},
 	Description: "chain id and token id and issuer chain id",
-	Error:       TokenParamsError,
+	Error:       newErr(ParamsErrorToken),
 }, {
-	Params: TokenParams{IssuerChainID: factom.NewBytes32(nil),
-		TokenID: &tokenID},
+	Params: ParamsToken{IssuerChainID: factom.NewBytes32(nil),
+		TokenID: tokenID},
 	Description: "token id and issuer chain id",
-	Error:       TokenNotFoundError,
+	Error:       newErr(ErrorTokenNotFound),
 }, {
-	Params:      TokenParams{ChainID: factom.NewBytes32(nil)},
+	Params:      ParamsToken{ChainID: factom.NewBytes32(nil)},
 	Description: "chain id",
-	Error:       TokenNotFoundError,
-},
-}
+	Error:       newErr(ErrorTokenNotFound),
+}}
 
 var getTransactionTests = []Test{{
-	Params: TokenParams{ChainID: factom.NewBytes32(nil),
-		IssuerChainID: factom.NewBytes32(nil)},
+	Params:      ParamsToken{ChainID: factom.NewBytes32(nil)},
 	Description: "no hash",
-	Error:       GetTransactionParamsError,
+	Error:       newErr(ParamsErrorGetTransaction),
 }, {
-	Params: GetTransactionParams{
-		TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)},
+	Params: ParamsGetTransaction{
+		ParamsToken: ParamsToken{ChainID: factom.NewBytes32(nil)},
 		Hash:        factom.NewBytes32(nil)},
-	Description: "tx not found",
-	Error:       TransactionNotFoundError,
-},
-}
+	Description: "token not found",
+	Error:       newErr(ErrorTokenNotFound),
+}}
 
 var getTransactionsTests = []Test{{
-	Params: GetTransactionsParams{
-		TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)},
-		Hash:        factom.NewBytes32(nil), Start: new(uint)},
-	Description: "hash and start",
-	Error:       GetTransactionsParamsError,
-}, {
-	Params: GetTransactionsParams{
-		TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)},
-		Hash:        factom.NewBytes32(nil)},
-	Description: "tx not found, with hash",
-	Error:       TransactionNotFoundError,
-}, {
-	Params: GetTransactionsParams{
-		TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)}, Limit: new(uint)},
+	Params: ParamsGetTransactions{
+		ParamsToken: ParamsToken{ChainID: factom.NewBytes32(nil)},
+		Hash:        factom.NewBytes32(nil), Cursor: 1},
+	Description: "hash and cursor",
+	Error:       newErr(ParamsErrorGetTransactions),
+}, {
+	Params: ParamsGetTransactions{
+		ParamsToken: ParamsToken{ChainID: factom.NewBytes32(nil)},
+		Limit:       new(uint)},
 	Description: "zero limit",
-	Error:       GetTransactionsParamsError,
-}, {
-	Params: GetTransactionsParams{
-		TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)}},
-	Description: "tx not found",
-	Error:       TransactionNotFoundError,
-},
-}
+	Error:       newErr(ParamsErrorGetTransactions),
+}, {
+	Params: ParamsGetTransactions{
+		ParamsToken: ParamsToken{ChainID: factom.NewBytes32(nil)},
+		StartHeight: 10, EndHeight: 5},
+	Description: "start height after end height",
+	Error:       newErr(ParamsErrorGetTransactions),
+}, {
+	Params: ParamsGetTransactions{
+		ParamsToken: ParamsToken{ChainID: factom.NewBytes32(nil)}},
+	Description: "token not found",
+	Error:       newErr(ErrorTokenNotFound),
+}}
 
 var getBalanceTests = []Test{{
-	Params: GetBalanceParams{
-		TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)}},
+	Params: ParamsGetBalance{
+		ParamsToken: ParamsToken{ChainID: factom.NewBytes32(nil)}},
 	Description: "no address",
-	Error:       GetBalanceParamsError,
+	Error:       newErr(ParamsErrorGetBalance),
 }, {
-	Params: GetBalanceParams{
-		Address: &factom.Address{}},
+	Params:      ParamsGetBalance{Address: &factom.Address{}},
 	Description: "no chain",
-	Error:       GetBalanceParamsError,
-}, {
-	Params: GetBalanceParams{TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)},
-		Address: &factom.Address{}},
-	Description: "valid",
-	Result:      0,
-},
-}
+	Error:       newErr(ParamsErrorToken),
+}}
 
 var getStatsTests = []Test{{
 	Description: "no params",
-	Error:       TokenParamsError,
+	Error:       newErr(ParamsErrorToken),
 }, {
-	Params:      TokenParams{ChainID: factom.NewBytes32(nil)},
-	Description: "valid",
-	Result: struct {
-		Supply                   int `json:"supply"`
-		CirculatingSupply        int `json:"circulating-supply"`
-		Transactions             int `json:"transactions"`
-		IssuanceTimestamp        int `json:"issuance-timestamp"`
-		LastTransactionTimestamp int `json:"last-transaction-timestamp"`
-	}{},
+	Params:      ParamsToken{ChainID: factom.NewBytes32(nil)},
+	Description: "token not found",
+	Error:       newErr(ErrorTokenNotFound),
 }}
 
-var NFTokenID = "test"
-
 var getNFTokenTests = []Test{{
-	Params: GetNFTokenParams{
-		TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)}},
+	Params: ParamsGetNFToken{
+		ParamsToken: ParamsToken{ChainID: factom.NewBytes32(nil)}},
 	Description: "no nf token param",
-	Error:       GetNFTokenParamsError,
-}, {
-	Params: GetNFTokenParams{NonFungibleTokenID: &NFTokenID,
-		TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)}},
-	Description: "valid",
-	Error:       TokenNotFoundError,
+	Error:       newErr(ParamsErrorGetNFToken),
 }}
 
 var sendTransactionTests = []Test{{
-	Description: "no params",
-	Error:       SendTransactionParamsError,
-}, {
-	Params: SendTransactionParams{Content: factom.Bytes{0x00},
-		ExtIDs:      []factom.Bytes{{0x00}},
-		TokenParams: TokenParams{ChainID: factom.NewBytes32(nil)}},
-	Description: "invalid token",
-	Error:       TokenNotFoundError,
+	Description: "no ecpub",
+	Error:       newErr(ErrorNoEC),
 }}
 
 var getDaemonTokensTests = []Test{{
 	Description: "no params",
-	Result: []struct {
-		TokenID  string          `json:"token-id"`
-		IssuerID *factom.Bytes32 `json:"issuer-id"`
-		ChainID  *factom.Bytes32 `json:"chain-id"`
-	}{{}},
+	Result:      []struct{}{},
 }, {
-	Params:      TokenParams{ChainID: factom.NewBytes32(nil)},
-	Description: "valid",
-	Error:       NoParamsError,
+	Params:      ParamsToken{ChainID: factom.NewBytes32(nil)},
+	Description: "params",
+	Error:       newErr(ParamsErrorNoParams),
 }}
 
 var getDaemonPropertiesTests = []Test{{
 	Params:      []int{0},
 	Description: "invalid params",
-	Error:       NoParamsError,
+	Error:       newErr(ParamsErrorNoParams),
 }, {
 	Description: "no params",
-	Result: struct {
-		FatdVersion string `json:"fatd-version"`
-		APIVersion  string `json:"api-version"`
-	}{FatdVersion: "0.0.0", APIVersion: "v0"},
+	Result: ResultsGetDaemonProperties{
+		FatdVersion: "0.0.0", APIVersion: "v0"},
 }}
 
 var methodTests = map[string][]Test{
@@ -210,37 +171,45 @@ var methodTests = map[string][]Test{
 }
 
 func TestMethods(t *testing.T) {
-	flag.APIAddress = "localhost:18888"
-	Start()
+	ts := httptest.NewServer(Handler())
+	defer ts.Close()
 	for method, tests := range methodTests {
+		method, tests := method, tests
 		t.Run(method, func(t *testing.T) {
 			for _, test := range tests {
+				test := test
 				t.Run(test.Description, func(t *testing.T) {
 					assert := assert.New(t)
 					require := require.New(t)
-					res, err := request(method,
+					res, err := request(ts.URL, method,
 						test.Params, &json.RawMessage{})
-					assert.NoError(err)
+					require.NoError(err)
 					assert.NotNil(res.ID)
 					if test.Result != nil {
 						data, err := json.Marshal(test.Result)
 						require.NoError(err)
+						require.Nil(res.Error)
 						result := res.Result.(*json.RawMessage)
 						require.NotEmpty(result)
 						assert.JSONEq(string(data), string(*result),
 							"Result")
 					} else {
 						require.NotNil(res.Error)
-						assert.Equal(test.Error, *res.Error, "Error")
+						assert.Equal(test.Error.Code,
+							res.Error.Code, "Error.Code")
+						assert.Equal(test.Error.Message,
+							res.Error.Message, "Error.Message")
 					}
 				})
 			}
 		})
 	}
-	Stop()
 }
 
-func request(method string, params interface{}, result interface{}) (jrpc.Response, error) {
+// request makes a JSON RPC request for method to url and unmarshals the result
+// into result.
+func request(url, method string, params interface{},
+	result interface{}) (jrpc.Response, error) {
 	// Generate a random ID for this request.
 	id := rand.Uint32()%200 + 500
 
@@ -251,8 +220,8 @@ func request(method string, params interface{}, result interface{}) (jrpc.Respon
 	}
 
 	// Make the HTTP request.
-	endpoint := "http://" + flag.APIAddress
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(reqBytes))
+	req, err := http.NewRequest(http.MethodPost, url,
+		bytes.NewBuffer(reqBytes))
 	if err != nil {
 		return jrpc.Response{}, err
 	}
@@ -262,14 +231,17 @@ func request(method string, params interface{}, result interface{}) (jrpc.Respon
 	if err != nil {
 		return jrpc.Response{}, err
 	}
-	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusBadRequest {
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK &&
+		res.StatusCode != http.StatusBadRequest {
 		return jrpc.Response{}, fmt.Errorf("http: %v", res.Status)
 	}
 
 	// Read the HTTP response.
 	resBytes, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return jrpc.Response{}, fmt.Errorf("ioutil.ReadAll(http.Response.Body): %v", err)
+		return jrpc.Response{}, fmt.Errorf(
+			"ioutil.ReadAll(http.Response.Body): %v", err)
 	}
 
 	// Unmarshal the HTTP response into a JSON RPC response.
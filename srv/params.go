@@ -6,6 +6,8 @@ import (
 	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
 	"github.com/Factom-Asset-Tokens/fatd/factom"
 	"github.com/Factom-Asset-Tokens/fatd/fat"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/state"
 )
 
 type Params interface {
@@ -67,10 +69,20 @@ type ParamsGetTransactions struct {
 	FactoidAddress     *factom.Address `json:"address,omitempty"`
 	ToFrom             string          `json:"tofrom"`
 
+	// Filters
+	StartHeight uint64       `json:"startheight,omitempty"`
+	EndHeight   uint64       `json:"endheight,omitempty"`
+	StartTime   *factom.Time `json:"starttime,omitempty"`
+	EndTime     *factom.Time `json:"endtime,omitempty"`
+	Coinbase    bool         `json:"coinbase,omitempty"`
+	Burns       bool         `json:"burns,omitempty"`
+
 	// Pagination
-	Hash  *factom.Bytes32 `json:"entryhash,omitempty"`
-	Start *uint           `json:"start,omitempty"`
-	Limit *uint           `json:"limit,omitempty"`
+	Hash   *factom.Bytes32 `json:"entryhash,omitempty"`
+	Cursor uint64          `json:"cursor,omitempty"`
+	Start  *uint           `json:"start,omitempty"`
+	Limit  *uint           `json:"limit,omitempty"`
+	Order  string          `json:"order,omitempty"`
 }
 
 func (p *ParamsGetTransactions) IsValid() bool {
@@ -97,16 +109,58 @@ func (p *ParamsGetTransactions) IsValid() bool {
 	default:
 		return false
 	}
+	switch p.Order {
+	case "asc":
+	case "desc":
+	case "":
+	default:
+		return false
+	}
+	if p.Hash != nil && p.Cursor > 0 {
+		return false
+	}
+	if p.EndHeight > 0 && p.StartHeight > p.EndHeight {
+		return false
+	}
+	if p.StartTime != nil && p.EndTime != nil &&
+		p.StartTime.After(p.EndTime.Time) {
+		return false
+	}
 	return true
 }
 
+// Query returns the state.TransactionsQuery selected by p. IsValid must be
+// called first.
+func (p ParamsGetTransactions) Query() state.TransactionsQuery {
+	q := state.TransactionsQuery{
+		Address:     p.FactoidAddress,
+		ToFrom:      p.ToFrom,
+		StartHeight: p.StartHeight,
+		EndHeight:   p.EndHeight,
+		Coinbase:    p.Coinbase,
+		Burns:       p.Burns,
+		Hash:        p.Hash,
+		Cursor:      p.Cursor,
+		Start:       *p.Start,
+		Limit:       *p.Limit,
+		NewestFirst: p.Order == "desc",
+	}
+	if p.StartTime != nil {
+		q.StartTime = p.StartTime.Time
+	}
+	if p.EndTime != nil {
+		q.EndTime = p.EndTime.Time
+	}
+	return q
+}
+
 func (p ParamsGetTransactions) Error() jrpc.Error {
 	return ParamsErrorGetTransactions
 }
 
 type ParamsGetNFToken struct {
 	ParamsToken
-	NonFungibleTokenID *string `json:"nftokenid,omitempty"`
+	NonFungibleTokenID *fat1.NFTokenID `json:"nftokenid,omitempty"`
 }
 
 func (p ParamsGetNFToken) IsValid() bool {
@@ -117,6 +171,20 @@ func (p ParamsGetNFToken) Error() jrpc.Error {
 	return ParamsErrorGetNFToken
 }
 
+// ParamsGetNFBalance is used to query for the NFTokenIDs held by an address.
+type ParamsGetNFBalance struct {
+	ParamsToken
+	Address *factom.Address `json:"address,omitempty"`
+}
+
+func (p ParamsGetNFBalance) IsValid() bool {
+	return p.Address != nil
+}
+
+func (p ParamsGetNFBalance) Error() jrpc.Error {
+	return ParamsErrorGetNFBalance
+}
+
 type ParamsGetBalance struct {
 	ParamsToken
 	Address *factom.Address `json:"address,omitempty"`
@@ -130,6 +198,21 @@ func (p ParamsGetBalance) Error() jrpc.Error {
 	return ParamsErrorGetBalance
 }
 
+// ParamsGetHoldersSnapshot is used to query for the balances of all holders of
+// a token as of a particular block height.
+type ParamsGetHoldersSnapshot struct {
+	ParamsToken
+	Height *uint64 `json:"height,omitempty"`
+}
+
+func (p ParamsGetHoldersSnapshot) IsValid() bool {
+	return p.Height != nil
+}
+
+func (p ParamsGetHoldersSnapshot) Error() jrpc.Error {
+	return ParamsErrorGetHoldersSnapshot
+}
+
 type ParamsSendTransaction struct {
 	ParamsToken
 	ExtIDs  []factom.Bytes `json:"extids"`
@@ -152,3 +235,43 @@ func (p ParamsSendTransaction) Entry() factom.Entry {
 		ChainID:   p.ChainID,
 	}
 }
+
+// ParamsGetBlockTransactions is used to query for the Issuance and Transaction
+// entries of all tracked tokens at a particular block height.
+type ParamsGetBlockTransactions struct {
+	Height *uint64 `json:"height,omitempty"`
+}
+
+func (p ParamsGetBlockTransactions) IsValid() bool {
+	return p.Height != nil
+}
+
+// ValidChainID returns a non-nil placeholder since the request is not scoped
+// to any particular token.
+func (p ParamsGetBlockTransactions) ValidChainID() *factom.Bytes32 {
+	return &factom.Bytes32{}
+}
+
+func (p ParamsGetBlockTransactions) Error() jrpc.Error {
+	return ParamsErrorGetBlockTransactions
+}
+
+// ParamsGetAddressBalances is used to query for the balances of addresses
+// across all tracked tokens.
+type ParamsGetAddressBalances struct {
+	Addresses []factom.Address `json:"addresses,omitempty"`
+}
+
+func (p ParamsGetAddressBalances) IsValid() bool {
+	return len(p.Addresses) > 0
+}
+
+// ValidChainID returns a non-nil placeholder since the request is not scoped
+// to any particular token.
+func (p ParamsGetAddressBalances) ValidChainID() *factom.Bytes32 {
+	return &factom.Bytes32{}
+}
+
+func (p ParamsGetAddressBalances) Error() jrpc.Error {
+	return ParamsErrorGetAddressBalances
+}
@@ -1,7 +1,10 @@
 package srv
 
 import (
+	"context"
 	"net/http"
+	"strings"
+	"time"
 
 	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
 	"github.com/Factom-Asset-Tokens/fatd/flag"
@@ -10,31 +13,99 @@ import (
 )
 
 var (
-	log _log.Log
-	srv http.Server
+	log     _log.Log
+	servers []*http.Server
 )
 
-func Start() {
+// Start serving the API on -apiaddress, the admin API on -adminaddress and
+// both on -apisocket, if set.
+func Start() error {
 	log = _log.New("srv")
 	jrpc.DebugMethodFunc = true
-	jrpcHandler := jrpc.HTTPRequestHandler(jrpcMethods)
-	// Set up server
+	if err := Configure(); err != nil {
+		return err
+	}
+	if err := ReloadTLS(); err != nil {
+		return err
+	}
+
+	srv, err := serve("api", flag.APIAddress, Handler())
+	if err != nil {
+		return err
+	}
+	servers = append(servers, srv)
+
+	if len(flag.AdminAddress) > 0 {
+		srv, err := serve("admin", flag.AdminAddress, AdminHandler())
+		if err != nil {
+			Stop()
+			return err
+		}
+		servers = append(servers, srv)
+	}
+
+	if len(flag.APISocket) > 0 {
+		srv, err := serveSocket(flag.APISocket, socketHandler())
+		if err != nil {
+			Stop()
+			return err
+		}
+		servers = append(servers, srv)
+	}
+	return nil
+}
+
+// newMux returns a ServeMux that serves methods on "/" and "/v1".
+func newMux(methods jrpc.MethodMap) *http.ServeMux {
+	jrpcHandler := jrpc.HTTPRequestHandler(methods)
 	srvMux := http.NewServeMux()
 	srvMux.Handle("/", jrpcHandler)
 	srvMux.Handle("/v1", jrpcHandler)
+	return srvMux
+}
 
-	cors := cors.New(cors.Options{AllowedOrigins: []string{"*"}})
+// Handler returns the http.Handler that serves the -apimethods of the JSON RPC
+// 2.0 API, subject to the authentication and rate limits set by Configure.
+func Handler() http.Handler {
+	handler := apiAuth.handler(newMux(apiMethodSet))
+	if len(strings.TrimSpace(flag.APICORS)) == 0 {
+		// Without any CORS headers, browsers refuse all cross-origin
+		// requests. An empty list of origins must not be passed to
+		// cors, which treats it as allowing all origins.
+		return handler
+	}
+	origins := strings.Split(flag.APICORS, ",")
+	for i := range origins {
+		origins[i] = strings.TrimSpace(origins[i])
+	}
+	cors := cors.New(cors.Options{AllowedOrigins: origins,
+		AllowedHeaders: []string{"Content-Type", "Authorization"}})
+	return cors.Handler(handler)
+}
 
-	srv = http.Server{Handler: cors.Handler(srvMux)}
-	srv.Addr = flag.APIAddress
-	go func() {
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			log.Errorf("srv.ListenAndServe(): %v", err)
-		}
-	}()
+// socketHandler returns the http.Handler that serves the -socketmethods on the
+// -apisocket.
+func socketHandler() http.Handler {
+	return socketAuth.handler(newMux(socketMethodSet))
 }
 
+// shutdownTimeout is how long Stop waits for active requests to complete.
+const shutdownTimeout = 5 * time.Second
+
+// Stop shuts down all servers. Connections with requests still active after
+// shutdownTimeout are closed.
 func Stop() error {
-	srv.Shutdown(nil)
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	var err error
+	for _, srv := range servers {
+		if shutdownErr := srv.Shutdown(ctx); shutdownErr != nil {
+			srv.Close()
+			if err == nil {
+				err = shutdownErr
+			}
+		}
+	}
+	servers = nil
+	return err
 }
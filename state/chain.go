@@ -15,6 +15,27 @@ type Chain struct {
 	fat.Issuance
 	Metadata
 	*gorm.DB
+
+	Failures ChainFailures
+
+	// Backfilling is true while the chain is being synced separately from
+	// the engine, during which the engine skips it.
+	Backfilling bool
+}
+
+// ChainFailures records the consecutive failures to process a chain. A
+// quarantined chain is no longer synced, but its existing state may still be
+// queried.
+type ChainFailures struct {
+	Count       uint
+	Err         error
+	Quarantined bool
+}
+
+// IsQuarantined returns true if the chain failed to process MaxFailures
+// consecutive times.
+func (chain Chain) IsQuarantined() bool {
+	return chain.Failures.Quarantined
 }
 
 func (chain Chain) String() string {
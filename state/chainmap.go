@@ -23,10 +23,11 @@ type ChainMap struct {
 func (cm *ChainMap) set(id *factom.Bytes32, chain *Chain) {
 	defer cm.Unlock()
 	cm.Lock()
-	if chain.IsIssued() {
-		if _, ok := cm.m[*id]; !ok {
-			cm.ids = append(cm.ids, id)
-		}
+	// The chain may already be in m without being issued, for example
+	// while it is backfilled, so only the previous status indicates
+	// whether it is already in ids.
+	if chain.IsIssued() && !cm.m[*id].IsIssued() {
+		cm.ids = append(cm.ids, id)
 	}
 	cm.m[*id] = *chain
 }
@@ -46,3 +47,73 @@ func (cm ChainMap) GetIssued() []*factom.Bytes32 {
 	cm.RLock()
 	return cm.ids
 }
+
+// GetFailing returns the chains that have failed to process at least once
+// since they were last processed successfully, including any quarantined
+// chains.
+func (cm ChainMap) GetFailing() []Chain {
+	defer cm.RUnlock()
+	cm.RLock()
+	var chains []Chain
+	for _, chain := range cm.m {
+		if chain.Failures.Count > 0 {
+			chains = append(chains, chain)
+		}
+	}
+	return chains
+}
+
+// remove deletes the chain with id so that it may be tracked again from
+// scratch.
+func (cm *ChainMap) remove(id *factom.Bytes32) {
+	defer cm.Unlock()
+	cm.Lock()
+	delete(cm.m, *id)
+	for i, issued := range cm.ids {
+		if *issued == *id {
+			cm.ids = append(cm.ids[:i:i], cm.ids[i+1:]...)
+			break
+		}
+	}
+}
+
+// SetBackfilling sets whether the chain with id is being backfilled.
+func (cm ChainMap) SetBackfilling(id *factom.Bytes32, backfilling bool) {
+	defer cm.Unlock()
+	cm.Lock()
+	chain, ok := cm.m[*id]
+	if !ok {
+		chain.ID = id
+	}
+	chain.Backfilling = backfilling
+	cm.m[*id] = chain
+}
+
+// GetBackfilling returns the IDs of the chains that are being backfilled.
+func (cm ChainMap) GetBackfilling() []*factom.Bytes32 {
+	defer cm.RUnlock()
+	cm.RLock()
+	var ids []*factom.Bytes32
+	for id, chain := range cm.m {
+		if chain.Backfilling {
+			id := id
+			ids = append(ids, &id)
+		}
+	}
+	return ids
+}
+
+// Quarantine the chain with id because of err so that the engine no longer
+// syncs it.
+func (cm ChainMap) Quarantine(id *factom.Bytes32, err error) {
+	defer cm.Unlock()
+	cm.Lock()
+	chain, ok := cm.m[*id]
+	if !ok {
+		chain.ID = id
+	}
+	chain.Failures.Count++
+	chain.Failures.Err = err
+	chain.Failures.Quarantined = true
+	cm.m[*id] = chain
+}
@@ -0,0 +1,62 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainMapRescanIssued(t *testing.T) {
+	assert := assert.New(t)
+	chain, cleanup := newTestChain(t)
+	defer cleanup()
+	defer Chains.remove(chain.ID)
+
+	countIssued := func() int {
+		var count int
+		for _, id := range Chains.GetIssued() {
+			if *id == *chain.ID {
+				count++
+			}
+		}
+		return count
+	}
+
+	chain.ChainStatus = ChainStatusIssued
+	Chains.set(chain.ID, &chain)
+	Chains.set(chain.ID, &chain)
+	assert.Equal(1, countIssued())
+
+	// Rescanning the chain resets it and then backfills it until it is
+	// issued again.
+	require.NoError(t, ResetChain(chain.ID))
+	assert.Equal(0, countIssued())
+	Chains.SetBackfilling(chain.ID, true)
+	backfill := Chain{ID: chain.ID, ChainStatus: ChainStatusTracked,
+		Backfilling: true}
+	Chains.set(chain.ID, &backfill)
+	assert.Equal(0, countIssued())
+
+	backfill.ChainStatus = ChainStatusIssued
+	Chains.set(chain.ID, &backfill)
+	assert.Equal(1, countIssued())
+	Chains.SetBackfilling(chain.ID, false)
+	Chains.set(chain.ID, &backfill)
+	assert.Equal(1, countIssued())
+
+	// Tracking a chain that was ignored works the same way.
+	id := factom.NewBytes32([]byte{0x02})
+	defer Chains.remove(id)
+	Chains.set(id, &Chain{ID: id, ChainStatus: ChainStatusIgnored})
+	Chains.remove(id)
+	Chains.SetBackfilling(id, true)
+	Chains.set(id, &Chain{ID: id, ChainStatus: ChainStatusIssued,
+		Backfilling: true})
+	var found bool
+	for _, issued := range Chains.GetIssued() {
+		found = found || *issued == *id
+	}
+	assert.True(found)
+}
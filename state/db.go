@@ -1,16 +1,20 @@
 package state
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/Factom-Asset-Tokens/fatd/factom"
 	"github.com/Factom-Asset-Tokens/fatd/fat"
 	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
 	"github.com/Factom-Asset-Tokens/fatd/flag"
 	_log "github.com/Factom-Asset-Tokens/fatd/log"
 
@@ -23,8 +27,10 @@ var (
 	log         _log.Log
 )
 
-// Load state from all existing databases
-func Load() error {
+// Load state from all existing databases. The Identities of issued chains are
+// retrieved from factomd using ctx. If factomd cannot be reached, they are
+// retrieved later when the chain is next processed.
+func Load(ctx context.Context) error {
 	log = _log.New("state")
 	// Try to create the database directory in case it doesn't already
 	// exist.
@@ -32,6 +38,10 @@ func Load() error {
 		return fmt.Errorf("os.Mkdir(%#v)", flag.DBPath)
 	}
 
+	if err := openIndex(); err != nil {
+		return err
+	}
+
 	minHeight := uint64(math.MaxUint64)
 
 	// Scan through all files within the database directory. Ignore invalid
@@ -54,7 +64,16 @@ func Load() error {
 		if err := chain.loadMetadata(); err != nil {
 			return err
 		}
-		if err := chain.loadIssuance(); err != nil {
+		if err := chain.loadIssuance(ctx); err != nil {
+			return err
+		}
+		if err := chain.indexAddresses(); err != nil {
+			return err
+		}
+		if err := chain.migrate(ctx); err != nil {
+			return err
+		}
+		if err := chain.indexBlockEntries(); err != nil {
 			return err
 		}
 		Chains.set(chain.ID, &chain)
@@ -100,17 +119,26 @@ func Close() {
 			continue
 		}
 		if err := chain.Close(); err != nil {
-			log.Errorf(err.Error())
+			log.Error(err)
+		}
+	}
+	if index != nil {
+		if err := index.Close(); err != nil {
+			log.Error(err)
 		}
 	}
 }
 
+// SaveHeight saves height as the height of all tracked chains, except for
+// quarantined chains, which are left at the last height that they processed
+// so that they resume from there after a restart.
 func SaveHeight(height uint64) error {
 	Chains.Lock()
 	defer Chains.Unlock()
 
 	for _, chain := range Chains.m {
-		if !chain.IsTracked() || chain.Metadata.Height >= height {
+		if !chain.IsTracked() || chain.IsQuarantined() ||
+			chain.Backfilling || chain.Metadata.Height >= height {
 			continue
 		}
 		if err := chain.saveHeight(height); err != nil {
@@ -122,6 +150,105 @@ func SaveHeight(height uint64) error {
 	return nil
 }
 
+// SaveChainHeight is like SaveHeight but only saves the height of the chain
+// with chainID, if it is tracked and not quarantined. SavedHeight is not
+// changed.
+func SaveChainHeight(chainID *factom.Bytes32, height uint64) error {
+	Chains.Lock()
+	defer Chains.Unlock()
+
+	chain, ok := Chains.m[*chainID]
+	if !ok || !chain.IsTracked() || chain.IsQuarantined() ||
+		chain.Metadata.Height >= height {
+		return nil
+	}
+	if err := chain.saveHeight(height); err != nil {
+		return err
+	}
+	Chains.m[*chainID] = chain
+	return nil
+}
+
+// Reset prepares the chains with the given chainIDs, or all chains if chainIDs
+// is empty, to be rebuilt from scratch. Their databases are renamed with a
+// ".bak" extension and their entries in the index are deleted. Reset must be
+// called before Load.
+func Reset(chainIDs []*factom.Bytes32) error {
+	if err := os.Mkdir(flag.DBPath, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("os.Mkdir(%#v)", flag.DBPath)
+	}
+	if len(chainIDs) == 0 {
+		files, err := ioutil.ReadDir(flag.DBPath)
+		if err != nil {
+			return fmt.Errorf("ioutil.ReadDir(%#v): %v",
+				flag.DBPath, err)
+		}
+		for _, f := range files {
+			if chainID := fnameToChainID(f.Name()); chainID != nil {
+				chainIDs = append(chainIDs, chainID)
+			}
+		}
+	}
+
+	if err := openIndex(); err != nil {
+		return err
+	}
+	defer func() {
+		index.Close()
+		index = nil
+	}()
+	for _, chainID := range chainIDs {
+		if err := resetChain(chainID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetChain is like Reset for a single chain but is called while the state is
+// loaded. The chain's database is closed and the chain is removed from Chains
+// so that it may be tracked again from scratch.
+func ResetChain(chainID *factom.Bytes32) error {
+	chain := Chains.Get(chainID)
+	if chain.DB != nil {
+		if err := chain.Close(); err != nil {
+			return err
+		}
+	}
+	Chains.remove(chainID)
+	return resetChain(chainID)
+}
+
+// IgnoreChain resets the chain with chainID and then ignores it until fatd is
+// restarted. Since its database is moved aside, it is not loaded again.
+func IgnoreChain(chainID *factom.Bytes32) error {
+	if err := ResetChain(chainID); err != nil {
+		return err
+	}
+	chain := Chain{ChainStatus: ChainStatusIgnored}
+	Chains.set(chainID, &chain)
+	return nil
+}
+
+// resetChain deletes the index rows for chainID and renames its database with
+// a ".bak" extension.
+func resetChain(chainID *factom.Bytes32) error {
+	if err := index.Where("chain_id = ?", chainID).
+		Delete(&BlockEntry{}).Error; err != nil {
+		return err
+	}
+	if err := index.Where("chain_id = ?", chainID).
+		Delete(&addressChain{}).Error; err != nil {
+		return err
+	}
+	fpath := fmt.Sprintf("%v/%v%v", flag.DBPath, chainID, dbFileExtension)
+	if err := os.Rename(fpath, fpath+".bak"); err != nil &&
+		!os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 const (
 	dbDriver        = "sqlite3"
 	dbFileExtension = ".sqlite3"
@@ -157,6 +284,9 @@ func autoMigrate(db *gorm.DB) error {
 	if err := db.AutoMigrate(&Metadata{}).Error; err != nil {
 		return fmt.Errorf("db.AutoMigrate(&Metadata{}): %v", err)
 	}
+	if err := db.AutoMigrate(&nfToken{}).Error; err != nil {
+		return fmt.Errorf("db.AutoMigrate(&nfToken{}): %v", err)
+	}
 	return nil
 }
 
@@ -174,6 +304,7 @@ func (chain *Chain) setupDB() error {
 			chain.DB = nil
 		}
 	}()
+	chain.Metadata.Version = schemaVersion
 	if err := chain.Create(&chain.Metadata).Error; err != nil {
 		return err
 	}
@@ -204,7 +335,7 @@ func (chain *Chain) loadMetadata() error {
 	return nil
 }
 
-func (chain *Chain) loadIssuance() error {
+func (chain *Chain) loadIssuance(ctx context.Context) error {
 	e := entry{}
 	if err := chain.First(&e).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -220,8 +351,11 @@ func (chain *Chain) loadIssuance() error {
 		return err
 	}
 	chain.ChainStatus = ChainStatusIssued
-	if err := chain.Identity.Get(); err != nil {
-		return err
+	if err := chain.Identity.Get(ctx); err != nil {
+		if !factom.IsNetworkError(err) && err != factom.ErrNotCached {
+			return err
+		}
+		log.Warnf("Identity%v.Get(): %v", chain.Identity.ChainID, err)
 	}
 	return nil
 }
@@ -300,6 +434,73 @@ func (chain *Chain) rollbackUnlessCommitted(savedChain Chain, err *error) {
 	chain.Issued = savedChain.Issued
 }
 
+// GetHolders returns the balances of all addresses holding tokens as of the
+// given height. The balances are computed by replaying every transaction
+// applied at or below height, so this can be used to obtain the balances at
+// any past height for which the chain has been synced. The coinbase address,
+// which holds any burned tokens, is omitted. ErrNotMigrated is returned if the
+// heights of the entries are not yet known.
+func (chain Chain) GetHolders(height uint64) (fat0.AddressAmountMap, error) {
+	if !chain.IsMigrated() {
+		return nil, ErrNotMigrated
+	}
+	rows, err := chain.DB.Model(&entry{}).Not("id = ?", 1).
+		Where("height <= ?", height).Order("id").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holders := make(fat0.AddressAmountMap)
+	for rows.Next() {
+		var e entry
+		if err := chain.ScanRows(rows, &e); err != nil {
+			return nil, err
+		}
+		if chain.Issuance.Type == fat.TypeFAT1 {
+			// The balance of a FAT-1 holder is the number of
+			// NFTokenIDs they hold.
+			transaction := fat1.NewTransaction(e.Entry())
+			if err := transaction.UnmarshalEntry(); err != nil {
+				return nil, err
+			}
+			if !transaction.IsCoinbase() {
+				for rcdHash, tkns := range transaction.Inputs {
+					holders[rcdHash] -= uint64(len(tkns))
+				}
+			}
+			for rcdHash, tkns := range transaction.Outputs {
+				holders[rcdHash] += uint64(len(tkns))
+			}
+			continue
+		}
+		transaction := fat0.NewTransaction(e.Entry())
+		if err := transaction.UnmarshalEntry(); err != nil {
+			return nil, err
+		}
+		if !transaction.IsCoinbase() {
+			for rcdHash, amount := range transaction.Inputs {
+				holders[rcdHash] -= amount
+			}
+		}
+		for rcdHash, amount := range transaction.Outputs {
+			holders[rcdHash] += amount
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	coinbase := factom.Address{}
+	delete(holders, *coinbase.RCDHash())
+	for rcdHash, amount := range holders {
+		if amount == 0 {
+			delete(holders, rcdHash)
+		}
+	}
+	return holders, nil
+}
+
 func (chain Chain) GetTransaction(hash *factom.Bytes32) (fat0.Transaction, error) {
 	e, err := chain.getEntry(hash)
 	if e == nil {
@@ -322,97 +523,159 @@ func (chain Chain) getEntry(hash *factom.Bytes32) (*entry, error) {
 	return &e, nil
 }
 
-func (chain Chain) GetTransactions(hash *factom.Bytes32,
-	adr *factom.Address, toFrom string,
-	start, limit uint) ([]fat0.Transaction, error) {
+// TransactionsQuery selects a page of transactions from a Chain.
+type TransactionsQuery struct {
+	// Address, if not nil, restricts the results to transactions with
+	// Address in the inputs or outputs, or only one of those if ToFrom
+	// is "to" or "from".
+	Address *factom.Address
+	ToFrom  string
+
+	// Hash, if not nil, begins the results at the transaction with the
+	// given entry hash. Cursor, if not zero, begins the results at the
+	// transaction with the given cursor, as returned by a previous call
+	// to GetTransactions. Start is then the number of transactions to
+	// skip.
+	Hash   *factom.Bytes32
+	Cursor uint64
+	Start  uint
+
+	// StartHeight and EndHeight, if not zero, restrict the results to
+	// transactions included in blocks within the inclusive height range.
+	// StartTime and EndTime, if not zero, similarly restrict the results
+	// to transactions with entry timestamps within the inclusive range.
+	StartHeight, EndHeight uint64
+	StartTime, EndTime     time.Time
+
+	// Coinbase restricts the results to coinbase transactions. Burns
+	// restricts the results to transactions with the coinbase address in
+	// the outputs.
+	Coinbase bool
+	Burns    bool
+
+	// Limit is the maximum number of transactions returned. Zero means
+	// no limit.
+	Limit uint
+
+	// NewestFirst returns the most recent transactions first.
+	NewestFirst bool
+}
+
+// GetTransactions returns the transactions selected by q in the order in which
+// they were applied, or the reverse order if q.NewestFirst is set. If there
+// are more transactions after the returned page, the cursor of the first
+// transaction on the next page is returned, otherwise the returned cursor is
+// zero. The transactions of FAT-1 chains are returned with only the Entry
+// populated and must be unmarshaled as fat1.Transactions. ErrNotMigrated is
+// returned if q filters by height or time before the chain is migrated.
+func (chain Chain) GetTransactions(q TransactionsQuery) (
+	[]fat0.Transaction, uint64, error) {
+	limit := q.Limit
 	if limit == 0 {
 		limit = math.MaxUint32
 	}
-	var e *entry
-	var es []entry
-	if adr != nil {
-		a, err := chain.getAddress(adr.RCDHash())
+
+	// The first entry is always the Issuance.
+	db := chain.DB.Model(&entry{}).Not("id = ?", 1)
+
+	cmp, order := ">=", "id"
+	if q.NewestFirst {
+		cmp, order = "<=", "id DESC"
+	}
+	if q.Hash != nil {
+		e, err := chain.getEntry(q.Hash)
+		if e == nil {
+			return nil, 0, err
+		}
+		db = db.Where("id "+cmp+" ?", e.ID)
+	}
+	if q.Cursor > 0 {
+		db = db.Where("id "+cmp+" ?", q.Cursor)
+	}
+
+	if (q.StartHeight > 0 || q.EndHeight > 0 ||
+		!q.StartTime.IsZero() || !q.EndTime.IsZero()) &&
+		!chain.IsMigrated() {
+		return nil, 0, ErrNotMigrated
+	}
+	if q.StartHeight > 0 {
+		db = db.Where("height >= ?", q.StartHeight)
+	}
+	if q.EndHeight > 0 {
+		db = db.Where("height <= ?", q.EndHeight)
+	}
+	if !q.StartTime.IsZero() {
+		db = db.Where("timestamp >= ?", q.StartTime.UTC())
+	}
+	if !q.EndTime.IsZero() {
+		db = db.Where("timestamp <= ?", q.EndTime.UTC())
+	}
+
+	if q.Coinbase || q.Burns {
+		coinbase := factom.Address{}
+		a, err := chain.getAddress(coinbase.RCDHash())
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-		var to, from []entry
-		if toFrom != "from" {
-			if err := chain.Limit(limit).Model(&a).
-				Association("To").Find(&to).Error; err != nil {
-				return nil, err
-			}
+		if q.Coinbase {
+			db = db.Where("id IN (SELECT entry_id FROM "+
+				"address_transactions_from WHERE address_id = ?)",
+				a.ID)
 		}
-		if toFrom != "to" {
-			if err := chain.Limit(limit).Model(&a).
-				Association("From").Find(&from).Error; err != nil {
-				return nil, err
-			}
+		if q.Burns {
+			db = db.Where("id IN (SELECT entry_id FROM "+
+				"address_transactions_to WHERE address_id = ?)",
+				a.ID)
 		}
-		if int(limit) > len(to)+len(from) {
-			limit = uint(len(to) + len(from))
-		}
-		es = make([]entry, limit)
-		var t, f int
-		for i := range es {
-			if t < len(to) && f < len(from) {
-				var next entry
-				if to[t].ID < from[f].ID {
-					next = to[t]
-					t++
-				} else {
-					next = from[f]
-					f++
-				}
-				es[i] = next
-			} else {
-				var nexts []entry
-				if t < len(to) {
-					nexts = to
-				} else {
-					nexts = from
-				}
-				es = append(es[:i], nexts...)
-				break
-			}
+	}
+
+	if q.Address != nil {
+		a, err := chain.getAddress(q.Address.RCDHash())
+		if err != nil {
+			return nil, 0, err
 		}
-		if hash != nil {
-			hashId := uint(len(es))
-			for i, e := range es {
-				if *e.Hash == *hash {
-					hashId = uint(i)
-					break
-				}
-			}
-			start += hashId
-			if start > uint(len(es)) {
-				start = uint(len(es))
-			}
+		var ids []string
+		if q.ToFrom != "from" {
+			ids = append(ids, "SELECT entry_id FROM "+
+				"address_transactions_to WHERE address_id = ?")
 		}
-		es = es[start:]
-	} else {
-		if hash != nil {
-			var err error
-			e, err = chain.getEntry(hash)
-			if e == nil {
-				return nil, err
-			}
-			start = uint(e.ID)
-		} else {
-			start++
+		if q.ToFrom != "to" {
+			ids = append(ids, "SELECT entry_id FROM "+
+				"address_transactions_from WHERE address_id = ?")
 		}
-		if err := chain.Offset(start).Limit(limit).Find(&es).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return nil, nil
-			}
-			return nil, err
+		args := make([]interface{}, len(ids))
+		for i := range args {
+			args[i] = a.ID
 		}
+		db = db.Where("id IN ("+strings.Join(ids, " UNION ")+")",
+			args...)
 	}
+
+	// Select one extra entry to determine the next cursor.
+	var es []entry
+	if err := db.Order(order).Offset(q.Start).Limit(uint64(limit) + 1).
+		Find(&es).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	var next uint64
+	if uint(len(es)) > limit {
+		next = es[limit].ID
+		es = es[:limit]
+	}
+
 	txs := make([]fat0.Transaction, len(es))
 	for i, e := range es {
 		txs[i] = fat0.NewTransaction(e.Entry())
+		if chain.Issuance.Type == fat.TypeFAT1 {
+			// Only the Entry is populated for FAT-1 transactions.
+			continue
+		}
 		if err := txs[i].UnmarshalEntry(); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 	}
-	return txs, nil
+	return txs, next, nil
 }
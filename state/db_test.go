@@ -0,0 +1,414 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/flag"
+	_log "github.com/Factom-Asset-Tokens/fatd/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testCoinbase = factom.Address{}
+	testAdrs     = func() []factom.RCDHash {
+		adrs := make([]factom.RCDHash, 3)
+		for i := range adrs {
+			adrs[i][0] = byte(i + 1)
+		}
+		return adrs
+	}()
+)
+
+// newTestChain returns a Chain with a freshly set up database in a temporary
+// directory. The returned func must be called to clean up.
+func newTestChain(t *testing.T) (Chain, func()) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "fatd-state-test")
+	require.NoError(err)
+	flag.DBPath = dir
+	require.NoError(openIndex())
+	chain := Chain{ID: factom.NewBytes32([]byte{0x01})}
+	require.NoError(chain.setupDB())
+	return chain, func() {
+		chain.Close()
+		index.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// applyTestTx applies tx to the chain as if it were included in the EBlock
+// at the given height. Signatures are not validated.
+func applyTestTx(t *testing.T, chain *Chain, height uint64,
+	tx fat0.Transaction) *factom.Bytes32 {
+	require := require.New(t)
+	tx.ChainID = chain.ID
+	tx.Height = height
+	tx.Timestamp = &factom.Time{Time: time.Now()}
+	require.NoError(tx.MarshalEntry())
+	tx.Hash = factom.NewBytes32(nil)
+	*tx.Hash = tx.ComputeHash()
+	require.NoError(chain.apply(tx))
+	return tx.Hash
+}
+
+// newTestChainWithTxs returns a test Chain with an Issuance entry and the
+// following applied transactions. The entry hashes of the transactions are
+// returned in order.
+//
+//	10: coinbase -> 0: 60, 1: 40
+//	11: 1: 40 -> 2: 30, coinbase: 10 (burn)
+//	12: 0: 60 -> 2: 60
+func newTestChainWithTxs(t *testing.T) (Chain, []*factom.Bytes32, func()) {
+	log = _log.New("state")
+	chain, cleanup := newTestChain(t)
+
+	// The first entry is always the Issuance.
+	issuance := factom.Entry{ChainID: chain.ID, Content: factom.Bytes("{}"),
+		Timestamp: &factom.Time{Time: time.Now()}}
+	issuance.Hash = factom.NewBytes32(nil)
+	*issuance.Hash = issuance.ComputeHash()
+	_, err := chain.createEntry(issuance)
+	require.NoError(t, err)
+
+	cb := *testCoinbase.RCDHash()
+	hashes := []*factom.Bytes32{
+		applyTestTx(t, &chain, 10, fat0.Transaction{
+			Inputs: fat0.AddressAmountMap{cb: 100},
+			Outputs: fat0.AddressAmountMap{
+				testAdrs[0]: 60, testAdrs[1]: 40},
+		}),
+		applyTestTx(t, &chain, 11, fat0.Transaction{
+			Inputs: fat0.AddressAmountMap{testAdrs[1]: 40},
+			Outputs: fat0.AddressAmountMap{
+				testAdrs[2]: 30, cb: 10},
+		}),
+		applyTestTx(t, &chain, 12, fat0.Transaction{
+			Inputs:  fat0.AddressAmountMap{testAdrs[0]: 60},
+			Outputs: fat0.AddressAmountMap{testAdrs[2]: 60},
+		}),
+	}
+	return chain, hashes, cleanup
+}
+
+func TestGetHolders(t *testing.T) {
+	chain, _, cleanup := newTestChainWithTxs(t)
+	defer cleanup()
+
+	tests := []struct {
+		Name    string
+		Height  uint64
+		Holders fat0.AddressAmountMap
+	}{{
+		Name:    "before coinbase",
+		Height:  9,
+		Holders: fat0.AddressAmountMap{},
+	}, {
+		Name:    "after coinbase",
+		Height:  10,
+		Holders: fat0.AddressAmountMap{testAdrs[0]: 60, testAdrs[1]: 40},
+	}, {
+		Name:    "after burn",
+		Height:  11,
+		Holders: fat0.AddressAmountMap{testAdrs[0]: 60, testAdrs[2]: 30},
+	}, {
+		Name:    "latest",
+		Height:  100,
+		Holders: fat0.AddressAmountMap{testAdrs[2]: 90},
+	}}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			holders, err := chain.GetHolders(test.Height)
+			assert.NoError(t, err)
+			assert.Equal(t, test.Holders, holders)
+		})
+	}
+}
+
+func TestGetTransactions(t *testing.T) {
+	chain, hashes, cleanup := newTestChainWithTxs(t)
+	defer cleanup()
+
+	adr := func(i int) *factom.Address {
+		a := factom.NewAddress(&testAdrs[i])
+		return &a
+	}
+	tests := []struct {
+		Name   string
+		Query  TransactionsQuery
+		Hashes []*factom.Bytes32
+		Next   bool
+	}{{
+		Name:   "all",
+		Hashes: hashes,
+	}, {
+		Name:   "newest first",
+		Query:  TransactionsQuery{NewestFirst: true},
+		Hashes: []*factom.Bytes32{hashes[2], hashes[1], hashes[0]},
+	}, {
+		Name:   "limit",
+		Query:  TransactionsQuery{Limit: 2},
+		Hashes: hashes[:2],
+		Next:   true,
+	}, {
+		Name:   "hash and start",
+		Query:  TransactionsQuery{Hash: hashes[1], Start: 1},
+		Hashes: hashes[2:],
+	}, {
+		Name:   "address",
+		Query:  TransactionsQuery{Address: adr(2)},
+		Hashes: hashes[1:],
+	}, {
+		Name:   "address to",
+		Query:  TransactionsQuery{Address: adr(1), ToFrom: "to"},
+		Hashes: hashes[:1],
+	}, {
+		Name:   "address from",
+		Query:  TransactionsQuery{Address: adr(1), ToFrom: "from"},
+		Hashes: hashes[1:2],
+	}, {
+		Name: "address newest first limit",
+		Query: TransactionsQuery{Address: adr(0),
+			NewestFirst: true, Limit: 1},
+		Hashes: hashes[2:],
+		Next:   true,
+	}, {
+		Name:   "height range",
+		Query:  TransactionsQuery{StartHeight: 11, EndHeight: 11},
+		Hashes: hashes[1:2],
+	}, {
+		Name:   "start height",
+		Query:  TransactionsQuery{StartHeight: 11},
+		Hashes: hashes[1:],
+	}, {
+		Name:   "end height",
+		Query:  TransactionsQuery{EndHeight: 11},
+		Hashes: hashes[:2],
+	}, {
+		Name: "time range",
+		Query: TransactionsQuery{StartTime: time.Now().Add(-time.Hour),
+			EndTime: time.Now().Add(time.Hour)},
+		Hashes: hashes,
+	}, {
+		Name:   "start time",
+		Query:  TransactionsQuery{StartTime: time.Now().Add(time.Hour)},
+		Hashes: []*factom.Bytes32{},
+	}, {
+		Name:   "coinbase",
+		Query:  TransactionsQuery{Coinbase: true},
+		Hashes: hashes[:1],
+	}, {
+		Name:   "burns",
+		Query:  TransactionsQuery{Burns: true},
+		Hashes: hashes[1:2],
+	}, {
+		Name:   "address burns",
+		Query:  TransactionsQuery{Address: adr(0), Burns: true},
+		Hashes: []*factom.Bytes32{},
+	}}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+			txs, next, err := chain.GetTransactions(test.Query)
+			assert.NoError(err)
+			hashes := make([]*factom.Bytes32, len(txs))
+			for i := range txs {
+				hashes[i] = txs[i].Hash
+			}
+			assert.Equal(test.Hashes, hashes)
+			if !test.Next {
+				assert.Zero(next)
+				return
+			}
+			// The next page must pick up where this one left off.
+			q := test.Query
+			q.Cursor, q.Limit = next, 0
+			rest, _, err := chain.GetTransactions(q)
+			assert.NoError(err)
+			all, _, err := chain.GetTransactions(TransactionsQuery{
+				Address: q.Address, ToFrom: q.ToFrom,
+				NewestFirst: q.NewestFirst})
+			assert.NoError(err)
+			assert.Equal(len(all), len(txs)+len(rest))
+			assert.Equal(all[len(txs)].Hash, rest[0].Hash)
+		})
+	}
+}
+
+func TestGetBlockEntries(t *testing.T) {
+	chain, hashes, cleanup := newTestChainWithTxs(t)
+	defer cleanup()
+	assert := assert.New(t)
+
+	// Spend more than the balance of address 0.
+	rejected := applyTestTx(t, &chain, 12, fat0.Transaction{
+		Inputs:  fat0.AddressAmountMap{testAdrs[0]: 1},
+		Outputs: fat0.AddressAmountMap{testAdrs[1]: 1},
+	})
+
+	bes, err := GetBlockEntries(12)
+	assert.NoError(err)
+	if assert.Len(bes, 2) {
+		assert.Equal(hashes[2], bes[0].Hash)
+		assert.Equal(chain.ID, bes[0].ChainID)
+		assert.True(bes[0].IsValid())
+		assert.Equal(rejected, bes[1].Hash)
+		assert.False(bes[1].IsValid())
+		assert.Contains(bes[1].Rejected, "insufficient balance")
+	}
+
+	bes, err = GetBlockEntries(13)
+	assert.NoError(err)
+	assert.Empty(bes)
+}
+
+func TestGetAddressChains(t *testing.T) {
+	chain, _, cleanup := newTestChainWithTxs(t)
+	defer cleanup()
+	assert := assert.New(t)
+
+	for i := range testAdrs {
+		chainIDs, err := GetAddressChains(factom.NewAddress(&testAdrs[i]))
+		assert.NoError(err)
+		assert.Equal([]*factom.Bytes32{chain.ID}, chainIDs)
+	}
+
+	// Addresses are indexed for existing databases.
+	assert.NoError(index.Delete(&addressChain{}).Error)
+	assert.NoError(chain.indexAddresses())
+	chainIDs, err := GetAddressChains(factom.NewAddress(&testAdrs[0]))
+	assert.NoError(err)
+	assert.Equal([]*factom.Bytes32{chain.ID}, chainIDs)
+
+	unused := factom.RCDHash{0xff}
+	chainIDs, err = GetAddressChains(factom.NewAddress(&unused))
+	assert.NoError(err)
+	assert.Empty(chainIDs)
+}
+
+func TestIndexBlockEntries(t *testing.T) {
+	chain, hashes, cleanup := newTestChainWithTxs(t)
+	defer cleanup()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Simulate fatd stopping after a transaction was applied but before
+	// it was indexed.
+	require.NoError(index.Where("hash = ?", hashes[1]).
+		Delete(&BlockEntry{}).Error)
+	bes, err := GetBlockEntries(11)
+	require.NoError(err)
+	assert.Empty(bes)
+
+	// Nothing is indexed until the heights are known.
+	chain.Metadata.Version = 0
+	require.NoError(chain.indexBlockEntries())
+	bes, err = GetBlockEntries(11)
+	require.NoError(err)
+	assert.Empty(bes)
+
+	chain.Metadata.Version = schemaVersion
+	require.NoError(chain.indexBlockEntries())
+	bes, err = GetBlockEntries(11)
+	require.NoError(err)
+	if assert.Len(bes, 1) {
+		assert.Equal(hashes[1], bes[0].Hash)
+		assert.Equal(chain.ID, bes[0].ChainID)
+		assert.True(bes[0].IsValid())
+	}
+	for _, height := range []uint64{10, 12} {
+		bes, err = GetBlockEntries(height)
+		require.NoError(err)
+		assert.Len(bes, 1)
+	}
+
+	// Indexing again changes nothing.
+	require.NoError(chain.indexBlockEntries())
+	bes, err = GetBlockEntries(11)
+	require.NoError(err)
+	assert.Len(bes, 1)
+}
+
+func TestReset(t *testing.T) {
+	chain, _, cleanup := newTestChainWithTxs(t)
+	defer cleanup()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	require.NoError(chain.Close())
+	require.NoError(index.Close())
+	require.NoError(Reset(nil))
+	assert.Nil(index)
+
+	fpath := fmt.Sprintf("%v/%v%v", flag.DBPath, chain.ID, dbFileExtension)
+	_, err := os.Stat(fpath)
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(fpath + ".bak")
+	assert.NoError(err)
+
+	require.NoError(openIndex())
+	bes, err := GetBlockEntries(12)
+	assert.NoError(err)
+	assert.Empty(bes)
+	chainIDs, err := GetAddressChains(factom.NewAddress(&testAdrs[0]))
+	assert.NoError(err)
+	assert.Empty(chainIDs)
+}
+
+func TestResetChain(t *testing.T) {
+	chain, _, cleanup := newTestChainWithTxs(t)
+	defer cleanup()
+	assert := assert.New(t)
+	require := require.New(t)
+	Chains.set(chain.ID, &chain)
+
+	require.NoError(ResetChain(chain.ID))
+	assert.True(Chains.Get(chain.ID).IsUnknown())
+	assert.NotContains(Chains.GetIssued(), chain.ID)
+	fpath := fmt.Sprintf("%v/%v%v", flag.DBPath, chain.ID, dbFileExtension)
+	_, err := os.Stat(fpath + ".bak")
+	assert.NoError(err)
+	bes, err := GetBlockEntries(12)
+	assert.NoError(err)
+	assert.Empty(bes)
+
+	Chains.SetBackfilling(chain.ID, true)
+	assert.Equal([]*factom.Bytes32{chain.ID}, Chains.GetBackfilling())
+	Chains.SetBackfilling(chain.ID, false)
+	assert.Empty(Chains.GetBackfilling())
+
+	require.NoError(IgnoreChain(chain.ID))
+	assert.True(Chains.Get(chain.ID).IsIgnored())
+	Chains.remove(chain.ID)
+}
+
+func TestSaveHeightQuarantined(t *testing.T) {
+	chain, _, cleanup := newTestChainWithTxs(t)
+	defer cleanup()
+	assert := assert.New(t)
+	require := require.New(t)
+	chain.ChainStatus = ChainStatusTracked
+	Chains.set(chain.ID, &chain)
+	defer Chains.remove(chain.ID)
+
+	require.NoError(SaveHeight(20))
+	assert.Equal(uint64(20), Chains.Get(chain.ID).Metadata.Height)
+
+	// A quarantined chain stays at the last height that it processed.
+	Chains.Quarantine(chain.ID, fmt.Errorf("test"))
+	require.NoError(SaveHeight(30))
+	require.NoError(SaveChainHeight(chain.ID, 30))
+	assert.Equal(uint64(20), Chains.Get(chain.ID).Metadata.Height)
+	assert.Equal(uint64(30), SavedHeight)
+
+	// The saved height is not advanced either.
+	var saved Metadata
+	require.NoError(chain.First(&saved).Error)
+	assert.Equal(uint64(20), saved.Height)
+}
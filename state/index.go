@@ -0,0 +1,191 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/flag"
+	"github.com/jinzhu/gorm"
+)
+
+// index is the database of data that spans all tracked chains.
+var index *gorm.DB
+
+const indexFileName = "index" + dbFileExtension
+
+// BlockEntry records the outcome of processing an Issuance or Transaction
+// entry in a tracked chain at a given height.
+type BlockEntry struct {
+	ID      uint64
+	Height  uint64          `gorm:"UNIQUE_INDEX:idx_height_hash; NOT NULL;"`
+	Hash    *factom.Bytes32 `gorm:"type:VARCHAR(32); UNIQUE_INDEX:idx_height_hash; NOT NULL;"`
+	ChainID *factom.Bytes32 `gorm:"type:VARCHAR(32); NOT NULL;"`
+
+	// Rejected is the reason the entry was not applied. It is empty for
+	// valid entries.
+	Rejected string
+}
+
+// IsValid returns true if the entry was applied to its chain.
+func (be BlockEntry) IsValid() bool {
+	return len(be.Rejected) == 0
+}
+
+// openIndex opens the index database, creating it if it does not exist.
+func openIndex() error {
+	var err error
+	if index, err = gorm.Open(dbDriver,
+		flag.DBPath+"/"+indexFileName); err != nil {
+		return err
+	}
+	index.LogMode(false)
+	// Chains are processed concurrently but SQLite only allows a single
+	// writer.
+	index.DB().SetMaxOpenConns(1)
+	if err := index.AutoMigrate(&BlockEntry{}).Error; err != nil {
+		index.Close()
+		return fmt.Errorf("index.AutoMigrate(&BlockEntry{}): %v", err)
+	}
+	if err := index.AutoMigrate(&addressChain{}).Error; err != nil {
+		index.Close()
+		return fmt.Errorf("index.AutoMigrate(&addressChain{}): %v", err)
+	}
+	return nil
+}
+
+// saveBlockEntry records that e was processed for chain at e.Height. If
+// rejected is not nil, then e is recorded as rejected for that reason.
+func (chain Chain) saveBlockEntry(e factom.Entry, rejected error) error {
+	be := BlockEntry{Height: e.Height, Hash: e.Hash, ChainID: chain.ID}
+	if rejected != nil {
+		be.Rejected = rejected.Error()
+	}
+	// A partially processed EBlock may be processed again after a
+	// restart, so keep whatever was recorded the first time.
+	return index.Where(BlockEntry{Height: be.Height, Hash: be.Hash}).
+		FirstOrCreate(&be).Error
+}
+
+// indexBlockEntries adds a BlockEntry for each entry in the chain's database
+// that is missing from the index. This happens if fatd stopped after an entry
+// was applied to the chain but before it was indexed, or if the database was
+// created before the index existed. Rejected entries are not saved in the
+// chain's database, but since the chain's height is only saved after its
+// EBlock is fully processed, they are indexed when the EBlock is processed
+// again.
+//
+// The heights of the entries are required, so nothing is done until the chain
+// is migrated.
+func (chain Chain) indexBlockEntries() (err error) {
+	if !chain.IsMigrated() {
+		return nil
+	}
+	var indexed, count int
+	if err := index.Model(&BlockEntry{}).
+		Where("chain_id = ? AND rejected = ?", chain.ID, "").
+		Count(&indexed).Error; err != nil {
+		return err
+	}
+	if err := chain.DB.Model(&entry{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if indexed >= count {
+		return nil
+	}
+
+	rows, err := chain.DB.Model(&entry{}).Select("hash, height").
+		Order("id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	tx := index.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	for rows.Next() {
+		var e entry
+		if err := chain.ScanRows(rows, &e); err != nil {
+			return err
+		}
+		be := BlockEntry{Height: e.Height, Hash: e.Hash, ChainID: chain.ID}
+		if err := tx.Where(BlockEntry{Height: be.Height, Hash: be.Hash}).
+			FirstOrCreate(&be).Error; err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// GetBlockEntries returns all Issuance and Transaction entries from tracked
+// chains that were processed at height, in the order they were processed.
+func GetBlockEntries(height uint64) ([]BlockEntry, error) {
+	var bes []BlockEntry
+	if err := index.Where("height = ?", height).Order("id").
+		Find(&bes).Error; err != nil {
+		return nil, err
+	}
+	return bes, nil
+}
+
+// addressChain records that an address has received tokens on a chain.
+type addressChain struct {
+	ID      uint64
+	RCDHash *factom.RCDHash `gorm:"type:VARCHAR(32); UNIQUE_INDEX:idx_address_chain; NOT NULL;"`
+	ChainID *factom.Bytes32 `gorm:"type:VARCHAR(32); UNIQUE_INDEX:idx_address_chain; NOT NULL;"`
+}
+
+// saveAddressChains records that each of rcdHashes has received tokens on
+// chain.
+func (chain Chain) saveAddressChains(rcdHashes ...*factom.RCDHash) error {
+	for _, rcdHash := range rcdHashes {
+		ac := addressChain{RCDHash: rcdHash, ChainID: chain.ID}
+		if err := index.Where(ac).FirstOrCreate(&ac).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexAddresses adds all addresses in the chain's database to the index if
+// the index has no addresses for the chain. This populates the index for
+// databases that were created before it existed.
+func (chain Chain) indexAddresses() error {
+	var count int
+	if err := index.Model(&addressChain{}).
+		Where("chain_id = ?", chain.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	var adrs []address
+	if err := chain.Select("rcd_hash").Find(&adrs).Error; err != nil {
+		return err
+	}
+	rcdHashes := make([]*factom.RCDHash, len(adrs))
+	for i := range adrs {
+		rcdHashes[i] = adrs[i].RCDHash
+	}
+	return chain.saveAddressChains(rcdHashes...)
+}
+
+// GetAddressChains returns the IDs of all chains on which adr has ever
+// received tokens.
+func GetAddressChains(adr factom.Address) ([]*factom.Bytes32, error) {
+	var acs []addressChain
+	if err := index.Where("rcd_hash = ?", adr.RCDHash()).Order("id").
+		Find(&acs).Error; err != nil {
+		return nil, err
+	}
+	chainIDs := make([]*factom.Bytes32, len(acs))
+	for i := range acs {
+		chainIDs[i] = acs[i].ChainID
+	}
+	return chainIDs, nil
+}
@@ -0,0 +1,106 @@
+package state
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+)
+
+// schemaVersion is the Metadata.Version of databases created by this version
+// of fatd.
+//
+// Version 1 records the height of each entry and saves all timestamps in UTC.
+// Earlier databases have a height of 0 for every entry and timestamps in the
+// local time zone, which SQLite compares as text, so queries by height or time
+// are not possible until the chain is migrated.
+const schemaVersion = 1
+
+// ErrNotMigrated is returned by queries that depend on data that is not
+// available until the chain's database is migrated.
+var ErrNotMigrated = fmt.Errorf("database not migrated: " +
+	"restart fatd while factomd is reachable to migrate it")
+
+// IsMigrated returns true if the chain's database has the current
+// schemaVersion.
+func (chain Chain) IsMigrated() bool {
+	return chain.Metadata.Version >= schemaVersion
+}
+
+// migrate the chain's database to the current schemaVersion using the chain's
+// EBlocks from factomd. If the EBlocks cannot be retrieved, a warning is logged
+// and the chain is left unmigrated until fatd is next started.
+func (chain *Chain) migrate(ctx context.Context) error {
+	if chain.IsMigrated() {
+		return nil
+	}
+	log.Infof("Migrating chain %v...", chain.ID)
+	eb := factom.EBlock{ChainID: chain.ID}
+	if err := eb.Get(ctx); err != nil {
+		log.Warnf("Chain %v not migrated: EBlock.Get(): %v",
+			chain.ID, err)
+		return nil
+	}
+	ebs, err := eb.GetAllPrev(ctx)
+	if err != nil {
+		log.Warnf("Chain %v not migrated: EBlock.GetAllPrev(): %v",
+			chain.ID, err)
+		return nil
+	}
+	return chain.migrateEntries(ebs)
+}
+
+// migrateEntries sets the height of each entry in the chain's database from
+// the EBlock in ebs that contains it, converts all entry timestamps to UTC,
+// and then saves the current schemaVersion. If any entry is not found in ebs,
+// a warning is logged and the chain is left unmigrated.
+func (chain *Chain) migrateEntries(ebs []factom.EBlock) (err error) {
+	tx := chain.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	for _, eb := range ebs {
+		for _, e := range eb.Entries {
+			if err := tx.Model(&entry{}).
+				Where("hash = ?", e.Hash).
+				Update("height", eb.Height).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	var es []entry
+	if err := tx.Select("id, timestamp").Find(&es).Error; err != nil {
+		return err
+	}
+	for _, e := range es {
+		if err := tx.Model(&e).
+			Update("timestamp", e.Timestamp.UTC()).Error; err != nil {
+			return err
+		}
+	}
+
+	var count int
+	if err := tx.Model(&entry{}).Where("height = ?", 0).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Warnf("Chain %v not migrated: %v entries not found in "+
+			"factomd", chain.ID, count)
+		return tx.Rollback().Error
+	}
+
+	metadata := chain.Metadata
+	metadata.Version = schemaVersion
+	if err := tx.Save(&metadata).Error; err != nil {
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	chain.Metadata = metadata
+	return nil
+}
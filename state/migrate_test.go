@@ -0,0 +1,78 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateEntries(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	chain, hashes, cleanup := newTestChainWithTxs(t)
+	defer cleanup()
+
+	var issuance entry
+	require.NoError(chain.First(&issuance).Error)
+
+	// Simulate a database created before entry heights were recorded
+	// and timestamps were saved in UTC.
+	require.NoError(chain.DB.Model(&entry{}).Update("height", 0).Error)
+	timestamp := time.Now().Add(-time.Hour)
+	require.NoError(chain.DB.Model(&entry{}).Where("hash = ?", hashes[0]).
+		Update("timestamp", timestamp.In(
+			time.FixedZone("UTC+10", 10*60*60))).Error)
+	chain.Metadata.Version = 0
+	require.NoError(chain.saveMetadata())
+
+	_, err := chain.GetHolders(100)
+	assert.Equal(ErrNotMigrated, err)
+	q := TransactionsQuery{EndTime: timestamp.Add(time.Minute)}
+	_, _, err = chain.GetTransactions(q)
+	assert.Equal(ErrNotMigrated, err)
+
+	eblock := func(height uint64, hashes ...*factom.Bytes32) factom.EBlock {
+		eb := factom.EBlock{ChainID: chain.ID}
+		eb.Height = height
+		for _, hash := range hashes {
+			eb.Entries = append(eb.Entries, factom.Entry{Hash: hash})
+		}
+		return eb
+	}
+	ebs := []factom.EBlock{
+		eblock(9, issuance.Hash),
+		eblock(10, hashes[0]),
+		eblock(11, hashes[1]),
+	}
+
+	// The chain is not migrated if any entry is missing.
+	require.NoError(chain.migrateEntries(ebs))
+	assert.False(chain.IsMigrated())
+	var count int
+	require.NoError(chain.DB.Model(&entry{}).Where("height = ?", 0).
+		Count(&count).Error)
+	assert.Equal(len(hashes)+1, count)
+
+	ebs = append(ebs, eblock(12, hashes[2]))
+	require.NoError(chain.migrateEntries(ebs))
+	assert.True(chain.IsMigrated())
+
+	var saved Metadata
+	require.NoError(chain.First(&saved).Error)
+	assert.Equal(uint(schemaVersion), saved.Version)
+
+	holders, err := chain.GetHolders(10)
+	assert.NoError(err)
+	assert.Equal(fat0.AddressAmountMap{testAdrs[0]: 60, testAdrs[1]: 40},
+		holders)
+
+	txs, _, err := chain.GetTransactions(q)
+	assert.NoError(err)
+	if assert.Len(txs, 1) {
+		assert.Equal(hashes[0], txs[0].Hash)
+	}
+}
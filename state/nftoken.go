@@ -0,0 +1,247 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
+	"github.com/jinzhu/gorm"
+)
+
+// nfToken records the current owner and metadata of a FAT-1 NFTokenID. The
+// NFTokenID is not used as the primary key because zero is a valid
+// NFTokenID.
+type nfToken struct {
+	ID        uint64
+	NFTokenID fat1.NFTokenID `gorm:"UNIQUE_INDEX; NOT NULL;"`
+	OwnerID   uint64         `gorm:"INDEX; NOT NULL;"`
+	Metadata  factom.Bytes
+}
+
+// NFToken is a FAT-1 NFTokenID with its current owner and any metadata set
+// when it was issued.
+type NFToken struct {
+	ID       fat1.NFTokenID
+	Owner    factom.RCDHash
+	Metadata json.RawMessage
+}
+
+func (chain *Chain) applyNFTransaction(transaction fat1.Transaction) (err error) {
+	db := chain.Begin()
+	defer chain.rollbackUnlessCommitted(*chain, &err)
+	chain.DB = db
+
+	entry, err := chain.createEntry(transaction.Entry.Entry)
+	if entry == nil {
+		// replayed transaction
+		if err == nil {
+			log.Debugf("Invalid Transaction Entry: %v, "+
+				"replayed transaction",
+				transaction.Hash)
+			return chain.saveBlockEntry(transaction.Entry.Entry,
+				fmt.Errorf("replayed transaction"))
+		}
+		return err
+	}
+
+	for rcdHash, tkns := range transaction.Inputs {
+		adr, err := chain.getAddress(&rcdHash)
+		if err != nil {
+			return err
+		}
+		if err := chain.DB.Model(&adr).
+			Association("From").Append(entry).Error; err != nil {
+			return err
+		}
+		amount := uint64(len(tkns))
+		if transaction.IsCoinbase() {
+			if chain.Supply > 0 &&
+				uint64(chain.Supply)-chain.Issued < amount {
+				// insufficient coinbase supply
+				log.Debugf("Invalid Transaction Entry: %v, "+
+					"insufficient coinbase supply",
+					entry.Hash)
+				return chain.saveBlockEntry(
+					transaction.Entry.Entry,
+					fmt.Errorf("insufficient coinbase supply"))
+			}
+			count, err := chain.countNFTokens(tkns, "")
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				// NFTokenIDs may only be issued once.
+				log.Debugf("Invalid Transaction Entry: %v, "+
+					"NFTokenID already issued", entry.Hash)
+				return chain.saveBlockEntry(
+					transaction.Entry.Entry,
+					fmt.Errorf("NFTokenID already issued"))
+			}
+			chain.Issued += amount
+			if err := chain.saveMetadata(); err != nil {
+				return err
+			}
+			break
+		}
+		count, err := chain.countNFTokens(tkns, "owner_id = ?", adr.ID)
+		if err != nil {
+			return err
+		}
+		if count != amount {
+			// insufficient balance
+			log.Debugf("Invalid Transaction Entry: %v, "+
+				"insufficient balance: %v",
+				entry.Hash, adr.Address())
+			return chain.saveBlockEntry(transaction.Entry.Entry,
+				fmt.Errorf("insufficient balance: %v",
+					adr.Address()))
+		}
+		adr.Balance -= amount
+		if err := chain.Save(&adr).Error; err != nil {
+			return err
+		}
+	}
+
+	for rcdHash, tkns := range transaction.Outputs {
+		a, err := chain.getAddress(&rcdHash)
+		if err != nil {
+			return err
+		}
+		a.Balance += uint64(len(tkns))
+		if err := chain.Save(&a).Error; err != nil {
+			return err
+		}
+		if err := chain.DB.Model(&a).
+			Association("To").Append(entry).Error; err != nil {
+			return err
+		}
+		if !transaction.IsCoinbase() {
+			if err := forNFTokenIDs(tkns, func(ids []fat1.NFTokenID) error {
+				return chain.DB.Model(&nfToken{}).
+					Where("nf_token_id IN (?)", ids).
+					Update("owner_id", a.ID).Error
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		for tknID := range tkns {
+			tkn := nfToken{NFTokenID: tknID, OwnerID: a.ID,
+				Metadata: factom.Bytes(
+					transaction.TokenMetadata[tknID])}
+			if err := chain.Create(&tkn).Error; err != nil {
+				return err
+			}
+		}
+	}
+	log.Debugf("Valid Transaction Entry: %+v", transaction)
+
+	if err := chain.Commit().Error; err != nil {
+		return err
+	}
+	outputs := make([]*factom.RCDHash, 0, len(transaction.Outputs))
+	for rcdHash := range transaction.Outputs {
+		rcdHash := rcdHash
+		outputs = append(outputs, &rcdHash)
+	}
+	if err := chain.saveAddressChains(outputs...); err != nil {
+		return err
+	}
+	return chain.saveBlockEntry(transaction.Entry.Entry, nil)
+}
+
+// maxSQLVariables is the maximum number of NFTokenIDs used in a single query,
+// which is kept well below SQLite's default limit of 999 host parameters.
+const maxSQLVariables = 500
+
+// forNFTokenIDs calls f with the NFTokenIDs of tkns in sorted batches of at
+// most maxSQLVariables.
+func forNFTokenIDs(tkns fat1.NFTokens, f func([]fat1.NFTokenID) error) error {
+	ids := tkns.Slice()
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxSQLVariables {
+			n = maxSQLVariables
+		}
+		if err := f(ids[:n]); err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+// countNFTokens returns the number of issued NFTokenIDs in tkns that also
+// satisfy the optional where condition.
+func (chain Chain) countNFTokens(tkns fat1.NFTokens,
+	where string, args ...interface{}) (uint64, error) {
+	var total uint64
+	err := forNFTokenIDs(tkns, func(ids []fat1.NFTokenID) error {
+		db := chain.DB.Model(&nfToken{}).Where("nf_token_id IN (?)", ids)
+		if len(where) > 0 {
+			db = db.Where(where, args...)
+		}
+		var count uint64
+		if err := db.Count(&count).Error; err != nil {
+			return err
+		}
+		total += count
+		return nil
+	})
+	return total, err
+}
+
+// CountNFTokens returns the number of tkns that have been issued. If owner is
+// not nil, only those currently owned by owner are counted.
+func (chain Chain) CountNFTokens(tkns fat1.NFTokens,
+	owner *factom.Address) (uint64, error) {
+	if owner == nil {
+		return chain.countNFTokens(tkns, "")
+	}
+	a, err := chain.getAddress(owner.RCDHash())
+	if err != nil || a.ID == 0 {
+		return 0, err
+	}
+	return chain.countNFTokens(tkns, "owner_id = ?", a.ID)
+}
+
+// GetNFToken returns the NFToken with the given id, or nil if it has not been
+// issued.
+func (chain Chain) GetNFToken(id fat1.NFTokenID) (*NFToken, error) {
+	var tkn nfToken
+	if err := chain.Where("nf_token_id = ?", id).
+		First(&tkn).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var owner address
+	if err := chain.First(&owner, tkn.OwnerID).Error; err != nil {
+		return nil, err
+	}
+	return &NFToken{ID: id, Owner: *owner.RCDHash,
+		Metadata: json.RawMessage(tkn.Metadata)}, nil
+}
+
+// GetNFTokens returns the NFTokenIDs currently owned by adr.
+func (chain Chain) GetNFTokens(adr factom.Address) (fat1.NFTokens, error) {
+	a, err := chain.getAddress(adr.RCDHash())
+	if err != nil {
+		return nil, err
+	}
+	tkns := make(fat1.NFTokens)
+	if a.ID == 0 {
+		return tkns, nil
+	}
+	var ids []fat1.NFTokenID
+	if err := chain.DB.Model(&nfToken{}).Where("owner_id = ?", a.ID).
+		Pluck("nf_token_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		tkns[id] = struct{}{}
+	}
+	return tkns, nil
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	"github.com/Factom-Asset-Tokens/fatd/fat"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
+	_log "github.com/Factom-Asset-Tokens/fatd/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyTestNFTx applies tx to the chain as if it were included in the EBlock
+// at the given height. Signatures are not validated.
+func applyTestNFTx(t *testing.T, chain *Chain, height uint64,
+	tx fat1.Transaction) *factom.Bytes32 {
+	require := require.New(t)
+	tx.ChainID = chain.ID
+	tx.Height = height
+	tx.Timestamp = &factom.Time{Time: time.Now()}
+	require.NoError(tx.MarshalEntry())
+	tx.Hash = factom.NewBytes32(nil)
+	*tx.Hash = tx.ComputeHash()
+	require.NoError(chain.applyNFTransaction(tx))
+	return tx.Hash
+}
+
+func newTestNFTokens(t *testing.T, ids ...fat1.NFTokensSetter) fat1.NFTokens {
+	tkns, err := fat1.NewNFTokens(ids...)
+	require.NoError(t, err)
+	return tkns
+}
+
+func TestApplyNFTransaction(t *testing.T) {
+	log = _log.New("state")
+	chain, cleanup := newTestChain(t)
+	defer cleanup()
+	assert := assert.New(t)
+	chain.Issuance.Type = fat.TypeFAT1
+	chain.Supply = 10
+
+	issuance := factom.Entry{ChainID: chain.ID, Content: factom.Bytes("{}"),
+		Timestamp: &factom.Time{Time: time.Now()}}
+	issuance.Hash = factom.NewBytes32(nil)
+	*issuance.Hash = issuance.ComputeHash()
+	_, err := chain.createEntry(issuance)
+	require.NoError(t, err)
+
+	cb := *testCoinbase.RCDHash()
+	metadata := json.RawMessage(`{"name":"zero"}`)
+	applyTestNFTx(t, &chain, 10, fat1.Transaction{
+		Inputs: fat1.AddressNFTokensMap{
+			cb: newTestNFTokens(t, fat1.NewNFTokenIDRange(0, 4))},
+		Outputs: fat1.AddressNFTokensMap{
+			testAdrs[0]: newTestNFTokens(t, fat1.NewNFTokenIDRange(0, 2)),
+			testAdrs[1]: newTestNFTokens(t, fat1.NFTokenID(3),
+				fat1.NFTokenID(4))},
+		TokenMetadata: fat1.NFTokenIDMetadataMap{0: metadata},
+	})
+	applyTestNFTx(t, &chain, 11, fat1.Transaction{
+		Inputs: fat1.AddressNFTokensMap{
+			testAdrs[0]: newTestNFTokens(t, fat1.NFTokenID(0))},
+		Outputs: fat1.AddressNFTokensMap{
+			testAdrs[2]: newTestNFTokens(t, fat1.NFTokenID(0))},
+	})
+	assert.Equal(uint64(5), chain.Issued)
+
+	// Re-issuing an NFTokenID is rejected.
+	rejected := applyTestNFTx(t, &chain, 12, fat1.Transaction{
+		Inputs: fat1.AddressNFTokensMap{
+			cb: newTestNFTokens(t, fat1.NFTokenID(4))},
+		Outputs: fat1.AddressNFTokensMap{
+			testAdrs[0]: newTestNFTokens(t, fat1.NFTokenID(4))},
+	})
+	// Spending an NFTokenID that is not owned is rejected.
+	applyTestNFTx(t, &chain, 12, fat1.Transaction{
+		Inputs: fat1.AddressNFTokensMap{
+			testAdrs[0]: newTestNFTokens(t, fat1.NFTokenID(3))},
+		Outputs: fat1.AddressNFTokensMap{
+			testAdrs[2]: newTestNFTokens(t, fat1.NFTokenID(3))},
+	})
+	bes, err := GetBlockEntries(12)
+	assert.NoError(err)
+	if assert.Len(bes, 2) {
+		assert.Equal(rejected, bes[0].Hash)
+		assert.Contains(bes[0].Rejected, "already issued")
+		assert.Contains(bes[1].Rejected, "insufficient balance")
+	}
+
+	tkn, err := chain.GetNFToken(0)
+	assert.NoError(err)
+	if assert.NotNil(tkn) {
+		assert.Equal(testAdrs[2], tkn.Owner)
+		assert.Equal(metadata, tkn.Metadata)
+	}
+	tkn, err = chain.GetNFToken(5)
+	assert.NoError(err)
+	assert.Nil(tkn)
+
+	tkns, err := chain.GetNFTokens(factom.NewAddress(&testAdrs[0]))
+	assert.NoError(err)
+	assert.Equal(newTestNFTokens(t, fat1.NFTokenID(1), fat1.NFTokenID(2)),
+		tkns)
+	balance, err := chain.GetBalance(factom.NewAddress(&testAdrs[0]))
+	assert.NoError(err)
+	assert.Equal(uint64(2), balance)
+
+	count, err := chain.CountNFTokens(newTestNFTokens(t,
+		fat1.NewNFTokenIDRange(3, 6)), nil)
+	assert.NoError(err)
+	assert.Equal(uint64(2), count)
+	owner := factom.NewAddress(&testAdrs[1])
+	count, err = chain.CountNFTokens(newTestNFTokens(t,
+		fat1.NewNFTokenIDRange(2, 4)), &owner)
+	assert.NoError(err)
+	assert.Equal(uint64(2), count)
+
+	holders, err := chain.GetHolders(11)
+	assert.NoError(err)
+	assert.Equal(map[factom.RCDHash]uint64{
+		testAdrs[0]: 2, testAdrs[1]: 2, testAdrs[2]: 1},
+		map[factom.RCDHash]uint64(holders))
+}
@@ -1,29 +1,55 @@
 package state
 
 import (
+	"context"
 	"fmt"
 
 	jrpc "github.com/AdamSLevy/jsonrpc2/v10"
 	"github.com/Factom-Asset-Tokens/fatd/factom"
 	"github.com/Factom-Asset-Tokens/fatd/fat"
 	"github.com/Factom-Asset-Tokens/fatd/fat/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/fat/fat1"
 )
 
-func (chain *Chain) Process(eb factom.EBlock) error {
+// MaxFailures is the number of consecutive times that Process may fail for a
+// chain with a FetchError that is not a network error before the chain is
+// quarantined.
+var MaxFailures uint = 5
+
+// FetchError is returned by Process if the data required to process an EBlock
+// could not be retrieved from factomd. All data is retrieved before any changes
+// are made to the chain, so the EBlock may be processed again.
+type FetchError struct {
+	Op  string
+	Err error
+}
+
+func (err FetchError) Error() string {
+	return fmt.Sprintf("%v: %v", err.Op, err.Err)
+}
+
+// Process the EBlock eb for chain. Any error other than a FetchError indicates
+// a failure to save the state of the chain.
+//
+// If Process fails for the chain MaxFailures consecutive times with a
+// FetchError that is not a network error, the chain is quarantined.
+func (chain *Chain) Process(ctx context.Context, eb factom.EBlock) (err error) {
 	// Ensure changes to chain are saved in Chains.
 	defer Chains.set(eb.ChainID, chain)
+	defer chain.recordFailure(&err)
 
 	// Load this Entry Block.
-	if err := eb.Get(); err != nil {
-		return fmt.Errorf("%#v.Get(): %v", eb, err)
+	if err := eb.Get(ctx); err != nil {
+		return FetchError{Op: fmt.Sprintf("%#v.Get()", eb), Err: err}
 	}
 
 	// Check if the EBlock represents a new chain.
 	if eb.IsFirst() {
 		// Load first entry of new chain.
 		first := eb.Entries[0]
-		if err := first.Get(); err != nil {
-			return fmt.Errorf("%#v.Get: %v", first, err)
+		if err := first.Get(ctx); err != nil {
+			return FetchError{Op: fmt.Sprintf("%#v.Get()", first),
+				Err: err}
 		}
 
 		// Ignore chains with NameIDs that don't match the fat pattern.
@@ -33,10 +59,18 @@ func (chain *Chain) Process(eb factom.EBlock) error {
 			return nil
 		}
 
+		// Retrieve everything else required before tracking the chain
+		// so that a FetchError leaves the chain unchanged.
+		identity := fat.Identity{ChainID: factom.NewBytes32(first.ExtIDs[3])}
+		if err := fetch(ctx, &identity, eb.Entries[1:]); err != nil {
+			return err
+		}
+
 		// Track this chain going forward.
 		if err := chain.track(first); err != nil {
 			return err
 		}
+		chain.Identity = identity
 		if len(eb.Entries) == 1 {
 			return nil
 		}
@@ -47,11 +81,54 @@ func (chain *Chain) Process(eb factom.EBlock) error {
 		// Ignore chains that are not already tracked.
 		chain.ignore()
 		return nil
+	} else if err := fetch(ctx, &chain.Identity, eb.Entries); err != nil {
+		return err
 	}
 
 	return chain.process(eb)
 }
 
+// fetch retrieves the Identity, if it is not already populated, and the data
+// for all of es. The Identity may not exist yet, in which case it remains
+// unpopulated.
+func fetch(ctx context.Context, identity *fat.Identity, es []factom.Entry) error {
+	if !identity.IsPopulated() {
+		if err := identity.Get(ctx); err != nil {
+			if _, ok := err.(jrpc.Error); !ok {
+				return FetchError{Op: fmt.Sprintf("Identity%v.Get()",
+					identity.ChainID), Err: err}
+			}
+		}
+	}
+	for i := range es {
+		if err := es[i].Get(ctx); err != nil {
+			return FetchError{Op: fmt.Sprintf("Entry%v.Get()", es[i].Hash),
+				Err: err}
+		}
+	}
+	return nil
+}
+
+// recordFailure updates the Failures of chain with the error returned by
+// Process. Network errors are not the fault of the chain and so are not
+// counted.
+func (chain *Chain) recordFailure(err *error) {
+	fetchErr, ok := (*err).(FetchError)
+	if !ok || factom.IsNetworkError(fetchErr.Err) {
+		if *err == nil {
+			chain.Failures = ChainFailures{}
+		}
+		return
+	}
+	chain.Failures.Count++
+	chain.Failures.Err = fetchErr
+	if chain.Failures.Count >= MaxFailures {
+		chain.Failures.Quarantined = true
+		log.Errorf("Chain %v quarantined after %v failures: %v",
+			chain.ID, chain.Failures.Count, fetchErr)
+	}
+}
+
 func (chain *Chain) process(eb factom.EBlock) (err error) {
 	defer func() {
 		if err != nil {
@@ -70,14 +147,8 @@ func (chain *Chain) process(eb factom.EBlock) (err error) {
 // in terms of computation and memory.
 func (chain *Chain) processIssuance(es []factom.Entry) error {
 	if !chain.Identity.IsPopulated() {
-		// The Identity may not have existed when this chain was first tracked.
-		// Attempt to retrieve it.
-		if err := chain.Identity.Get(); err != nil {
-			if _, ok := err.(jrpc.Error); ok {
-				return nil
-			}
-			return err
-		}
+		// The Identity did not exist when this EBlock was fetched.
+		return nil
 	}
 	// If these entries were created in a lower block height than the
 	// Identity entry, then none of them can be a valid Issuance entry.
@@ -93,19 +164,21 @@ func (chain *Chain) processIssuance(es []factom.Entry) error {
 				"created before identity")
 			continue
 		}
-		// Get the data for the entry.
-		if err := e.Get(); err != nil {
-			return fmt.Errorf("Entry%+v.Get(): %v", e, err)
-		}
 		issuance := fat.NewIssuance(e)
 		if err := issuance.Valid(chain.Identity.IDKey); err != nil {
 			log.Debugf("Invalid Issuance Entry: %v, %v", e.Hash, err)
+			if err := chain.saveBlockEntry(e, err); err != nil {
+				return err
+			}
 			continue
 		}
 
 		if err := chain.issue(issuance); err != nil {
 			return err
 		}
+		if err := chain.saveBlockEntry(e, nil); err != nil {
+			return err
+		}
 
 		// Process remaining entries as transactions
 		return chain.processTransactions(es[i+1:])
@@ -115,12 +188,18 @@ func (chain *Chain) processIssuance(es []factom.Entry) error {
 
 func (chain *Chain) processTransactions(es []factom.Entry) error {
 	for _, e := range es {
-		if err := e.Get(); err != nil {
-			return fmt.Errorf("Entry%v.Get(): %v", e, err)
+		if chain.Issuance.Type == fat.TypeFAT1 {
+			if err := chain.processNFTransaction(e); err != nil {
+				return err
+			}
+			continue
 		}
 		transaction := fat0.NewTransaction(e)
 		if err := transaction.Valid(chain.Identity.IDKey); err != nil {
 			log.Debugf("Invalid Transaction Entry: %v, %v", e.Hash, err)
+			if err := chain.saveBlockEntry(e, err); err != nil {
+				return err
+			}
 			continue
 		}
 		if err := chain.apply(transaction); err != nil {
@@ -130,6 +209,15 @@ func (chain *Chain) processTransactions(es []factom.Entry) error {
 	return nil
 }
 
+func (chain *Chain) processNFTransaction(e factom.Entry) error {
+	transaction := fat1.NewTransaction(e)
+	if err := transaction.Valid(chain.Identity.IDKey); err != nil {
+		log.Debugf("Invalid Transaction Entry: %v, %v", e.Hash, err)
+		return chain.saveBlockEntry(e, err)
+	}
+	return chain.applyNFTransaction(transaction)
+}
+
 func (chain *Chain) apply(transaction fat0.Transaction) (err error) {
 	db := chain.Begin()
 	defer chain.rollbackUnlessCommitted(*chain, &err)
@@ -142,6 +230,8 @@ func (chain *Chain) apply(transaction fat0.Transaction) (err error) {
 			log.Debugf("Invalid Transaction Entry: %v, "+
 				"replayed transaction",
 				transaction.Hash)
+			return chain.saveBlockEntry(transaction.Entry.Entry,
+				fmt.Errorf("replayed transaction"))
 		}
 		return err
 	}
@@ -162,7 +252,9 @@ func (chain *Chain) apply(transaction fat0.Transaction) (err error) {
 				log.Debugf("Invalid Transaction Entry: %v, "+
 					"insufficient coinbase supply",
 					entry.Hash)
-				return nil
+				return chain.saveBlockEntry(
+					transaction.Entry.Entry,
+					fmt.Errorf("insufficient coinbase supply"))
 			}
 			chain.Issued += amount
 			if err := chain.saveMetadata(); err != nil {
@@ -175,7 +267,9 @@ func (chain *Chain) apply(transaction fat0.Transaction) (err error) {
 			log.Debugf("Invalid Transaction Entry: %v, "+
 				"insufficient balance: %v",
 				entry.Hash, adr.Address())
-			return nil
+			return chain.saveBlockEntry(transaction.Entry.Entry,
+				fmt.Errorf("insufficient balance: %v",
+					adr.Address()))
 		}
 		adr.Balance -= amount
 		if err := chain.Save(&adr).Error; err != nil {
@@ -199,5 +293,16 @@ func (chain *Chain) apply(transaction fat0.Transaction) (err error) {
 	}
 	log.Debugf("Valid Transaction Entry: %+v", transaction)
 
-	return chain.Commit().Error
+	if err := chain.Commit().Error; err != nil {
+		return err
+	}
+	outputs := make([]*factom.RCDHash, 0, len(transaction.Outputs))
+	for rcdHash := range transaction.Outputs {
+		rcdHash := rcdHash
+		outputs = append(outputs, &rcdHash)
+	}
+	if err := chain.saveAddressChains(outputs...); err != nil {
+		return err
+	}
+	return chain.saveBlockEntry(transaction.Entry.Entry, nil)
 }
@@ -0,0 +1,42 @@
+package state
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/fatd/factom"
+	_log "github.com/Factom-Asset-Tokens/fatd/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordFailure(t *testing.T) {
+	log = _log.New("state")
+	assert := assert.New(t)
+	chain := Chain{ID: factom.NewBytes32([]byte{0x01})}
+	record := func(err error) {
+		chain.recordFailure(&err)
+	}
+
+	// Network errors are not the fault of the chain.
+	record(FetchError{Op: "test", Err: &url.Error{Op: "Post",
+		Err: fmt.Errorf("connection refused")}})
+	assert.Equal(uint(0), chain.Failures.Count)
+
+	malformed := FetchError{Op: "test", Err: fmt.Errorf("malformed")}
+	record(malformed)
+	assert.Equal(uint(1), chain.Failures.Count)
+	assert.Equal(malformed, chain.Failures.Err)
+	assert.False(chain.IsQuarantined())
+
+	// Success resets the failures.
+	record(nil)
+	assert.Equal(ChainFailures{}, chain.Failures)
+
+	for i := uint(0); i < MaxFailures; i++ {
+		assert.False(chain.IsQuarantined())
+		record(malformed)
+	}
+	assert.True(chain.IsQuarantined())
+	assert.Equal(MaxFailures, chain.Failures.Count)
+}
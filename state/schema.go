@@ -16,19 +16,28 @@ type Metadata struct {
 	Issuer *factom.Bytes32
 
 	Issued uint64
+
+	// Version is the schemaVersion of the database. Databases created
+	// before the entries table recorded heights and UTC timestamps have
+	// Version 0 until they are migrated.
+	Version uint
 }
 
 type entry struct {
 	ID        uint64
 	Hash      *factom.Bytes32 `gorm:"type:VARCHAR(32); UNIQUE_INDEX; NOT NULL;"`
-	Timestamp time.Time       `gorm:"NOT NULL;"`
+	Height    uint64          `gorm:"INDEX; NOT NULL; DEFAULT:0;"`
+	Timestamp time.Time       `gorm:"INDEX; NOT NULL;"`
 	Data      factom.Bytes    `gorm:"NOT NULL;"`
 }
 
 func newEntry(e factom.Entry) entry {
+	// Timestamps are compared as text by SQLite so they must all be saved
+	// in the same time zone.
 	return entry{
 		Hash:      e.Hash,
-		Timestamp: e.Timestamp.Time,
+		Height:    e.Height,
+		Timestamp: e.Timestamp.Time.UTC(),
 		Data:      e.MarshalBinary(),
 	}
 }
@@ -38,7 +47,8 @@ func (e entry) IsValid() bool {
 }
 
 func (e entry) Entry() factom.Entry {
-	fe := factom.Entry{Hash: e.Hash, Timestamp: &factom.Time{Time: e.Timestamp}}
+	fe := factom.Entry{Hash: e.Hash, Height: e.Height,
+		Timestamp: &factom.Time{Time: e.Timestamp}}
 	fe.UnmarshalBinary(e.Data)
 	return fe
 }